@@ -38,15 +38,38 @@ type (
 
 	// ActivityInfo contains information about currently executing activity.
 	ActivityInfo struct {
-		TaskToken         []byte
-		WorkflowExecution WorkflowExecution
-		ActivityID        string
-		ActivityType      ActivityType
+		TaskToken          []byte
+		WorkflowExecution  WorkflowExecution
+		WorkflowDomain     string
+		ActivityID         string
+		ActivityType       ActivityType
+		HeartbeatTimeout   time.Duration
+		ScheduledTimestamp time.Time
+		StartedTimestamp   time.Time
+		// Deadline is the earliest of the schedule to close and the start to close deadlines, the activity
+		// context is cancelled with context.DeadlineExceeded when it is reached.
+		Deadline time.Time
 	}
 
 	// RegisterActivityOptions consists of options for registering an activity
 	RegisterActivityOptions struct {
+		// The name of the activity, or the prefix of the method names when registering the activities of a struct.
 		Name string
+
+		// Optional: Sets the rate limiting on the number of executions of the activity per second on each worker, on
+		// top of the MaxActivityExecutionPerSecond of the worker. The tasks over the limit wait in the worker until
+		// they can run, and the wait is reported by the cadence-activity-throttle-latency timer. The waiting tasks
+		// don't count towards the MaxConcurrentActivityExecutionSize of the worker, unless that many tasks are already
+		// waiting, and they heartbeat while they wait. The wait counts towards the start to close timeout of the
+		// activity. When registering the activities of a struct, each method has its own limit.
+		// default: 0, no limit
+		MaxExecutionsPerSecond float64
+
+		// Optional: Sets the max number of concurrent executions of the activity on each worker, on top of the
+		// MaxConcurrentActivityExecutionSize of the worker. The tasks over the limit wait in the worker like for
+		// MaxExecutionsPerSecond.
+		// default: 0, no limit
+		MaxConcurrentExecutions int
 	}
 )
 
@@ -60,6 +83,11 @@ type (
 //	func sampleActivity(arg1 bool) (result int, err error)
 //	func sampleActivity(arg1 bool) (err error)
 // Serialization of all primitive types, structures is supported ... except channels, functions, variadic, unsafe pointer.
+// activityFunc can also be a pointer to a struct, in which case all its exported methods that comply with the expected
+// format are registered, so that activities sharing clients and configuration can be grouped in a struct:
+//	RegisterActivity(&Activities{db: db})
+//	ExecuteActivity(ctx, (*Activities).SampleActivity, arg1)
+// Workflows execute them by passing the method expression, or the method value, to ExecuteActivity.
 // This method calls panic if activityFunc doesn't comply with the expected format.
 func RegisterActivity(activityFunc interface{}) {
 	RegisterActivityWithOptions(activityFunc, RegisterActivityOptions{})
@@ -70,6 +98,9 @@ func RegisterActivity(activityFunc interface{}) {
 // external name is required. This can be used as
 //  client.RegisterActivity(barActivity, RegisterActivityOptions{})
 //  client.RegisterActivity(barActivity, RegisterActivityOptions{Name: "barExternal"})
+// When activityFunc is a pointer to a struct, the name is the prefix of the method names:
+//  client.RegisterActivity(&Activities{}, RegisterActivityOptions{Name: "activities."})
+// Workflows execute the activities of a struct registered with a prefix by name, such as "activities.SampleActivity".
 // An activity takes a context and input and returns a (result, error) or just error.
 // Examples:
 //	func sampleActivity(ctx context.Context, input []byte) (result []byte, err error)
@@ -92,10 +123,15 @@ func RegisterActivityWithOptions(activityFunc interface{}, opts RegisterActivity
 func GetActivityInfo(ctx context.Context) ActivityInfo {
 	env := getActivityEnv(ctx)
 	return ActivityInfo{
-		ActivityID:        env.activityID,
-		ActivityType:      env.activityType,
-		TaskToken:         env.taskToken,
-		WorkflowExecution: env.workflowExecution,
+		ActivityID:         env.activityID,
+		ActivityType:       env.activityType,
+		TaskToken:          env.taskToken,
+		WorkflowExecution:  env.workflowExecution,
+		WorkflowDomain:     env.workflowDomain,
+		HeartbeatTimeout:   env.heartbeatTimeout,
+		ScheduledTimestamp: env.scheduledTimestamp,
+		StartedTimestamp:   env.startedTimestamp,
+		Deadline:           env.deadline,
 	}
 }
 
@@ -120,21 +156,7 @@ func GetActivityMetricsScope(ctx context.Context) tally.Scope {
 // details - the details that you provided here can be seen in the worflow when it receives TimeoutError, you
 // can check error TimeOutType()/Details().
 func RecordActivityHeartbeat(ctx context.Context, details ...interface{}) {
-	var data []byte
-	var err error
-	// We would like to be a able to pass in "nil" as part of details(that is no progress to report to)
-	if len(details) != 1 || details[0] != nil {
-		data, err = getHostEnvironment().encodeArgs(details)
-		if err != nil {
-			panic(err)
-		}
-	}
-	env := getActivityEnv(ctx)
-	err = env.serviceInvoker.Heartbeat(data)
-	if err != nil {
-		log := GetActivityLogger(ctx)
-		log.Debug("RecordActivityHeartbeat With Error:", zap.Error(err))
-	}
+	getActivityInterceptor(ctx).RecordHeartbeat(ctx, details...)
 }
 
 // ServiceInvoker abstracts calls to the Cadence service from an activity implementation.
@@ -147,6 +169,7 @@ type ServiceInvoker interface {
 
 // WithActivityTask adds activity specific information into context.
 // Use this method to unit test activity implementations that use context extractor methodshared.
+// The deadline of the task is reported by GetActivityInfo, it is up to the caller to apply it to the context.
 func WithActivityTask(
 	ctx context.Context,
 	task *shared.PollForActivityTaskResponse,
@@ -154,7 +177,20 @@ func WithActivityTask(
 	logger *zap.Logger,
 	scope tally.Scope,
 ) context.Context {
-	// TODO: Add activity start to close timeout to activity task and use it as the deadline
+	return withActivityTask(ctx, task, "", invoker, logger, scope, nil)
+}
+
+func withActivityTask(
+	ctx context.Context,
+	task *shared.PollForActivityTaskResponse,
+	domain string,
+	invoker ServiceInvoker,
+	logger *zap.Logger,
+	scope tally.Scope,
+	interceptors []ActivityInterceptorFactory,
+) context.Context {
+	scheduled := time.Unix(0, task.GetScheduledTimestamp())
+	started := time.Unix(0, task.GetStartedTimestamp())
 	return context.WithValue(ctx, activityEnvContextKey, &activityEnvironment{
 		taskToken:      task.TaskToken,
 		serviceInvoker: invoker,
@@ -163,11 +199,31 @@ func WithActivityTask(
 		workflowExecution: WorkflowExecution{
 			RunID: *task.WorkflowExecution.RunId,
 			ID:    *task.WorkflowExecution.WorkflowId},
-		logger:       logger,
-		metricsScope: scope,
+		workflowDomain:     domain,
+		heartbeatTimeout:   time.Duration(task.GetHeartbeatTimeoutSeconds()) * time.Second,
+		scheduledTimestamp: scheduled,
+		startedTimestamp:   started,
+		deadline:           getActivityTaskDeadline(task),
+		logger:             logger,
+		metricsScope:       scope,
+		interceptors:       interceptors,
+		header:             task.Header,
 	})
 }
 
+// getActivityTaskDeadline returns the earliest of the schedule to close and the start to close deadlines of the task.
+func getActivityTaskDeadline(task *shared.PollForActivityTaskResponse) time.Time {
+	scheduled := time.Unix(0, task.GetScheduledTimestamp())
+	started := time.Unix(0, task.GetStartedTimestamp())
+	scheduleToCloseDeadline := scheduled.Add(time.Duration(task.GetScheduleToCloseTimeoutSeconds()) * time.Second)
+	startToCloseDeadline := started.Add(time.Duration(task.GetStartToCloseTimeoutSeconds()) * time.Second)
+	// Minimum of the two deadlines.
+	if scheduleToCloseDeadline.Before(startToCloseDeadline) {
+		return scheduleToCloseDeadline
+	}
+	return startToCloseDeadline
+}
+
 // ActivityOptions stores all activity-specific parameters that will be stored inside of a context.
 type ActivityOptions struct {
 	// TaskList that the activity needs to be scheduled on.
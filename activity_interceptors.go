@@ -0,0 +1,64 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"context"
+)
+
+type (
+	// ActivityInterceptorFactory is used to create a single link of the activity interceptor chain.
+	// Set WorkerOptions.ActivityInterceptors to add interceptor factories to a worker.
+	ActivityInterceptorFactory interface {
+		// NewInterceptor creates an interceptor for a single activity execution. The returned interceptor must
+		// delegate every call to next for the activity to function correctly.
+		NewInterceptor(info *ActivityInfo, next ActivityInterceptor) ActivityInterceptor
+	}
+
+	// ActivityInterceptor intercepts the invocation of the activity function and the heartbeats recorded by it.
+	// Embed ActivityInterceptorBase to only override the methods of interest.
+	ActivityInterceptor interface {
+		// ExecuteActivity intercepts the invocation of the activity function. The args are the decoded activity
+		// arguments. The result is the value returned by the activity function, it is nil if the function only
+		// returns error. The ctx passed to next is the one the activity function receives.
+		ExecuteActivity(ctx context.Context, activityType string, args ...interface{}) (result interface{}, err error)
+		// RecordHeartbeat intercepts cadence.RecordActivityHeartbeat.
+		RecordHeartbeat(ctx context.Context, details ...interface{})
+	}
+
+	// ActivityInterceptorBase is an ActivityInterceptor that forwards all calls to the Next interceptor.
+	// It is meant to be embedded by interceptors that only need to override some of the methods.
+	ActivityInterceptorBase struct {
+		Next ActivityInterceptor
+	}
+)
+
+var _ ActivityInterceptor = (*ActivityInterceptorBase)(nil)
+
+// ExecuteActivity forwards to t.Next
+func (t *ActivityInterceptorBase) ExecuteActivity(ctx context.Context, activityType string, args ...interface{}) (interface{}, error) {
+	return t.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+// RecordHeartbeat forwards to t.Next
+func (t *ActivityInterceptorBase) RecordHeartbeat(ctx context.Context, details ...interface{}) {
+	t.Next.RecordHeartbeat(ctx, details...)
+}
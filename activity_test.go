@@ -191,3 +191,32 @@ func TestActivityHeartbeat_SuppressContinousInvokes(t *testing.T) {
 	<-waitCh2
 	invoker4.Close()
 }
+
+func TestActivityAutoHeartbeat(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	invoker := newCadenceInvoker([]byte("task-token"), "identity", service, cancel, 1)
+	defer invoker.Close()
+	ctx = context.WithValue(ctx, activityEnvContextKey, &activityEnvironment{
+		serviceInvoker: invoker,
+		logger:         getLogger()})
+
+	service.EXPECT().RecordActivityTaskHeartbeat(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.RecordActivityTaskHeartbeatResponse{}, nil).Times(1)
+	RecordActivityHeartbeat(ctx, "testDetails")
+
+	// The framework keeps reporting the last details and cancels the activity when asked to.
+	service.EXPECT().RecordActivityTaskHeartbeat(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.RecordActivityTaskHeartbeatResponse{CancelRequested: common.BoolPtr(true)}, nil).
+		Do(func(ctx context.Context, request *s.RecordActivityTaskHeartbeatRequest, opts ...yarpc.CallOption) {
+			var progress string
+			require.NoError(t, EncodedValues(request.Details).Get(&progress))
+			require.Equal(t, "testDetails", progress)
+		}).MinTimes(1)
+	go invoker.autoHeartbeat()
+
+	<-ctx.Done()
+	require.Equal(t, context.Canceled, ctx.Err())
+}
@@ -24,6 +24,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/uber-go/tally"
 	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
 	s "go.uber.org/cadence/.gen/go/shared"
@@ -155,6 +156,13 @@ type (
 	ClientOptions struct {
 		MetricsScope tally.Scope
 		Identity     string
+
+		// Optional: Sets the tracer used to create the spans of StartWorkflow and SignalWorkflow. The span in the
+		// context passed to these calls is the parent of the created span, which is set in the context of the request
+		// to the service so that the transport propagates it in the request headers. The span context is also sent in
+		// the header of the started workflow or of the signal, so the spans of the workers are its children.
+		// default: no tracing
+		Tracer opentracing.Tracer
 	}
 
 	// StartWorkflowOptions configuration parameters for starting a workflow execution.
@@ -220,8 +228,10 @@ func NewClient(service workflowserviceclient.Interface, domain string, options *
 		identity = options.Identity
 	}
 	var metricScope tally.Scope
+	var tracer opentracing.Tracer
 	if options != nil {
 		metricScope = options.MetricsScope
+		tracer = options.Tracer
 	}
 	metricScope = tagScope(metricScope, tagDomain, domain)
 	return &workflowClient{
@@ -229,6 +239,7 @@ func NewClient(service workflowserviceclient.Interface, domain string, options *
 		domain:          domain,
 		metricsScope:    metricScope,
 		identity:        identity,
+		tracer:          tracer,
 	}
 }
 
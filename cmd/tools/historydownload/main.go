@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// historydownload downloads the history of a workflow execution through Client.GetWorkflowHistory and writes it in the
+// JSON format of cadence.ExportHistoryJSON, to share it or replay it with a cadence.WorkflowReplayer.
+//
+// Usage:
+//
+//	historydownload -domain domain -workflow_id id [-run_id id] [-address host:port] [-decode_payloads] [-output file]
+//
+// The history of the latest run of the workflow is downloaded when no run ID is given, and it is written to the
+// standard output when no output file is given.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/transport/tchannel"
+)
+
+const (
+	clientName     = "cadence-historydownload"
+	frontendName   = "cadence-frontend"
+	requestTimeout = time.Minute
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("historydownload: ")
+	address := flag.String("address", "127.0.0.1:7933", "host:port of the cadence frontend")
+	domain := flag.String("domain", "", "domain of the workflow execution")
+	workflowID := flag.String("workflow_id", "", "workflow ID of the workflow execution")
+	runID := flag.String("run_id", "", "run ID of the workflow execution, the latest run when empty")
+	decodePayloads := flag.Bool("decode_payloads", false, "write the payloads as JSON values instead of base64")
+	output := flag.String("output", "", "name of the written file, the standard output when empty")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: historydownload -domain domain -workflow_id id [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *domain == "" || *workflowID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*address, *domain, *workflowID, *runID, *decodePayloads, *output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(address, domain, workflowID, runID string, decodePayloads bool, output string) error {
+	transport, err := tchannel.NewChannelTransport(tchannel.ServiceName(clientName))
+	if err != nil {
+		return err
+	}
+	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+		Name: clientName,
+		Outbounds: yarpc.Outbounds{
+			frontendName: {Unary: transport.NewSingleOutbound(address)},
+		},
+	})
+	if err := dispatcher.Start(); err != nil {
+		return err
+	}
+	defer dispatcher.Stop()
+
+	service := workflowserviceclient.New(dispatcher.ClientConfig(frontendName))
+	client := cadence.NewClient(service, domain, &cadence.ClientOptions{Identity: clientName})
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	history, err := client.GetWorkflowHistory(ctx, workflowID, runID)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return cadence.ExportHistoryJSON(os.Stdout, history, cadence.HistoryJSONOptions{DecodePayloads: decodePayloads})
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := cadence.ExportHistoryJSON(file, history, cadence.HistoryJSONOptions{DecodePayloads: decodePayloads}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,512 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	cadencePackagePath = "go.uber.org/cadence"
+	contextPackagePath = "context"
+	mockPackagePath    = "github.com/stretchr/testify/mock"
+	generatedHeader    = "// Code generated by stubgen. DO NOT EDIT."
+)
+
+// wellKnownImports are the packages the generated code refers to by their usual names.
+var wellKnownImports = map[string]string{
+	cadencePackagePath: "cadence",
+	contextPackagePath: "context",
+	mockPackagePath:    "mock",
+}
+
+// reservedNames are the identifiers the generated functions use for their own parameters and locals.
+var reservedNames = map[string]bool{
+	"c":       true,
+	"ctx":     true,
+	"env":     true,
+	"err":     true,
+	"f":       true,
+	"fn":      true,
+	"options": true,
+	"r":       true,
+}
+
+type (
+	// registration is a workflow or activity function passed to one of the cadence Register functions.
+	registration struct {
+		fn       *types.Func
+		workflow bool
+	}
+
+	// param is a typed parameter of a generated function.
+	param struct {
+		Name string
+		Type string
+	}
+
+	// stub holds everything the template needs to generate the wrappers of a single function.
+	stub struct {
+		Workflow bool
+		Func     string // the function reference, qualified when it lives in another package
+		Name     string // the function name, used in the doc comments
+		Start    string
+		Execute  string
+		On       string
+		Future   string
+		Mock     string
+		// HasContext is set for the activities that take a context.Context as their first parameter.
+		HasContext bool
+		Params     []param
+		Result     string // empty when the function only returns an error
+		Signature  string // the signature of the wrapped function, only set for the mocks
+	}
+
+	// importSet assigns a unique package name to every package referenced by the generated code.
+	importSet struct {
+		self   *types.Package
+		byPath map[string]string
+		byName map[string]string
+	}
+)
+
+func newImportSet(self *types.Package) *importSet {
+	return &importSet{self: self, byPath: make(map[string]string), byName: make(map[string]string)}
+}
+
+// add returns the name the generated code uses for the package with the given path, adding it to the imports.
+func (s *importSet) add(path, name string) string {
+	if n, ok := s.byPath[path]; ok {
+		return n
+	}
+	if n, ok := wellKnownImports[path]; ok {
+		s.byPath[path] = n
+		s.byName[n] = path
+		return n
+	}
+	unique := name
+	for i := 2; s.taken(unique); i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	s.byPath[path] = unique
+	s.byName[unique] = path
+	return unique
+}
+
+func (s *importSet) taken(name string) bool {
+	if _, ok := s.byName[name]; ok {
+		return true
+	}
+	for _, n := range wellKnownImports {
+		if n == name {
+			return true
+		}
+	}
+	return s.self.Scope().Lookup(name) != nil
+}
+
+func (s *importSet) qualifier(p *types.Package) string {
+	if p == s.self {
+		return ""
+	}
+	return s.add(p.Path(), p.Name())
+}
+
+// imports returns the import specs sorted by path, with the standard library packages in a group of their own.
+func (s *importSet) imports() [][]string {
+	var paths []string
+	for path := range s.byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var std, other []string
+	for _, path := range paths {
+		spec := strconv.Quote(path)
+		if name := s.byPath[path]; name != path[strings.LastIndex(path, "/")+1:] {
+			spec = name + " " + spec
+		}
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	var groups [][]string
+	for _, group := range [][]string{std, other} {
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// findRegistrations returns the functions registered as workflows or activities in the given files.
+func findRegistrations(files []*ast.File, info *types.Info) []registration {
+	var regs []registration
+	seen := make(map[*types.Func]bool)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			workflow, ok := registerKind(calleeOf(call, info))
+			if !ok {
+				return true
+			}
+			fn, ok := funcOf(call.Args[0], info)
+			if !ok || seen[fn] {
+				return true
+			}
+			seen[fn] = true
+			regs = append(regs, registration{fn: fn, workflow: workflow})
+			return true
+		})
+	}
+	return regs
+}
+
+func calleeOf(call *ast.CallExpr, info *types.Info) *types.Func {
+	var id *ast.Ident
+	switch f := call.Fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return nil
+	}
+	fn, _ := info.Uses[id].(*types.Func)
+	return fn
+}
+
+// registerKind reports whether fn is one of the cadence Register functions and whether it registers a workflow.
+func registerKind(fn *types.Func) (workflow bool, ok bool) {
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != cadencePackagePath {
+		return false, false
+	}
+	switch fn.Name() {
+	case "RegisterWorkflow", "RegisterWorkflowWithOptions":
+		return true, true
+	case "RegisterActivity", "RegisterActivityWithOptions":
+		return false, true
+	}
+	return false, false
+}
+
+// funcOf resolves a registered function argument to a package level function.
+func funcOf(expr ast.Expr, info *types.Info) (*types.Func, bool) {
+	for {
+		p, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = p.X
+	}
+	var id *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	default:
+		return nil, false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Type().(*types.Signature).Recv() != nil {
+		return nil, false
+	}
+	return fn, true
+}
+
+// generate returns the formatted sources of the typed wrappers of the registered functions of pkg and of their
+// mocks. The mocks go to a test file, so that the package only depends on the mock package in its tests.
+func generate(pkg *types.Package, regs []registration) (stubs []byte, mocks []byte, err error) {
+	if stubs, err = generateFile(pkg, regs, stubTemplate, false); err != nil {
+		return nil, nil, err
+	}
+	if mocks, err = generateFile(pkg, regs, mockTemplate, true); err != nil {
+		return nil, nil, err
+	}
+	return stubs, mocks, nil
+}
+
+func generateFile(pkg *types.Package, regs []registration, tmpl *template.Template, mocks bool) ([]byte, error) {
+	imports := newImportSet(pkg)
+	imports.add(cadencePackagePath, "cadence")
+	var stubs []stub
+	for _, reg := range regs {
+		s, err := newStub(pkg, reg, imports)
+		if err != nil {
+			return nil, err
+		}
+		if mocks {
+			s.Signature = strings.TrimPrefix(types.TypeString(reg.fn.Type(), imports.qualifier), "func")
+			if s.Workflow || s.HasContext {
+				imports.add(mockPackagePath, "mock")
+			}
+		} else if s.Workflow {
+			imports.add(contextPackagePath, "context")
+		}
+		stubs = append(stubs, s)
+	}
+	sort.Slice(stubs, func(i, j int) bool { return stubs[i].Name < stubs[j].Name })
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, struct {
+		Package string
+		Imports [][]string
+		Stubs   []stub
+	}{pkg.Name(), imports.imports(), stubs})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the generated code: %v", err)
+	}
+	return src, nil
+}
+
+func newStub(pkg *types.Package, reg registration, imports *importSet) (stub, error) {
+	fn := reg.fn
+	sig := fn.Type().(*types.Signature)
+	kind := "activity"
+	if reg.workflow {
+		kind = "workflow"
+	}
+	invalid := func(reason string) (stub, error) {
+		return stub{}, fmt.Errorf("%v: %s %s %s", fn.Pkg().Path(), kind, fn.Name(), reason)
+	}
+	if sig.Variadic() {
+		return invalid("is variadic")
+	}
+
+	s := stub{Workflow: reg.workflow, Name: fn.Name(), Func: fn.Name()}
+	if fn.Pkg() != pkg {
+		if !fn.Exported() {
+			return invalid("is not exported")
+		}
+		s.Func = imports.qualifier(fn.Pkg()) + "." + fn.Name()
+	}
+	s.Start, s.Execute, s.On = prefixed("start", fn), prefixed("execute", fn), prefixed("on", fn)
+	s.Future, s.Mock = fn.Name()+"Future", fn.Name()+"Mock"
+
+	params := sig.Params()
+	first := 0
+	if params.Len() > 0 && isNamed(params.At(0).Type(), cadencePackagePath, "Context") {
+		first = 1
+	} else if reg.workflow {
+		return invalid("must take a cadence.Context as its first parameter")
+	}
+	if !reg.workflow && params.Len() > 0 && isNamed(params.At(0).Type(), contextPackagePath, "Context") {
+		first = 1
+		s.HasContext = true
+	}
+	used := make(map[string]bool)
+	for i := first; i < params.Len(); i++ {
+		name := params.At(i).Name()
+		if name == "" || name == "_" {
+			name = "arg" + strconv.Itoa(i-first+1)
+		}
+		for reservedNames[name] || used[name] || imports.taken(name) {
+			name += "Arg"
+		}
+		used[name] = true
+		s.Params = append(s.Params, param{Name: name, Type: types.TypeString(params.At(i).Type(), imports.qualifier)})
+	}
+
+	results := sig.Results()
+	if results.Len() == 0 || results.Len() > 2 || !isError(results.At(results.Len()-1).Type()) {
+		return invalid("must return either an error or a value and an error")
+	}
+	if results.Len() == 2 {
+		s.Result = types.TypeString(results.At(0).Type(), imports.qualifier)
+	}
+	return s, nil
+}
+
+// prefixed returns the name of a generated function, which is exported only when the wrapped function is.
+func prefixed(prefix string, fn *types.Func) string {
+	if fn.Exported() {
+		r, n := utf8.DecodeRuneInString(prefix)
+		prefix = string(unicode.ToUpper(r)) + prefix[n:]
+	}
+	r, n := utf8.DecodeRuneInString(fn.Name())
+	return prefix + string(unicode.ToUpper(r)) + fn.Name()[n:]
+}
+
+func isNamed(t types.Type, path, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == path && obj.Name() == name
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// templateFuncs are the helpers of the templates to render the parameters of the generated functions.
+var templateFuncs = template.FuncMap{
+	"args": func(params []param) string {
+		var names []string
+		for _, p := range params {
+			names = append(names, ", "+p.Name)
+		}
+		return strings.Join(names, "")
+	},
+	"typed": func(params []param) string {
+		var decls []string
+		for _, p := range params {
+			decls = append(decls, ", "+p.Name+" "+p.Type)
+		}
+		return strings.Join(decls, "")
+	},
+	"untyped": func(params []param) string {
+		var decls []string
+		for _, p := range params {
+			decls = append(decls, ", "+p.Name+" interface{}")
+		}
+		return strings.Join(decls, "")
+	},
+}
+
+// fileHeader is the beginning of the generated files.
+const fileHeader = generatedHeader + `
+
+package {{.Package}}
+
+import (
+{{- range $i, $group := .Imports}}
+{{- if $i}}
+{{end}}
+{{- range $group}}
+	{{.}}
+{{- end}}
+{{- end}}
+)
+`
+
+var stubTemplate = template.Must(template.New("stubs").Funcs(templateFuncs).Parse(fileHeader + `
+{{range .Stubs}}
+{{- if .Workflow}}
+// {{.Start}} starts the {{.Name}} workflow with the typed arguments.
+func {{.Start}}(ctx context.Context, c cadence.Client, options cadence.StartWorkflowOptions{{typed .Params}}) (*cadence.WorkflowExecution, error) {
+	return c.StartWorkflow(ctx, options, {{.Func}}{{args .Params}})
+}
+
+// {{.Execute}} executes the {{.Name}} workflow as a child workflow with the typed arguments.
+func {{.Execute}}(ctx cadence.Context{{typed .Params}}) {{.Future}} {
+	return {{.Future}}{cadence.ExecuteChildWorkflow(ctx, {{.Func}}{{args .Params}})}
+}
+
+// {{.Future}} is the typed future of the {{.Name}} child workflow.
+type {{.Future}} struct {
+	cadence.ChildWorkflowFuture
+}
+
+// Get blocks until the child workflow completes and returns its result.
+{{- if .Result}}
+func (f {{.Future}}) Get(ctx cadence.Context) ({{.Result}}, error) {
+	var r {{.Result}}
+	err := f.ChildWorkflowFuture.Get(ctx, &r)
+	return r, err
+}
+{{- else}}
+func (f {{.Future}}) Get(ctx cadence.Context) error {
+	return f.ChildWorkflowFuture.Get(ctx, nil)
+}
+{{- end}}
+{{- else}}
+// {{.Execute}} executes the {{.Name}} activity with the typed arguments.
+func {{.Execute}}(ctx cadence.Context{{typed .Params}}) {{.Future}} {
+	return {{.Future}}{cadence.ExecuteActivity(ctx, {{.Func}}{{args .Params}})}
+}
+
+// {{.Future}} is the typed future of the {{.Name}} activity.
+type {{.Future}} struct {
+	cadence.Future
+}
+
+// Get blocks until the activity completes and returns its result.
+{{- if .Result}}
+func (f {{.Future}}) Get(ctx cadence.Context) ({{.Result}}, error) {
+	var r {{.Result}}
+	err := f.Future.Get(ctx, &r)
+	return r, err
+}
+{{- else}}
+func (f {{.Future}}) Get(ctx cadence.Context) error {
+	return f.Future.Get(ctx, nil)
+}
+{{- end}}
+{{- end}}
+{{end}}`))
+
+var mockTemplate = template.Must(template.New("mocks").Funcs(templateFuncs).Parse(fileHeader + `
+{{range .Stubs}}
+{{- if .Workflow}}
+// {{.On}} sets up a mock call for the {{.Name}} child workflow called with the given values or matchers.
+func {{.On}}(env *cadence.TestWorkflowEnvironment{{untyped .Params}}) {{.Mock}} {
+	return {{.Mock}}{env.OnWorkflow({{.Func}}, mock.Anything{{args .Params}})}
+}
+{{- else}}
+// {{.On}} sets up a mock call for the {{.Name}} activity called with the given values or matchers.
+func {{.On}}(env *cadence.TestWorkflowEnvironment{{untyped .Params}}) {{.Mock}} {
+	return {{.Mock}}{env.OnActivity({{.Func}}{{if .HasContext}}, mock.Anything{{end}}{{args .Params}})}
+}
+{{- end}}
+
+// {{.Mock}} is the typed mock call of {{.Name}}.
+type {{.Mock}} struct {
+	*cadence.MockCallWrapper
+}
+
+// Return sets the values the mocked call returns.
+{{- if .Result}}
+func (m {{.Mock}}) Return(r {{.Result}}, err error) *cadence.MockCallWrapper {
+	return m.MockCallWrapper.Return(r, err)
+}
+{{- else}}
+func (m {{.Mock}}) Return(err error) *cadence.MockCallWrapper {
+	return m.MockCallWrapper.Return(err)
+}
+{{- end}}
+
+// ReturnFunc sets the function the mocked call runs in place of {{.Name}}.
+func (m {{.Mock}}) ReturnFunc(fn func{{.Signature}}) *cadence.MockCallWrapper {
+	return m.MockCallWrapper.Return(fn)
+}
+{{end}}`))
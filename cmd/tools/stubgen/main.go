@@ -0,0 +1,132 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// stubgen generates typed wrappers of the workflow and activity functions registered in a package, so that passing
+// arguments of the wrong type or count to a workflow or an activity becomes a compile error rather than a runtime one.
+//
+// Workflow and activity functions are the package level functions passed to cadence.RegisterWorkflow,
+// cadence.RegisterWorkflowWithOptions, cadence.RegisterActivity and cadence.RegisterActivityWithOptions. For a workflow
+// function MyWorkflow the generated file contains:
+//
+//	StartMyWorkflow    starts the workflow through a cadence.Client
+//	ExecuteMyWorkflow  executes the workflow as a child workflow and returns a MyWorkflowFuture
+//	OnMyWorkflow       sets up a mock of the child workflow in a cadence.TestWorkflowEnvironment
+//
+// and for an activity function MyActivity, ExecuteMyActivity and OnMyActivity. The futures have a Get method that
+// returns the typed result and the mocks have typed Return and ReturnFunc methods. The wrappers of unexported
+// functions are unexported too.
+//
+// Usage:
+//
+//	stubgen [-output file] [packages]
+//
+// The wrappers are written to a file in the directory of each package that registers functions. The mocks, which
+// depend on github.com/stretchr/testify/mock, are written to the test file of the same name with a _test suffix, so
+// that they are only compiled into the tests of the package. Adding a
+//
+//	//go:generate stubgen
+//
+// comment to the package keeps the wrappers up to date with go generate.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax |
+	packages.NeedTypes | packages.NeedTypesInfo
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("stubgen: ")
+	output := flag.String("output", "cadence_stubs.go", "name of the generated file in the package directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: stubgen [-output file] [packages]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if !strings.HasSuffix(*output, ".go") || strings.HasSuffix(*output, "_test.go") {
+		log.Fatalf("invalid output %s, must be a .go file that is not a test file", *output)
+	}
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	cfg := &packages.Config{
+		Mode: loadMode,
+		// Previously generated wrappers are ignored, as they may no longer compile after the functions changed.
+		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			mode := parser.AllErrors | parser.ParseComments
+			if filepath.Base(filename) == *output && bytes.HasPrefix(src, []byte(generatedHeader)) {
+				mode = parser.PackageClauseOnly
+			}
+			return parser.ParseFile(fset, filename, src, mode)
+		},
+	}
+	if err := run(cfg, *output, patterns); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(cfg *packages.Config, output string, patterns []string) error {
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return fmt.Errorf("failed to load packages")
+	}
+	for _, pkg := range pkgs {
+		regs := findRegistrations(pkg.Syntax, pkg.TypesInfo)
+		if len(regs) == 0 || len(pkg.GoFiles) == 0 {
+			continue
+		}
+		stubs, mocks, err := generate(pkg.Types, regs)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(pkg.GoFiles[0])
+		if err := ioutil.WriteFile(filepath.Join(dir, output), stubs, 0644); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, mockOutput(output)), mocks, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mockOutput returns the name of the test file the mocks are written to.
+func mockOutput(output string) string {
+	return strings.TrimSuffix(output, ".go") + "_test.go"
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cadence stubs the cadence client for the stubgen tests.
+package cadence
+
+import "context"
+
+type (
+	Context interface{}
+
+	Future interface {
+		Get(ctx Context, valuePtr interface{}) error
+	}
+
+	ChildWorkflowFuture interface {
+		Future
+	}
+
+	Client interface {
+		StartWorkflow(ctx context.Context, options StartWorkflowOptions, workflow interface{}, args ...interface{}) (*WorkflowExecution, error)
+	}
+
+	StartWorkflowOptions struct{}
+
+	WorkflowExecution struct{}
+
+	RegisterWorkflowOptions struct{}
+
+	RegisterActivityOptions struct{}
+
+	TestWorkflowEnvironment struct{}
+
+	MockCallWrapper struct{}
+)
+
+func RegisterWorkflow(workflowFunc interface{}) {}
+
+func RegisterWorkflowWithOptions(workflowFunc interface{}, options RegisterWorkflowOptions) {}
+
+func RegisterActivity(activityFunc interface{}) {}
+
+func RegisterActivityWithOptions(activityFunc interface{}, options RegisterActivityOptions) {}
+
+func ExecuteActivity(ctx Context, activity interface{}, args ...interface{}) Future { return nil }
+
+func ExecuteChildWorkflow(ctx Context, childWorkflow interface{}, args ...interface{}) ChildWorkflowFuture {
+	return nil
+}
+
+func (t *TestWorkflowEnvironment) OnActivity(activity interface{}, args ...interface{}) *MockCallWrapper {
+	return nil
+}
+
+func (t *TestWorkflowEnvironment) OnWorkflow(workflow interface{}, args ...interface{}) *MockCallWrapper {
+	return nil
+}
+
+func (c *MockCallWrapper) Return(returnArguments ...interface{}) *MockCallWrapper { return c }
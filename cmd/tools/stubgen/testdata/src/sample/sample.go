@@ -0,0 +1,61 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sample
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/cadence"
+)
+
+type Order struct {
+	ID    string
+	Items []string
+}
+
+func init() {
+	cadence.RegisterWorkflow(OrderWorkflow)
+	cadence.RegisterWorkflowWithOptions(cleanupWorkflow, cadence.RegisterWorkflowOptions{})
+	cadence.RegisterActivity(ChargeActivity)
+	cadence.RegisterActivityWithOptions(notifyActivity, cadence.RegisterActivityOptions{})
+	cadence.RegisterActivity(PingActivity)
+}
+
+func OrderWorkflow(ctx cadence.Context, order Order, options time.Duration) (map[string]int, error) {
+	return nil, nil
+}
+
+func cleanupWorkflow(ctx cadence.Context, ids []string) error {
+	return nil
+}
+
+func ChargeActivity(ctx context.Context, order *Order, currency string) (float64, error) {
+	return 0, nil
+}
+
+func notifyActivity(string, int) error {
+	return nil
+}
+
+func PingActivity() (string, error) {
+	return "", nil
+}
@@ -0,0 +1,408 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	cadencePackagePath = "go.uber.org/cadence"
+	ignoreDirective    = "//workflowcheck:ignore"
+
+	// maximum number of reports of a function kept in its fact
+	maxFactReports = 3
+)
+
+var analyzer = &analysis.Analyzer{
+	Name:      "workflowcheck",
+	Doc:       "reports non-deterministic code reachable from cadence workflow functions",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(nonDeterministicFact)},
+}
+
+// registerWorkflowFuncs are the cadence functions whose first argument is a workflow function.
+var registerWorkflowFuncs = map[string]bool{
+	"RegisterWorkflow":            true,
+	"RegisterWorkflowWithOptions": true,
+}
+
+// timeFuncs are the functions of the time package that depend on the wall clock, with their cadence replacements.
+var timeFuncs = map[string]string{
+	"Now":       "cadence.Now",
+	"Since":     "cadence.Now",
+	"Until":     "cadence.Now",
+	"Sleep":     "cadence.Sleep",
+	"After":     "cadence.NewTimer",
+	"AfterFunc": "cadence.NewTimer",
+	"Tick":      "cadence.NewTimer",
+	"NewTimer":  "cadence.NewTimer",
+	"NewTicker": "cadence.NewTimer",
+}
+
+type (
+	// nonDeterministicFact is exported for the functions of a package that reach non-deterministic code, so that
+	// the calls to them from workflows in other packages are reported.
+	nonDeterministicFact struct {
+		Reports []string
+	}
+
+	// report is a non-deterministic construct found in a function.
+	report struct {
+		pos     token.Pos
+		message string
+	}
+
+	// funcInfo is what a function does directly, its callees are followed separately.
+	funcInfo struct {
+		reports []report
+		// static calls to the functions of this package
+		calls []*types.Func
+		// static calls to the functions of other packages
+		externalCalls []externalCall
+	}
+
+	externalCall struct {
+		pos token.Pos
+		fn  *types.Func
+	}
+
+	checker struct {
+		pass    *analysis.Pass
+		decls   map[*types.Func]*ast.FuncDecl
+		infos   map[*types.Func]*funcInfo
+		ignored map[string]map[int]bool // file name -> lines with the ignore directive
+	}
+)
+
+// AFact marks nonDeterministicFact as an analysis.Fact.
+func (*nonDeterministicFact) AFact() {}
+
+func (f *nonDeterministicFact) String() string {
+	return "nonDeterministic(" + strings.Join(f.Reports, "; ") + ")"
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if isTrustedPackage(pass) {
+		return nil, nil
+	}
+	c := &checker{
+		pass:    pass,
+		decls:   make(map[*types.Func]*ast.FuncDecl),
+		infos:   make(map[*types.Func]*funcInfo),
+		ignored: make(map[string]map[int]bool),
+	}
+	// The registered workflows are resolved once the functions of all the files are known.
+	var workflows []ast.Expr
+	for _, file := range pass.Files {
+		c.collectIgnored(file)
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+				if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+					c.decls[fn] = fd
+				}
+			}
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok && c.isRegisterWorkflow(call) && len(call.Args) > 0 {
+				workflows = append(workflows, call.Args[0])
+			}
+			return true
+		})
+	}
+
+	c.exportFacts()
+	c.checkWorkflows(workflows)
+	return nil, nil
+}
+
+// isTrustedPackage returns true for the standard library and the cadence client, which are not checked.
+func isTrustedPackage(pass *analysis.Pass) bool {
+	path := pass.Pkg.Path()
+	if path == cadencePackagePath || strings.HasPrefix(path, cadencePackagePath+"/") {
+		return true
+	}
+	goroot := filepath.Join(build.Default.GOROOT, "src") + string(filepath.Separator)
+	return len(pass.Files) > 0 && strings.HasPrefix(pass.Fset.Position(pass.Files[0].Pos()).Filename, goroot)
+}
+
+func (c *checker) collectIgnored(file *ast.File) {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !strings.HasPrefix(comment.Text, ignoreDirective) {
+				continue
+			}
+			position := c.pass.Fset.Position(comment.Pos())
+			lines, ok := c.ignored[position.Filename]
+			if !ok {
+				lines = make(map[int]bool)
+				c.ignored[position.Filename] = lines
+			}
+			lines[position.Line] = true
+		}
+	}
+}
+
+// isIgnored returns true if the ignore directive is on the same line as pos or on the line above it.
+func (c *checker) isIgnored(pos token.Pos) bool {
+	position := c.pass.Fset.Position(pos)
+	lines := c.ignored[position.Filename]
+	return lines[position.Line] || lines[position.Line-1]
+}
+
+func (c *checker) isRegisterWorkflow(call *ast.CallExpr) bool {
+	fn := typeutil.StaticCallee(c.pass.TypesInfo, call)
+	return fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == cadencePackagePath && registerWorkflowFuncs[fn.Name()]
+}
+
+// workflowInfo returns what the registered workflow function does, nil if the function can't be inspected. A
+// workflow declared in another package is reported through its fact, like the calls to the functions of that package.
+func (c *checker) workflowInfo(arg ast.Expr) *funcInfo {
+	for {
+		paren, ok := arg.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		arg = paren.X
+	}
+	var fn *types.Func
+	switch arg := arg.(type) {
+	case *ast.FuncLit:
+		return c.inspect(arg)
+	case *ast.Ident:
+		fn, _ = c.pass.TypesInfo.Uses[arg].(*types.Func)
+	case *ast.SelectorExpr:
+		fn, _ = c.pass.TypesInfo.Uses[arg.Sel].(*types.Func)
+	}
+	if fn == nil {
+		return nil
+	}
+	if _, ok := c.decls[fn]; ok {
+		return c.funcInfo(fn)
+	}
+	info := &funcInfo{}
+	var fact nonDeterministicFact
+	if fn.Pkg() != c.pass.Pkg && !c.isIgnored(arg.Pos()) && c.pass.ImportObjectFact(fn, &fact) {
+		info.reports = append(info.reports, report{
+			pos:     arg.Pos(),
+			message: fmt.Sprintf("workflow %s is not deterministic: %s", fn.FullName(), strings.Join(fact.Reports, "; ")),
+		})
+	}
+	return info
+}
+
+// funcInfo returns what the declared function does directly.
+func (c *checker) funcInfo(fn *types.Func) *funcInfo {
+	info, ok := c.infos[fn]
+	if !ok {
+		info = c.inspect(c.decls[fn])
+		c.infos[fn] = info
+	}
+	return info
+}
+
+// inspect collects the non-deterministic constructs and the calls of the function, including the ones of the function
+// literals it contains. Functions with the ignore directive in their doc comment are not inspected.
+func (c *checker) inspect(node ast.Node) *funcInfo {
+	info := &funcInfo{}
+	if fd, ok := node.(*ast.FuncDecl); ok && fd.Doc != nil {
+		for _, comment := range fd.Doc.List {
+			if strings.HasPrefix(comment.Text, ignoreDirective) {
+				return info
+			}
+		}
+	}
+	add := func(pos token.Pos, format string, args ...interface{}) {
+		if !c.isIgnored(pos) {
+			info.reports = append(info.reports, report{pos: pos, message: fmt.Sprintf(format, args...)})
+		}
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			add(n.Pos(), "go statement is not deterministic, use cadence.Go")
+		case *ast.SelectStmt:
+			add(n.Pos(), "select statement is not deterministic, use cadence.NewSelector")
+		case *ast.SendStmt:
+			add(n.Pos(), "send to native channel is not deterministic, use cadence.Channel")
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				add(n.Pos(), "receive from native channel is not deterministic, use cadence.Channel")
+			}
+		case *ast.RangeStmt:
+			switch c.pass.TypesInfo.TypeOf(n.X).Underlying().(type) {
+			case *types.Map:
+				add(n.Pos(), "iteration over map is not deterministic, iterate over the sorted keys")
+			case *types.Chan:
+				add(n.Pos(), "range over native channel is not deterministic, use cadence.Channel")
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if v := c.globalVar(lhs); v != nil {
+						add(lhs.Pos(), "modification of global variable %s is not deterministic", v.Name())
+					}
+				}
+			}
+		case *ast.IncDecStmt:
+			if v := c.globalVar(n.X); v != nil {
+				add(n.Pos(), "modification of global variable %s is not deterministic", v.Name())
+			}
+		case *ast.CallExpr:
+			fn := typeutil.StaticCallee(c.pass.TypesInfo, n)
+			if fn == nil || fn.Pkg() == nil {
+				break
+			}
+			if fn.Pkg().Path() == "time" && isPackageFunc(fn) {
+				if replacement, ok := timeFuncs[fn.Name()]; ok {
+					add(n.Pos(), "time.%s is not deterministic, use %s", fn.Name(), replacement)
+				}
+			} else if _, ok := c.decls[fn]; ok {
+				if !c.isIgnored(n.Pos()) {
+					info.calls = append(info.calls, fn)
+				}
+			} else if fn.Pkg() != c.pass.Pkg && !c.isIgnored(n.Pos()) {
+				info.externalCalls = append(info.externalCalls, externalCall{pos: n.Pos(), fn: fn})
+			}
+		}
+		return true
+	})
+	return info
+}
+
+func isPackageFunc(fn *types.Func) bool {
+	return fn.Type().(*types.Signature).Recv() == nil
+}
+
+// globalVar returns the package level variable modified by the assignment to the expression, if any.
+func (c *checker) globalVar(expr ast.Expr) *types.Var {
+	for {
+		switch e := expr.(type) {
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			if v, ok := c.pass.TypesInfo.Uses[e.Sel].(*types.Var); ok && isGlobal(v) {
+				return v
+			}
+			expr = e.X
+		case *ast.Ident:
+			if v, ok := c.pass.TypesInfo.Uses[e].(*types.Var); ok && isGlobal(v) {
+				return v
+			}
+			return nil
+		default:
+			return nil
+		}
+	}
+}
+
+func isGlobal(v *types.Var) bool {
+	return v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+}
+
+// reachable walks the static calls from the roots through the functions of the package. It returns the reports of
+// all the reached functions and the calls they make to the non-deterministic functions of other packages.
+func (c *checker) reachable(roots ...*funcInfo) []report {
+	var reports []report
+	visited := make(map[*types.Func]bool)
+	queue := roots
+	for len(queue) > 0 {
+		info := queue[0]
+		queue = queue[1:]
+		reports = append(reports, info.reports...)
+		for _, call := range info.externalCalls {
+			var fact nonDeterministicFact
+			if c.pass.ImportObjectFact(call.fn, &fact) {
+				reports = append(reports, report{
+					pos:     call.pos,
+					message: fmt.Sprintf("call to %s is not deterministic: %s", call.fn.FullName(), strings.Join(fact.Reports, "; ")),
+				})
+			}
+		}
+		for _, fn := range info.calls {
+			if !visited[fn] {
+				visited[fn] = true
+				queue = append(queue, c.funcInfo(fn))
+			}
+		}
+	}
+	return reports
+}
+
+// exportFacts exports the fact for every exported function of the package that reaches non-deterministic code.
+func (c *checker) exportFacts() {
+	for fn := range c.decls {
+		if !fn.Exported() {
+			continue
+		}
+		reports := c.reachable(c.funcInfo(fn))
+		if len(reports) == 0 {
+			continue
+		}
+		sortReports(reports)
+		fact := &nonDeterministicFact{}
+		for i, r := range reports {
+			if i == maxFactReports {
+				fact.Reports = append(fact.Reports, fmt.Sprintf("and %d more", len(reports)-i))
+				break
+			}
+			fact.Reports = append(fact.Reports, fmt.Sprintf("%s at %s", r.message, c.pass.Fset.Position(r.pos)))
+		}
+		c.pass.ExportObjectFact(fn, fact)
+	}
+}
+
+// checkWorkflows reports the non-deterministic code reachable from the workflows, every construct is reported once.
+func (c *checker) checkWorkflows(workflows []ast.Expr) {
+	var roots []*funcInfo
+	for _, workflow := range workflows {
+		if info := c.workflowInfo(workflow); info != nil {
+			roots = append(roots, info)
+		}
+	}
+	reported := make(map[token.Pos]bool)
+	reports := c.reachable(roots...)
+	sortReports(reports)
+	for _, r := range reports {
+		if !reported[r.pos] {
+			reported[r.pos] = true
+			c.pass.Reportf(r.pos, "%s", r.message)
+		}
+	}
+}
+
+func sortReports(reports []report) {
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].pos < reports[j].pos })
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// workflowcheck reports the code reachable from workflow functions that breaks the determinism workflows require:
+// calls to time.Now and similar functions, go statements, native channel operations and select statements, iteration
+// over maps and modification of global variables.
+//
+// Workflow functions are the functions passed to cadence.RegisterWorkflow and cadence.RegisterWorkflowWithOptions.
+// The check follows the static calls from them through the functions of the same package and the functions of the
+// imported packages. Standard library packages and the cadence client are trusted. Add a
+// "//workflowcheck:ignore" comment to the reported line or the line above it to suppress a report, or to the doc
+// comment of a function to skip that function altogether.
+//
+// Usage:
+//
+//	workflowcheck [packages]
+//
+// The reports are printed in the "file:line:column: message" format of go vet. The command can also be run by go vet:
+//
+//	go vet -vettool=$(which workflowcheck) [packages]
+package main
+
+import "golang.org/x/tools/go/analysis/singlechecker"
+
+func main() {
+	singlechecker.Main(analyzer)
+}
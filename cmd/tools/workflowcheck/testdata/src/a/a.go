@@ -0,0 +1,78 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package a
+
+import (
+	"b"
+	"time"
+
+	"go.uber.org/cadence"
+)
+
+var counter int
+
+func init() {
+	cadence.RegisterWorkflow(Workflow)
+	cadence.RegisterWorkflowWithOptions(func(ctx cadence.Context) error {
+		go helper() // want "go statement is not deterministic, use cadence.Go"
+		return nil
+	}, cadence.RegisterWorkflowOptions{Name: "literal"})
+	// workflows declared in another file and in other packages
+	cadence.RegisterWorkflow(OtherFileWorkflow)
+	cadence.RegisterWorkflow(b.Workflow) // want `workflow b.Workflow is not deterministic: go statement is not deterministic`
+	cadence.RegisterWorkflow(b.Deterministic)
+}
+
+func Workflow(ctx cadence.Context) error { // want Workflow:"nonDeterministic"
+	_ = time.Now() // want "time.Now is not deterministic, use cadence.Now"
+	m := map[string]int{}
+	for range m { // want "iteration over map is not deterministic"
+	}
+	helper()
+	b.Deterministic()
+	b.NonDeterministic() // want `call to b.NonDeterministic is not deterministic: time.Sleep is not deterministic`
+	cadence.Go(ctx, func(ctx cadence.Context) {})
+
+	_ = time.Now() //workflowcheck:ignore
+	//workflowcheck:ignore
+	notWorkflow()
+	ignored()
+	return nil
+}
+
+func helper() {
+	counter++ // want "modification of global variable counter is not deterministic"
+	ch := make(chan int, 1)
+	ch <- 1  // want "send to native channel is not deterministic"
+	<-ch     // want "receive from native channel is not deterministic"
+	select { // want "select statement is not deterministic"
+	default:
+	}
+}
+
+//workflowcheck:ignore
+func ignored() {
+	go helper()
+}
+
+func notWorkflow() {
+	go helper()
+}
@@ -48,6 +48,19 @@ type Cache interface {
 	Size() int
 }
 
+// A PurgeableCache is a Cache that can also evict and list all its elements.
+// The caches returned by New implement it
+type PurgeableCache interface {
+	Cache
+
+	// Purge evicts all the elements of the cache that are not pinned
+	Purge()
+
+	// Values returns the elements of the cache, most recently used first,
+	// without updating their access time
+	Values() []interface{}
+}
+
 // Options control the behavior of the cache
 type Options struct {
 	// TTL controls the time-to-live for a given cache entry.  Cache entries that
@@ -63,6 +76,22 @@ type Options struct {
 	// RemovedFunc is an optional function called when an element
 	// is scheduled for deletion
 	RemovedFunc RemovedFunc
+
+	// EvictedFunc is an optional function called, in addition to RemovedFunc,
+	// when an element is evicted by the cache rather than deleted
+	EvictedFunc RemovedFunc
+
+	// IdleTimeout evicts the entries that are not accessed for longer than it.
+	// Idle entries are evicted when the cache is accessed
+	IdleTimeout time.Duration
+
+	// MaxTotalSize evicts the least recently used entries when the total size
+	// of the entries, as returned by SizeFunc, exceeds it
+	MaxTotalSize int64
+
+	// SizeFunc returns the size of an element for MaxTotalSize. The size of
+	// an entry is re-evaluated every time the entry is accessed
+	SizeFunc SizeFunc
 }
 
 // RemovedFunc is a type for notifying applications when an item is
@@ -70,3 +99,6 @@ type Options struct {
 // appropriate signature and i is the interface{} scheduled for
 // deletion, Cache calls go f(i)
 type RemovedFunc func(interface{})
+
+// SizeFunc is a type for returning the size of an element of the Cache
+type SizeFunc func(interface{}) int64
@@ -34,28 +34,39 @@ var (
 
 // lru is a concurrent fixed size cache that evicts elements in lru order
 type lru struct {
-	mut      sync.Mutex
-	byAccess *list.List
-	byKey    map[string]*list.Element
-	maxSize  int
-	ttl      time.Duration
-	pin      bool
-	rmFunc   RemovedFunc
+	mut          sync.Mutex
+	byAccess     *list.List
+	byKey        map[string]*list.Element
+	maxSize      int
+	ttl          time.Duration
+	pin          bool
+	rmFunc       RemovedFunc
+	evictFunc    RemovedFunc
+	idleTimeout  time.Duration
+	maxTotalSize int64
+	sizeFunc     SizeFunc
+	totalSize    int64
+	now          func() time.Time // returns the current time, replaced by the tests
 }
 
-// New creates a new cache with the given options
+// New creates a new cache with the given options, which implements PurgeableCache
 func New(maxSize int, opts *Options) Cache {
 	if opts == nil {
 		opts = &Options{}
 	}
 
 	return &lru{
-		byAccess: list.New(),
-		byKey:    make(map[string]*list.Element, opts.InitialCapacity),
-		ttl:      opts.TTL,
-		maxSize:  maxSize,
-		pin:      opts.Pin,
-		rmFunc:   opts.RemovedFunc,
+		byAccess:     list.New(),
+		byKey:        make(map[string]*list.Element, opts.InitialCapacity),
+		ttl:          opts.TTL,
+		maxSize:      maxSize,
+		pin:          opts.Pin,
+		rmFunc:       opts.RemovedFunc,
+		evictFunc:    opts.EvictedFunc,
+		idleTimeout:  opts.IdleTimeout,
+		maxTotalSize: opts.MaxTotalSize,
+		sizeFunc:     opts.SizeFunc,
+		now:          time.Now,
 	}
 }
 
@@ -89,17 +100,15 @@ func (c *lru) Get(key string) interface{} {
 		cacheEntry.refCount++
 	}
 
-	if cacheEntry.refCount == 0 && !cacheEntry.expiration.IsZero() && time.Now().After(cacheEntry.expiration) {
+	if cacheEntry.refCount == 0 && !cacheEntry.expiration.IsZero() && c.now().After(cacheEntry.expiration) {
 		// Entry has expired
-		if c.rmFunc != nil {
-			go c.rmFunc(cacheEntry.value)
-		}
-		c.byAccess.Remove(elt)
-		delete(c.byKey, cacheEntry.key)
+		c.removeElement(elt, true)
 		return nil
 	}
 
 	c.byAccess.MoveToFront(elt)
+	c.accessEntry(cacheEntry)
+	c.evictIdleAndOversized()
 	return cacheEntry.value
 }
 
@@ -134,11 +143,7 @@ func (c *lru) Delete(key string) {
 
 	elt := c.byKey[key]
 	if elt != nil {
-		entry := c.byAccess.Remove(elt).(*cacheEntry)
-		if c.rmFunc != nil {
-			go c.rmFunc(entry.value)
-		}
-		delete(c.byKey, key)
+		c.removeElement(elt, false)
 	}
 }
 
@@ -160,6 +165,32 @@ func (c *lru) Size() int {
 	return len(c.byKey)
 }
 
+// Purge evicts all the elements of the lru, except the pinned ones which are in use
+func (c *lru) Purge() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for elt := c.byAccess.Back(); elt != nil; {
+		prev := elt.Prev()
+		if elt.Value.(*cacheEntry).refCount == 0 {
+			c.removeElement(elt, true)
+		}
+		elt = prev
+	}
+}
+
+// Values returns the elements of the lru, most recently used first
+func (c *lru) Values() []interface{} {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	values := make([]interface{}, 0, c.byAccess.Len())
+	for elt := c.byAccess.Front(); elt != nil; elt = elt.Next() {
+		values = append(values, elt.Value.(*cacheEntry).value)
+	}
+	return values
+}
+
 // Put puts a new value associated with a given key, returning the existing value (if present)
 // allowUpdate flag is used to control overwrite behavior if the value exists
 func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (interface{}, error) {
@@ -174,12 +205,14 @@ func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (inte
 			entry.value = value
 		}
 		if c.ttl != 0 {
-			entry.expiration = time.Now().Add(c.ttl)
+			entry.expiration = c.now().Add(c.ttl)
 		}
 		c.byAccess.MoveToFront(elt)
 		if c.pin {
 			entry.refCount++
 		}
+		c.accessEntry(entry)
+		c.evictIdleAndOversized()
 		return existing, nil
 	}
 
@@ -193,7 +226,7 @@ func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (inte
 	}
 
 	if c.ttl != 0 {
-		entry.expiration = time.Now().Add(c.ttl)
+		entry.expiration = c.now().Add(c.ttl)
 	}
 
 	c.byKey[key] = c.byAccess.PushFront(entry)
@@ -208,19 +241,61 @@ func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (inte
 			return nil, ErrCacheFull
 		}
 
-		c.byAccess.Remove(c.byAccess.Back())
-		if c.rmFunc != nil {
-			go c.rmFunc(oldest.value)
-		}
-		delete(c.byKey, oldest.key)
+		c.removeElement(c.byAccess.Back(), true)
 	}
 
+	c.accessEntry(entry)
+	c.evictIdleAndOversized()
 	return nil, nil
 }
 
+// accessEntry records the access time of an entry and re-evaluates its size
+func (c *lru) accessEntry(entry *cacheEntry) {
+	entry.accessTime = c.now()
+	if c.sizeFunc != nil {
+		c.totalSize -= entry.size
+		entry.size = c.sizeFunc(entry.value)
+		c.totalSize += entry.size
+	}
+}
+
+// evictIdleAndOversized evicts the least recently used entries while they are idle or the total size of the
+// entries exceeds the max total size. The most recently used entry is kept.
+func (c *lru) evictIdleAndOversized() {
+	if c.idleTimeout == 0 && c.maxTotalSize == 0 {
+		return
+	}
+	now := c.now()
+	for c.byAccess.Len() > 1 {
+		elt := c.byAccess.Back()
+		entry := elt.Value.(*cacheEntry)
+		idle := c.idleTimeout != 0 && now.Sub(entry.accessTime) > c.idleTimeout
+		oversized := c.maxTotalSize != 0 && c.totalSize > c.maxTotalSize
+		if entry.refCount > 0 || (!idle && !oversized) {
+			return
+		}
+		c.removeElement(elt, true)
+	}
+}
+
+// removeElement removes an element, evicted tells whether the cache evicts the element or it is deleted
+func (c *lru) removeElement(elt *list.Element, evicted bool) {
+	entry := c.byAccess.Remove(elt).(*cacheEntry)
+	delete(c.byKey, entry.key)
+	c.totalSize -= entry.size
+	if c.rmFunc != nil {
+		go c.rmFunc(entry.value)
+	}
+	if evicted && c.evictFunc != nil {
+		go c.evictFunc(entry.value)
+	}
+}
+
 type cacheEntry struct {
 	key        string
 	expiration time.Time
+	accessTime time.Time
 	value      interface{}
 	refCount   int
+	size       int64
 }
@@ -60,6 +60,7 @@ func TestLRU(t *testing.T) {
 
 	cache.Delete("A")
 	assert.Nil(t, cache.Get("A"))
+	assert.Equal(t, []interface{}{"Felp", "Cid", "Epsi"}, cache.(PurgeableCache).Values())
 }
 
 func TestLRUWithTTL(t *testing.T) {
@@ -154,3 +155,98 @@ func TestRemovedFuncWithTTL(t *testing.T) {
 		t.Error("RemovedFunc did not send true on channel ch")
 	}
 }
+
+func TestLRUWithIdleTimeout(t *testing.T) {
+	cache := New(5, &Options{
+		IdleTimeout: time.Millisecond * 100,
+	})
+	now := time.Now()
+	cache.(*lru).now = func() time.Time { return now }
+	cache.Put("A", "foo")
+	cache.Put("B", "bar")
+	now = now.Add(time.Millisecond * 50)
+	assert.Equal(t, "foo", cache.Get("A"))
+	now = now.Add(time.Millisecond * 70)
+
+	// B is idle, A was accessed since.
+	cache.Put("C", "cid")
+	assert.Nil(t, cache.Get("B"))
+	assert.Equal(t, "foo", cache.Get("A"))
+	assert.Equal(t, 2, cache.Size())
+}
+
+func TestLRUWithMaxTotalSize(t *testing.T) {
+	sizes := map[string]int64{"A": 3, "B": 4, "C": 2}
+	cache := New(10, &Options{
+		MaxTotalSize: 8,
+		SizeFunc: func(i interface{}) int64 {
+			return sizes[i.(string)]
+		},
+	})
+	cache.Put("A", "A")
+	cache.Put("B", "B")
+	assert.Equal(t, 2, cache.Size())
+
+	// A is the least recently used entry.
+	cache.Put("C", "C")
+	assert.Nil(t, cache.Get("A"))
+	assert.Equal(t, 2, cache.Size())
+
+	// The size of an entry is re-evaluated when it is accessed.
+	sizes["C"] = 5
+	assert.Equal(t, "C", cache.Get("C"))
+	assert.Nil(t, cache.Get("B"))
+	assert.Equal(t, 1, cache.Size())
+}
+
+func TestEvictedFunc(t *testing.T) {
+	removed := make(chan interface{}, 10)
+	evicted := make(chan interface{}, 10)
+	cache := New(5, &Options{
+		RemovedFunc: func(i interface{}) {
+			removed <- i
+		},
+		EvictedFunc: func(i interface{}) {
+			evicted <- i
+		},
+	})
+
+	cache.Put("A", "foo")
+	cache.Put("B", "bar")
+	cache.Delete("A")
+	assert.Equal(t, "foo", <-removed)
+
+	cache.(PurgeableCache).Purge()
+	assert.Equal(t, 0, cache.Size())
+	assert.Equal(t, "bar", <-removed)
+	assert.Equal(t, "bar", <-evicted)
+	assert.Equal(t, 0, len(evicted))
+}
+
+func TestPurgeWithPin(t *testing.T) {
+	removed := make(chan interface{}, 10)
+	cache := New(5, &Options{
+		Pin: true,
+		RemovedFunc: func(i interface{}) {
+			removed <- i
+		},
+	})
+
+	_, err := cache.PutIfNotExist("A", "foo")
+	assert.NoError(t, err)
+	_, err = cache.PutIfNotExist("B", "bar")
+	assert.NoError(t, err)
+	cache.Release("B")
+
+	// A is in use and stays in the cache.
+	cache.(PurgeableCache).Purge()
+	assert.Equal(t, 1, cache.Size())
+	assert.Equal(t, "bar", <-removed)
+	assert.Equal(t, 0, len(removed))
+	assert.Equal(t, []interface{}{"foo"}, cache.(PurgeableCache).Values())
+
+	cache.Release("A")
+	cache.(PurgeableCache).Purge()
+	assert.Equal(t, 0, cache.Size())
+	assert.Equal(t, "foo", <-removed)
+}
@@ -65,17 +65,30 @@ const (
 	ActivityTaskFailedCounter          = CadenceMetricsPrefix + "activity-task-failed"
 	ActivityTaskCanceledCounter        = CadenceMetricsPrefix + "activity-task-canceled"
 
-	UnhandledSignalsCounter = CadenceMetricsPrefix + "unhandled-signals"
+	UnhandledSignalsCounter      = CadenceMetricsPrefix + "unhandled-signals"
+	NonDeterministicErrorCounter = CadenceMetricsPrefix + "non-deterministic-error"
 
 	WorkerStartCounter = CadenceMetricsPrefix + "worker-start"
 	PollerStartCounter = CadenceMetricsPrefix + "poller-start"
+	PollerCount        = CadenceMetricsPrefix + "poller-count"
+
+	ActivityThrottleLatency = CadenceMetricsPrefix + "activity-throttle-latency"
+	FaultInjectedCounter    = CadenceMetricsPrefix + "fault-injected"
+
+	ShadowReplaySucceedCounter          = CadenceMetricsPrefix + "shadow-replay-succeed"
+	ShadowReplayNonDeterministicCounter = CadenceMetricsPrefix + "shadow-replay-non-deterministic"
+	ShadowReplayFailedCounter           = CadenceMetricsPrefix + "shadow-replay-failed"
+	ShadowScanLatency                   = CadenceMetricsPrefix + "shadow-scan-latency"
 
 	CadenceRequest        = CadenceMetricsPrefix + "request"
 	CadenceError          = CadenceMetricsPrefix + "error"
 	CadenceLatency        = CadenceMetricsPrefix + "latency"
 	CadenceInvalidRequest = CadenceMetricsPrefix + "invalid-request"
 
-	StickyCacheHit   = CadenceMetricsPrefix + "sticky-cache-hit"
-	StickyCacheMiss  = CadenceMetricsPrefix + "sticky-cache-miss"
-	StickyCacheStall = CadenceMetricsPrefix + "sticky-cache-stall"
+	StickyCacheHit      = CadenceMetricsPrefix + "sticky-cache-hit"
+	StickyCacheMiss     = CadenceMetricsPrefix + "sticky-cache-miss"
+	StickyCacheStall    = CadenceMetricsPrefix + "sticky-cache-stall"
+	StickyCacheEviction = CadenceMetricsPrefix + "sticky-cache-eviction"
+	StickyCacheSize     = CadenceMetricsPrefix + "sticky-cache-size"
+	StickyCacheHitRatio = CadenceMetricsPrefix + "sticky-cache-hit-ratio"
 )
@@ -118,6 +118,16 @@ type (
 		args    []interface{}
 		options *workflowOptions
 	}
+
+	// NonDeterministicError is reported by the workflow worker when the decisions made by replaying the workflow code
+	// don't match the events recorded in the workflow history. This happens when the workflow code was changed in an
+	// incompatible way without using GetVersion, or when it isn't deterministic. WorkerOptions.NonDeterministicWorkflowPolicy
+	// decides what the worker does about it.
+	NonDeterministicError struct {
+		reason     string
+		mismatch   string
+		stackTrace string
+	}
 )
 
 const (
@@ -133,6 +143,18 @@ const (
 // that could report the activity completed event to cadence server via Client.CompleteActivity() API.
 var ErrActivityResultPending = errors.New("not error: do not autocomplete, using Client.CompleteActivity() to complete")
 
+// ErrWorkerStopping is the error of the context of a running activity once the worker hosting it is stopped with a
+// WorkerOptions.WorkerStopTimeout. It lets the activity tell a worker shutdown apart from a cancellation requested by
+// the workflow. The activity has until the timeout expires to wrap up, what it returns is then reported as usual.
+var ErrWorkerStopping = errors.New("worker is stopping")
+
+// ErrSessionFailed is returned by the activities executed in a session that has failed, for example because the worker
+// host running the session is gone. The workflow can create a new session to start over on another host.
+var ErrSessionFailed = errors.New("session has failed")
+
+// ErrSessionClosed is returned by the activities executed in a session that was completed by CompleteSession.
+var ErrSessionClosed = errors.New("session is closed")
+
 // NewCustomError create new instance of *CustomError with reason and optional details.
 func NewCustomError(reason string, details ...interface{}) *CustomError {
 	if strings.HasPrefix(reason, "cadenceInternal:") {
@@ -276,6 +298,16 @@ func (e *PanicError) StackTrace() string {
 	return e.stackTrace
 }
 
+// Error from error interface. It includes the replayed decisions and the recorded events side by side.
+func (e *NonDeterministicError) Error() string {
+	return fmt.Sprintf("nondeterministic workflow: %s\n%s", e.reason, e.mismatch)
+}
+
+// StackTrace return stack trace of the workflow coroutines when the mismatch was detected
+func (e *NonDeterministicError) StackTrace() string {
+	return e.stackTrace
+}
+
 // Error from error interface
 func (e *ContinueAsNewError) Error() string {
 	return "ContinueAsNew"
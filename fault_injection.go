@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"time"
+)
+
+type (
+	// FaultInjectionTarget is the worker operation that a FaultInjectionRule targets.
+	FaultInjectionTarget int
+
+	// FaultInjectionRule injects latency or errors into the operations of a worker, to test the resilience of
+	// workflows to the failures of workers and of the cadence service, typically in staging. Set the rules with
+	// WorkerOptions.FaultInjectionRules. Every injected fault is logged and counted by the cadence-fault-injected
+	// metric.
+	FaultInjectionRule struct {
+		// The operation the rule applies to.
+		Target FaultInjectionTarget
+
+		// Optional: Restricts the rule to the decision tasks of a workflow type. The activity operations have no
+		// workflow type, so a rule with a workflow type only applies to the decision task operations.
+		// default: all the workflow types
+		WorkflowType string
+
+		// Optional: Restricts the rule to the tasks of an activity type. A rule with an activity type only applies to
+		// the activity task operations.
+		// default: all the activity types
+		ActivityType string
+
+		// Optional: Sets the probability, between 0 and 1, that the rule applies to a matching operation.
+		// The zero value of this applies the rule to every matching operation.
+		Probability float64
+
+		// Optional: Delays the operation. The delays are cut short when the worker stops.
+		// default: 0, no delay
+		Latency time.Duration
+
+		// Optional: Fails the operation with the error after the latency, the operation isn't performed.
+		// default: nil, the operation is performed
+		Error error
+
+		// Optional: Sets the max number of times the rule applies.
+		// default: 0, no limit
+		MaxCount int
+	}
+)
+
+const (
+	// FaultInjectionTargetPoll targets the decision and activity tasks polled by the worker. The latency delays the
+	// processing of a task, and the error drops it as if the poll failed, so the task times out on the service.
+	FaultInjectionTargetPoll FaultInjectionTarget = iota
+	// FaultInjectionTargetRespond targets the reports of the results of the decision and activity tasks. The error
+	// fails the report, so the task times out on the service.
+	FaultInjectionTargetRespond
+	// FaultInjectionTargetHeartbeat targets the activity heartbeats. The error fails the heartbeat like an error of the
+	// service.
+	FaultInjectionTargetHeartbeat
+	// FaultInjectionTargetHistoryFetch targets the pages of history fetched by the decision tasks. The error fails the
+	// decision task.
+	FaultInjectionTargetHistoryFetch
+	// FaultInjectionTargetActivityExecution targets the executions of the activities. The error fails the activity
+	// instead of running it.
+	FaultInjectionTargetActivityExecution
+)
@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"io"
+
+	s "go.uber.org/cadence/.gen/go/shared"
+)
+
+// HistoryJSONFormatVersion is the version of the JSON format of the histories written by ExportHistoryJSON.
+//
+// The format is a JSON object with a formatVersion field and an events field, which lists the history events with
+// the field names of the cadence IDL and the names of the event types, like "WorkflowExecutionStarted". A payload, like
+// the input of a workflow or the result of an activity, is either a base64 string of its bytes or, when it holds the
+// JSON values encoded by the workflow and activity functions, the array of these values. ImportHistoryJSON also reads
+// the plain JSON encoding of a shared.History, without the formatVersion field.
+const HistoryJSONFormatVersion = 1
+
+// HistoryJSONOptions configure the JSON written by ExportHistoryJSON.
+type HistoryJSONOptions struct {
+	// Optional: Writes the payloads as the arrays of the JSON values they hold, so that they are readable. The payloads
+	// that don't hold JSON values, like the ones encoded with thrift, are written as base64 strings anyway.
+	// default: false, all the payloads are written as base64 strings
+	DecodePayloads bool
+}
+
+// ExportHistoryJSON writes the history in the JSON format described by HistoryJSONFormatVersion, to share it or
+// replay it with a WorkflowReplayer or a TestWorkflowEnvironment. Use Client.GetWorkflowHistory to get the history of a
+// workflow execution.
+func ExportHistoryJSON(w io.Writer, history *s.History, options HistoryJSONOptions) error {
+	return exportHistoryJSON(w, history, options)
+}
+
+// ImportHistoryJSON reads a history written by ExportHistoryJSON. The decoded payloads are encoded back to the exact
+// bytes of the exported history.
+func ImportHistoryJSON(r io.Reader) (*s.History, error) {
+	return importHistoryJSON(r)
+}
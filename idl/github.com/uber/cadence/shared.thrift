@@ -177,6 +177,10 @@ struct TaskList {
   10: optional string name
 }
 
+struct Header {
+  10: optional map<string, binary> fields
+}
+
 struct WorkflowExecution {
   10: optional string workflowId
   20: optional string runId
@@ -208,6 +212,7 @@ struct ScheduleActivityTaskDecisionAttributes {
   50: optional i32 scheduleToStartTimeoutSeconds
   55: optional i32 startToCloseTimeoutSeconds
   60: optional i32 heartbeatTimeoutSeconds
+  70: optional Header header
 }
 
 struct RequestCancelActivityTaskDecisionAttributes {
@@ -266,6 +271,7 @@ struct StartChildWorkflowExecutionDecisionAttributes {
   70: optional i32 taskStartToCloseTimeoutSeconds
   80: optional ChildPolicy childPolicy
   90: optional binary control
+  100: optional Header header
 }
 
 struct Decision {
@@ -290,6 +296,7 @@ struct WorkflowExecutionStartedEventAttributes {
   40: optional i32 executionStartToCloseTimeoutSeconds
   50: optional i32 taskStartToCloseTimeoutSeconds
   60: optional string identity
+  70: optional Header header
 }
 
 struct WorkflowExecutionCompletedEventAttributes {
@@ -359,6 +366,7 @@ struct ActivityTaskScheduledEventAttributes {
   55: optional i32 startToCloseTimeoutSeconds
   60: optional i32 heartbeatTimeoutSeconds
   90: optional i64 (js.type = "Long") decisionTaskCompletedEventId
+  100: optional Header header
 }
 
 struct ActivityTaskStartedEventAttributes {
@@ -455,6 +463,7 @@ struct WorkflowExecutionSignaledEventAttributes {
   10: optional string signalName
   20: optional binary input
   30: optional string identity
+  40: optional Header header
 }
 
 struct WorkflowExecutionTerminatedEventAttributes {
@@ -496,6 +505,7 @@ struct StartChildWorkflowExecutionInitiatedEventAttributes {
   80:  optional ChildPolicy childPolicy
   90:  optional binary control
   100: optional i64 (js.type = "Long") decisionTaskCompletedEventId
+  110: optional Header header
 }
 
 struct StartChildWorkflowExecutionFailedEventAttributes {
@@ -680,6 +690,7 @@ struct StartWorkflowExecutionRequest {
   70: optional i32 taskStartToCloseTimeoutSeconds
   80: optional string identity
   90: optional string requestId
+  100: optional Header header
 }
 
 struct StartWorkflowExecutionResponse {
@@ -734,6 +745,7 @@ struct PollForActivityTaskResponse {
   90:  optional i64 (js.type = "Long") startedTimestamp
   100: optional i32 startToCloseTimeoutSeconds
   110: optional i32 heartbeatTimeoutSeconds
+  120: optional Header header
 }
 
 struct RecordActivityTaskHeartbeatRequest {
@@ -790,6 +802,7 @@ struct SignalWorkflowExecutionRequest {
   30: optional string signalName
   40: optional binary input
   50: optional string identity
+  60: optional Header header
 }
 
 struct TerminateWorkflowExecutionRequest {
@@ -27,8 +27,12 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/uber-go/tally"
+	s "go.uber.org/cadence/.gen/go/shared"
 	"go.uber.org/zap"
 )
 
@@ -56,6 +60,7 @@ type (
 		HeartbeatTimeoutSeconds       int32
 		WaitForCancellation           bool
 		OriginalTaskListName          string
+		Header                        *s.Header
 	}
 
 	// asyncActivityClient for requesting activity execution
@@ -72,16 +77,42 @@ type (
 	}
 
 	activityEnvironment struct {
-		taskToken         []byte
-		workflowExecution WorkflowExecution
-		activityID        string
-		activityType      ActivityType
-		serviceInvoker    ServiceInvoker
-		logger            *zap.Logger
-		metricsScope      tally.Scope
+		taskToken          []byte
+		workflowExecution  WorkflowExecution
+		workflowDomain     string
+		activityID         string
+		activityType       ActivityType
+		heartbeatTimeout   time.Duration
+		scheduledTimestamp time.Time
+		startedTimestamp   time.Time
+		deadline           time.Time
+		serviceInvoker     ServiceInvoker
+		logger             *zap.Logger
+		metricsScope       tally.Scope
+		interceptors       []ActivityInterceptorFactory
+		interceptor        ActivityInterceptor // interceptor chain of the executing activity
+		header             *s.Header           // header scheduled with the activity
+	}
+
+	// activityEnvironmentInterceptor is the last link of the activity interceptor chain, it invokes the activity
+	// function and records heartbeats through the ServiceInvoker.
+	activityEnvironmentInterceptor struct {
+		fn interface{}
+	}
+
+	// workerStopContext is the root context of an activity execution. It is cancelled with ErrWorkerStopping when
+	// the worker is stopped. It has a done channel of its own rather than wrapping a cancel context, so that the
+	// contexts derived from it report its error instead of context.Canceled.
+	workerStopContext struct {
+		context.Context
+		done chan struct{}
+		sync.Mutex
+		err error
 	}
 )
 
+var _ ActivityInterceptor = (*activityEnvironmentInterceptor)(nil)
+
 const activityEnvContextKey = "activityEnv"
 const activityOptionsContextKey = "activityOptions"
 
@@ -93,6 +124,108 @@ func getActivityEnv(ctx context.Context) *activityEnvironment {
 	return env.(*activityEnvironment)
 }
 
+func getActivityInterceptor(ctx context.Context) ActivityInterceptor {
+	env := getActivityEnv(ctx)
+	if env.interceptor == nil {
+		return &activityEnvironmentInterceptor{}
+	}
+	return env.interceptor
+}
+
+// newActivityInterceptorChain creates the interceptors in the order of the factories configured for the activity
+// context, so that the first factory creates the outermost interceptor.
+func newActivityInterceptorChain(ctx context.Context, fn interface{}) ActivityInterceptor {
+	var interceptor ActivityInterceptor = &activityEnvironmentInterceptor{fn: fn}
+	env, ok := ctx.Value(activityEnvContextKey).(*activityEnvironment)
+	if !ok {
+		return interceptor
+	}
+	info := GetActivityInfo(ctx)
+	for i := len(env.interceptors) - 1; i >= 0; i-- {
+		interceptor = env.interceptors[i].NewInterceptor(&info, interceptor)
+	}
+	env.interceptor = interceptor
+	return interceptor
+}
+
+func (a *activityEnvironmentInterceptor) ExecuteActivity(ctx context.Context, activityType string, args ...interface{}) (interface{}, error) {
+	fnType := reflect.TypeOf(a.fn)
+	argValues := []reflect.Value{}
+
+	// activities optionally might not take context.
+	fnArgIndex := 0
+	if fnType.NumIn() > 0 && isActivityContext(fnType.In(0)) {
+		argValues = append(argValues, reflect.ValueOf(ctx))
+		fnArgIndex++
+	}
+	for i, arg := range args {
+		if arg == nil {
+			argValues = append(argValues, reflect.Zero(fnType.In(fnArgIndex+i)))
+		} else {
+			argValues = append(argValues, reflect.ValueOf(arg))
+		}
+	}
+
+	// Invoke the activity with arguments.
+	fnValue := reflect.ValueOf(a.fn)
+	retValues := fnValue.Call(argValues)
+	return validateFunctionAndGetResult(a.fn, retValues)
+}
+
+func (a *activityEnvironmentInterceptor) RecordHeartbeat(ctx context.Context, details ...interface{}) {
+	var data []byte
+	var err error
+	// We would like to be a able to pass in "nil" as part of details(that is no progress to report to)
+	if len(details) != 1 || details[0] != nil {
+		data, err = getHostEnvironment().encodeArgs(details)
+		if err != nil {
+			panic(err)
+		}
+	}
+	env := getActivityEnv(ctx)
+	err = env.serviceInvoker.Heartbeat(data)
+	if err != nil {
+		log := GetActivityLogger(ctx)
+		log.Debug("RecordActivityHeartbeat With Error:", zap.Error(err))
+	}
+}
+
+// newWorkerStopContext returns a context that is cancelled with ErrWorkerStopping when workerStopCh is closed, with
+// the error of parent when parent is done, or with context.Canceled when the returned cancel function is called.
+func newWorkerStopContext(parent context.Context, workerStopCh <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx := &workerStopContext{Context: parent, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-workerStopCh:
+			ctx.cancel(ErrWorkerStopping)
+		case <-parent.Done():
+			ctx.cancel(parent.Err())
+		case <-ctx.done:
+		}
+	}()
+	return ctx, func() { ctx.cancel(context.Canceled) }
+}
+
+func (c *workerStopContext) cancel(err error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+}
+
+func (c *workerStopContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *workerStopContext) Err() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.err
+}
+
 func getActivityOptions(ctx Context) *executeActivityParameters {
 	eap := ctx.Value(activityOptionsContextKey)
 	if eap == nil {
@@ -136,15 +269,19 @@ func validateFunctionArgs(f interface{}, args []interface{}, isWorkflow bool) er
 	if fType.Kind() != reflect.Func {
 		return fmt.Errorf("Provided type: %v is not a function type", f)
 	}
-	fnName := getFunctionName(f)
+	return validateFunctionTypeArgs(fType, getFunctionName(f), 0, args, isWorkflow)
+}
 
-	fnArgIndex := 0
+// validateFunctionTypeArgs validates the arguments against the parameters of the function type from fnArgIndex on,
+// so that the receiver of a method expression can be skipped.
+func validateFunctionTypeArgs(
+	fType reflect.Type, fnName string, fnArgIndex int, args []interface{}, isWorkflow bool,
+) error {
 	// Skip Context function argument.
-	if fType.NumIn() > 0 {
-		if isWorkflow && isWorkflowContext(fType.In(0)) {
+	if fType.NumIn() > fnArgIndex {
+		if isWorkflow && isWorkflowContext(fType.In(fnArgIndex)) {
 			fnArgIndex++
-		}
-		if !isWorkflow && isActivityContext(fType.In(0)) {
+		} else if !isWorkflow && isActivityContext(fType.In(fnArgIndex)) {
 			fnArgIndex++
 		}
 	}
@@ -198,31 +335,71 @@ func validateFunctionResults(f interface{}, result interface{}) ([]byte, error)
 }
 
 func getValidatedActivityFunction(f interface{}, args []interface{}) (*ActivityType, []byte, error) {
-	fnName := ""
+	fnName, err := getValidatedActivityType(getHostEnvironment(), f, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input, err := getHostEnvironment().encodeArgs(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ActivityType{Name: fnName}, input, nil
+}
+
+// getValidatedActivityType validates the arguments against the activity function and returns the activity type name
+// given by getActivityFunctionName.
+func getValidatedActivityType(registry *hostEnvImpl, f interface{}, args []interface{}) (string, error) {
 	fType := reflect.TypeOf(f)
 	switch fType.Kind() {
 	case reflect.String:
-		fnName = reflect.ValueOf(f).String()
+		return reflect.ValueOf(f).String(), nil
 
 	case reflect.Func:
-		if err := validateFunctionArgs(f, args, false); err != nil {
-			return nil, nil, err
+		fnName := getActivityFunctionName(registry, f)
+		// The method expression of an activity registered from a struct takes the receiver as its first argument.
+		fnArgIndex := 0
+		if isMethodExpression(f) {
+			fnArgIndex = 1
 		}
-		fnName = getFunctionName(f)
-		if alias, ok := getHostEnvironment().getActivityAlias(fnName); ok {
-			fnName = alias
+		if err := validateFunctionTypeArgs(fType, fnName, fnArgIndex, args, false); err != nil {
+			return "", err
 		}
+		return fnName, nil
 
 	default:
-		return nil, nil, fmt.Errorf(
+		return "", fmt.Errorf(
 			"Invalid type 'f' parameter provided, it can be either activity function or name of the activity: %v", f)
 	}
+}
+
+// isMethodExpression tells whether f is a method expression, such as (*T).Method, rather than a plain function.
+func isMethodExpression(f interface{}) bool {
+	fnType := reflect.TypeOf(f)
+	if fnType.NumIn() == 0 {
+		return false
+	}
+	fnName := getFunctionName(f)
+	method, ok := fnType.In(0).MethodByName(fnName[strings.LastIndex(fnName, ".")+1:])
+	return ok && method.Func.IsValid() && method.Func.Pointer() == reflect.ValueOf(f).Pointer()
+}
 
-	input, err := getHostEnvironment().encodeArgs(args)
-	if err != nil {
-		return nil, nil, err
+// getActivityFunctionName returns the activity type name of the function. The methods of a struct are named after
+// their method expression, whether they are passed as method expressions or method values and whatever the registry
+// holds, since the calling side doesn't have to register the struct. The other functions resolve their alias.
+func getActivityFunctionName(registry *hostEnvImpl, f interface{}) string {
+	fnName := getFunctionName(f)
+	// The compiler names the method values after the method expression with a "-fm" suffix.
+	if strings.HasSuffix(fnName, "-fm") {
+		return strings.TrimSuffix(fnName, "-fm")
 	}
-	return &ActivityType{Name: fnName}, input, nil
+	if isMethodExpression(f) {
+		return fnName
+	}
+	if alias, ok := registry.getActivityAlias(fnName); ok {
+		return alias
+	}
+	return fnName
 }
 
 func isActivityContext(inType reflect.Type) bool {
@@ -231,6 +408,20 @@ func isActivityContext(inType reflect.Type) bool {
 }
 
 func validateFunctionAndGetResults(f interface{}, values []reflect.Value) ([]byte, error) {
+	result, err := validateFunctionAndGetResult(f, values)
+	if result == nil {
+		return nil, err
+	}
+	data, encodeErr := getHostEnvironment().encodeArg(result)
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
+	return data, err
+}
+
+// validateFunctionAndGetResult returns the result value and the error returned by the function. The result is nil if
+// the function only returns error or if it returned a nil pointer.
+func validateFunctionAndGetResult(f interface{}, values []reflect.Value) (interface{}, error) {
 	fnName := getFunctionName(f)
 	resultSize := len(values)
 
@@ -240,17 +431,13 @@ func validateFunctionAndGetResults(f interface{}, values []reflect.Value) ([]byt
 			fnName, resultSize)
 	}
 
-	var result []byte
-	var err error
+	var result interface{}
 
 	// Parse result
 	if resultSize > 1 {
 		retValue := values[0]
 		if retValue.Kind() != reflect.Ptr || !retValue.IsNil() {
-			result, err = getHostEnvironment().encodeArg(retValue.Interface())
-			if err != nil {
-				return nil, err
-			}
+			result = retValue.Interface()
 		}
 	}
 
@@ -0,0 +1,133 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/common/metrics"
+	"golang.org/x/time/rate"
+)
+
+type (
+	// activityLimits are the limits of an activity type, set by RegisterActivityOptions.
+	activityLimits struct {
+		maxExecutionsPerSecond  float64
+		maxConcurrentExecutions int
+	}
+
+	// activityThrottler delays the activity tasks of a worker that are over the limits of their activity type. The
+	// activity task poller throttles the tasks before they are processed, see taskThrottler.
+	activityThrottler struct {
+		sync.Mutex
+		metricsScope tally.Scope
+		limiters     map[string]*activityTypeLimiter
+	}
+
+	// activityTypeLimiter enforces the limits of an activity type.
+	activityTypeLimiter struct {
+		rateLimiter *rate.Limiter // nil without rate limit
+		slots       chan struct{} // nil without concurrency limit, a token per running execution otherwise
+	}
+)
+
+func newActivityThrottler(metricsScope tally.Scope) *activityThrottler {
+	return &activityThrottler{
+		metricsScope: metricsScope,
+		limiters:     make(map[string]*activityTypeLimiter),
+	}
+}
+
+func (t *activityThrottler) getLimiter(activityType string, limits activityLimits) *activityTypeLimiter {
+	t.Lock()
+	defer t.Unlock()
+	limiter, ok := t.limiters[activityType]
+	if !ok {
+		limiter = &activityTypeLimiter{}
+		if limits.maxExecutionsPerSecond > 0 {
+			limiter.rateLimiter = rate.NewLimiter(rate.Limit(limits.maxExecutionsPerSecond), 1)
+		}
+		if limits.maxConcurrentExecutions > 0 {
+			limiter.slots = make(chan struct{}, limits.maxConcurrentExecutions)
+		}
+		t.limiters[activityType] = limiter
+	}
+	return limiter
+}
+
+// acquire waits until an execution of the activity type is within its limits, and returns the function releasing the
+// execution when it completes. It fails if the context is done first.
+func (t *activityThrottler) acquire(ctx context.Context, activityType string, limits activityLimits) (release func(), err error) {
+	if limits == (activityLimits{}) {
+		return func() {}, nil
+	}
+	limiter := t.getLimiter(activityType, limits)
+	startTime := time.Now()
+	defer func() {
+		tagScope(t.metricsScope, tagActivityType, activityType).
+			Timer(metrics.ActivityThrottleLatency).Record(time.Now().Sub(startTime))
+	}()
+
+	release = func() {}
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- struct{}{}:
+			release = func() { <-limiter.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if limiter.rateLimiter != nil {
+		if err := limiter.rateLimiter.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// tryAcquire is acquire without waiting, it fails if the activity type is over its limits.
+func (t *activityThrottler) tryAcquire(activityType string, limits activityLimits) (release func(), ok bool) {
+	if limits == (activityLimits{}) {
+		return func() {}, true
+	}
+	limiter := t.getLimiter(activityType, limits)
+
+	release = func() {}
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- struct{}{}:
+			release = func() { <-limiter.slots }
+		default:
+			return nil, false
+		}
+	}
+	if limiter.rateLimiter != nil && !limiter.rateLimiter.Allow() {
+		release()
+		return nil, false
+	}
+	return release, true
+}
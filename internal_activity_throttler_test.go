@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestActivityThrottlerConcurrency(t *testing.T) {
+	throttler := newActivityThrottler(tally.NoopScope)
+	limits := activityLimits{maxConcurrentExecutions: 1}
+
+	release, err := throttler.acquire(context.Background(), "testActivity", limits)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = throttler.acquire(ctx, "testActivity", limits)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Other activity types have their own limits.
+	releaseOther, err := throttler.acquire(context.Background(), "otherActivity", limits)
+	require.NoError(t, err)
+	releaseOther()
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release()
+		close(released)
+	}()
+	release, err = throttler.acquire(context.Background(), "testActivity", limits)
+	require.NoError(t, err)
+	<-released
+	release()
+}
+
+func TestActivityThrottlerRate(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	throttler := newActivityThrottler(scope)
+	limits := activityLimits{maxExecutionsPerSecond: 20}
+
+	startTime := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := throttler.acquire(context.Background(), "testActivity", limits)
+		require.NoError(t, err)
+		release()
+	}
+	assert.True(t, time.Now().Sub(startTime) >= 90*time.Millisecond)
+	assert.Equal(t, 1, len(scope.Snapshot().Timers()))
+
+	// Activities without limits don't wait.
+	release, err := throttler.acquire(context.Background(), "unlimitedActivity", activityLimits{})
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, 1, len(scope.Snapshot().Timers()))
+}
+
+func TestActivityThrottlerTryAcquire(t *testing.T) {
+	throttler := newActivityThrottler(tally.NoopScope)
+
+	concurrencyLimits := activityLimits{maxConcurrentExecutions: 1}
+	release, ok := throttler.tryAcquire("testActivity", concurrencyLimits)
+	require.True(t, ok)
+	_, ok = throttler.tryAcquire("testActivity", concurrencyLimits)
+	assert.False(t, ok)
+	release()
+	release, ok = throttler.tryAcquire("testActivity", concurrencyLimits)
+	require.True(t, ok)
+	release()
+
+	// A task over the rate limit doesn't keep a concurrency slot.
+	limits := activityLimits{maxExecutionsPerSecond: 1, maxConcurrentExecutions: 1}
+	release, ok = throttler.tryAcquire("rateLimitedActivity", limits)
+	require.True(t, ok)
+	release()
+	_, ok = throttler.tryAcquire("rateLimitedActivity", limits)
+	assert.False(t, ok)
+	assert.Equal(t, 0, len(throttler.getLimiter("rateLimitedActivity", limits).slots))
+
+	_, ok = throttler.tryAcquire("unlimitedActivity", activityLimits{})
+	assert.True(t, ok)
+}
+
+func TestActivityLimitsRegistration(t *testing.T) {
+	registry := newRegistry()
+	assert.Error(t, registry.RegisterActivityWithOptions(testActivity, RegisterActivityOptions{
+		Name:                   "testLimitedActivity",
+		MaxExecutionsPerSecond: -1,
+	}))
+	require.NoError(t, registry.RegisterActivityWithOptions(testActivity, RegisterActivityOptions{
+		Name:                    "testLimitedActivity",
+		MaxExecutionsPerSecond:  5,
+		MaxConcurrentExecutions: 2,
+	}))
+	assert.Equal(t, activityLimits{maxExecutionsPerSecond: 5, maxConcurrentExecutions: 2},
+		registry.getActivityLimits("testLimitedActivity"))
+	assert.Equal(t, activityLimits{}, registry.getActivityLimits("testActivity"))
+}
@@ -588,6 +588,29 @@ func TestPanic(t *testing.T) {
 	require.Contains(t, err.StackTrace(), "cadence.TestPanic")
 }
 
+func TestDeadlockDetection(t *testing.T) {
+	blocked := make(chan struct{})
+	d := newDispatcherWithDeadlockDetection(background, 100*time.Millisecond, func(ctx Context) {
+		c := NewNamedChannel(ctx, "forever_blocked")
+		GoNamed(ctx, "deadlocked", func(ctx Context) {
+			<-blocked // native channel doesn't yield
+		})
+		c.Receive(ctx, nil) // blocked forever
+	})
+	err := d.ExecuteUntilAllBlocked()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "Potential deadlock detected")
+	require.Contains(t, err.Error(), `"deadlocked"`)
+	require.Contains(t, err.StackTrace(), "coroutine deadlocked [deadlocked]:")
+	require.Contains(t, err.StackTrace(), "cadence.TestDeadlockDetection")
+	require.Contains(t, err.StackTrace(), "coroutine 1 [blocked on forever_blocked.Receive]:")
+
+	// the deadlocked coroutine is not called again
+	require.Equal(t, err, d.ExecuteUntilAllBlocked())
+	close(blocked)
+	d.Close()
+}
+
 func TestFutureSetValue(t *testing.T) {
 	var history []string
 	var f Future
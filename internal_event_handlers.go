@@ -79,17 +79,20 @@ type (
 		counterID         int32     // To generate sequence IDs for activity/timer etc.
 		currentReplayTime time.Time // Indicates current replay time of the decision.
 
-		completeHandler completionHandler               // events completion handler
-		cancelHandler   func()                          // A cancel handler to be invoked on a cancel notification
-		signalHandler   func(name string, input []byte) // A signal handler to be invoked on a signal event
-		queryHandler    func(queryType string, queryArgs []byte) ([]byte, error)
+		completeHandler completionHandler // events completion handler
+		cancelHandler   func()            // A cancel handler to be invoked on a cancel notification
+		// A signal handler to be invoked on a signal event with the header sent with the signal
+		signalHandler func(name string, input []byte, header *m.Header)
+		queryHandler  func(queryType string, queryArgs []byte) ([]byte, error)
 
 		logger                *zap.Logger
 		isReplay              bool // flag to indicate if workflow is in replay mode
 		enableLoggingInReplay bool // flag to indicate if workflow should enable logging in replay mode
 
-		metricsScope tally.Scope
-		hostEnv      *hostEnvImpl
+		metricsScope             tally.Scope
+		hostEnv                  *hostEnvImpl
+		workflowInterceptors     []WorkflowInterceptorFactory
+		deadlockDetectionTimeout time.Duration
 	}
 
 	// wrapper around zapcore.Core that will be aware of replay
@@ -125,15 +128,19 @@ func newWorkflowExecutionEventHandler(
 	enableLoggingInReplay bool,
 	scope tally.Scope,
 	hostEnv *hostEnvImpl,
+	workflowInterceptors []WorkflowInterceptorFactory,
+	deadlockDetectionTimeout time.Duration,
 ) workflowExecutionEventHandler {
 	context := &workflowEnvironmentImpl{
-		workflowInfo:          workflowInfo,
-		decisionsHelper:       newDecisionsHelper(),
-		sideEffectResult:      make(map[int32][]byte),
-		changeVersions:        make(map[string]Version),
-		completeHandler:       completeHandler,
-		enableLoggingInReplay: enableLoggingInReplay,
-		hostEnv:               hostEnv,
+		workflowInfo:             workflowInfo,
+		decisionsHelper:          newDecisionsHelper(),
+		sideEffectResult:         make(map[int32][]byte),
+		changeVersions:           make(map[string]Version),
+		completeHandler:          completeHandler,
+		enableLoggingInReplay:    enableLoggingInReplay,
+		hostEnv:                  hostEnv,
+		workflowInterceptors:     workflowInterceptors,
+		deadlockDetectionTimeout: deadlockDetectionTimeout,
 	}
 	context.logger = logger.With(
 		zapcore.Field{Key: tagWorkflowType, Type: zapcore.StringType, String: workflowInfo.WorkflowType.Name},
@@ -223,6 +230,7 @@ func (wc *workflowEnvironmentImpl) ExecuteChildWorkflow(
 	attributes.Input = options.input
 	attributes.WorkflowType = workflowTypePtr(*options.workflowType)
 	attributes.ChildPolicy = options.childPolicy.toThriftChildPolicyPtr()
+	attributes.Header = options.header
 
 	decision := wc.decisionsHelper.startChildWorkflowExecution(attributes)
 	decision.setData(&scheduledChildWorkflow{
@@ -238,7 +246,7 @@ func (wc *workflowEnvironmentImpl) ExecuteChildWorkflow(
 	return nil
 }
 
-func (wc *workflowEnvironmentImpl) RegisterSignalHandler(handler func(name string, input []byte)) {
+func (wc *workflowEnvironmentImpl) RegisterSignalHandler(handler func(name string, input []byte, header *m.Header)) {
 	wc.signalHandler = handler
 }
 
@@ -254,6 +262,22 @@ func (wc *workflowEnvironmentImpl) GetMetricsScope() tally.Scope {
 	return wc.metricsScope
 }
 
+func (wc *workflowEnvironmentImpl) IsReplaying() bool {
+	return wc.isReplay
+}
+
+func (wc *workflowEnvironmentImpl) GetWorkflowInterceptors() []WorkflowInterceptorFactory {
+	return wc.workflowInterceptors
+}
+
+func (wc *workflowEnvironmentImpl) GetDeadlockDetectionTimeout() time.Duration {
+	return wc.deadlockDetectionTimeout
+}
+
+func (wc *workflowEnvironmentImpl) GetRegistry() *hostEnvImpl {
+	return wc.hostEnv
+}
+
 func (wc *workflowEnvironmentImpl) GenerateSequenceID() string {
 	return fmt.Sprintf("%d", wc.GenerateSequence())
 }
@@ -285,6 +309,7 @@ func (wc *workflowEnvironmentImpl) ExecuteActivity(parameters executeActivityPar
 	scheduleTaskAttr.StartToCloseTimeoutSeconds = common.Int32Ptr(parameters.StartToCloseTimeoutSeconds)
 	scheduleTaskAttr.ScheduleToStartTimeoutSeconds = common.Int32Ptr(parameters.ScheduleToStartTimeoutSeconds)
 	scheduleTaskAttr.HeartbeatTimeoutSeconds = common.Int32Ptr(parameters.HeartbeatTimeoutSeconds)
+	scheduleTaskAttr.Header = parameters.Header
 
 	decision := wc.decisionsHelper.scheduleActivityTask(scheduleTaskAttr)
 	decision.setData(&scheduledActivity{
@@ -604,7 +629,7 @@ func (weh *workflowExecutionEventHandlerImpl) handleWorkflowExecutionStarted(
 	}
 
 	// Invoke the workflow.
-	weh.workflowDefinition.Execute(weh, attributes.Input)
+	weh.workflowDefinition.Execute(weh, attributes.Header, attributes.Input)
 	return nil
 }
 
@@ -712,7 +737,7 @@ func (weh *workflowExecutionEventHandlerImpl) handleMarkerRecorded(
 
 func (weh *workflowExecutionEventHandlerImpl) handleWorkflowExecutionSignaled(
 	attributes *m.WorkflowExecutionSignaledEventAttributes) {
-	weh.signalHandler(attributes.GetSignalName(), attributes.Input)
+	weh.signalHandler(attributes.GetSignalName(), attributes.Input, attributes.Header)
 }
 
 func (weh *workflowExecutionEventHandlerImpl) handleStartChildWorkflowExecutionFailed(event *m.HistoryEvent) error {
@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/common/metrics"
+	"go.uber.org/zap"
+)
+
+type (
+	// faultInjector applies the fault injection rules of a worker, a nil faultInjector injects no fault.
+	faultInjector struct {
+		sync.Mutex
+		rules        []FaultInjectionRule
+		counts       []int // The number of times each rule applied.
+		logger       *zap.Logger
+		metricsScope tally.Scope
+		stopC        chan struct{} // Closed when the worker stops, it interrupts the injected latencies.
+		stopOnce     sync.Once
+	}
+)
+
+var faultInjectionTargetNames = map[FaultInjectionTarget]string{
+	FaultInjectionTargetPoll:              "poll",
+	FaultInjectionTargetRespond:           "respond",
+	FaultInjectionTargetHeartbeat:         "heartbeat",
+	FaultInjectionTargetHistoryFetch:      "history-fetch",
+	FaultInjectionTargetActivityExecution: "activity-execution",
+}
+
+func newFaultInjector(rules []FaultInjectionRule, logger *zap.Logger, metricsScope tally.Scope) *faultInjector {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &faultInjector{
+		rules:        append([]FaultInjectionRule(nil), rules...),
+		counts:       make([]int, len(rules)),
+		logger:       logger,
+		metricsScope: metricsScope,
+		stopC:        make(chan struct{}),
+	}
+}
+
+func validateFaultInjectionRules(rules []FaultInjectionRule) error {
+	for _, rule := range rules {
+		if _, ok := faultInjectionTargetNames[rule.Target]; !ok {
+			return errors.New("invalid fault injection Target")
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return errors.New("fault injection Probability is not between 0 and 1")
+		}
+		if rule.Latency < 0 {
+			return errors.New("negative fault injection Latency")
+		}
+		if rule.MaxCount < 0 {
+			return errors.New("negative fault injection MaxCount")
+		}
+	}
+	return nil
+}
+
+// inject applies the rules matching the operation: it sleeps for their latencies, or until the worker stops, and
+// returns the first of their errors, nil if the operation must be performed.
+func (f *faultInjector) inject(target FaultInjectionTarget, workflowType, activityType string) error {
+	if f == nil {
+		return nil
+	}
+	var latency time.Duration
+	var err error
+	f.Lock()
+	for i, rule := range f.rules {
+		if !f.matchLocked(i, target, workflowType, activityType) {
+			continue
+		}
+		f.counts[i]++
+		latency += rule.Latency
+		if err == nil {
+			err = rule.Error
+		}
+		f.logger.Info("Injected fault.",
+			zap.String(tagFaultTarget, faultInjectionTargetNames[target]),
+			zap.String(tagWorkflowType, workflowType),
+			zap.String(tagActivityType, activityType),
+			zap.Duration("Latency", rule.Latency),
+			zap.Error(rule.Error))
+		tagScope(f.metricsScope, tagFaultTarget, faultInjectionTargetNames[target]).Counter(metrics.FaultInjectedCounter).Inc(1)
+	}
+	f.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-f.stopC:
+		}
+	}
+	return err
+}
+
+// stop interrupts the injected latencies once the worker stops.
+func (f *faultInjector) stop() {
+	if f == nil {
+		return
+	}
+	f.stopOnce.Do(func() { close(f.stopC) })
+}
+
+func (f *faultInjector) matchLocked(i int, target FaultInjectionTarget, workflowType, activityType string) bool {
+	rule := f.rules[i]
+	if rule.Target != target ||
+		(rule.WorkflowType != "" && rule.WorkflowType != workflowType) ||
+		(rule.ActivityType != "" && rule.ActivityType != activityType) ||
+		(rule.MaxCount > 0 && f.counts[i] >= rule.MaxCount) {
+		return false
+	}
+	return rule.Probability == 0 || rand.Float64() < rule.Probability
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+)
+
+func TestFaultInjector(t *testing.T) {
+	injectedErr := errors.New("injected")
+	faults := newFaultInjector([]FaultInjectionRule{
+		{Target: FaultInjectionTargetRespond, WorkflowType: "testWorkflow", Error: injectedErr, MaxCount: 2},
+		{Target: FaultInjectionTargetHeartbeat, Latency: 50 * time.Millisecond},
+		{Target: FaultInjectionTargetPoll, Probability: 0.000001, Error: injectedErr},
+	}, getLogger(), nil)
+
+	// The rules apply to their target and types, up to their max count.
+	assert.NoError(t, faults.inject(FaultInjectionTargetRespond, "otherWorkflow", ""))
+	assert.NoError(t, faults.inject(FaultInjectionTargetRespond, "", "testActivity"))
+	assert.Equal(t, injectedErr, faults.inject(FaultInjectionTargetRespond, "testWorkflow", ""))
+	assert.Equal(t, injectedErr, faults.inject(FaultInjectionTargetRespond, "testWorkflow", ""))
+	assert.NoError(t, faults.inject(FaultInjectionTargetRespond, "testWorkflow", ""))
+	assert.NoError(t, faults.inject(FaultInjectionTargetHistoryFetch, "testWorkflow", ""))
+
+	startTime := time.Now()
+	assert.NoError(t, faults.inject(FaultInjectionTargetHeartbeat, "", "testActivity"))
+	assert.True(t, time.Now().Sub(startTime) >= 50*time.Millisecond)
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, faults.inject(FaultInjectionTargetPoll, "testWorkflow", ""))
+	}
+
+	// The worker stop interrupts the latencies.
+	faults = newFaultInjector([]FaultInjectionRule{{Target: FaultInjectionTargetPoll, Latency: time.Minute}}, getLogger(), nil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		faults.stop()
+	}()
+	startTime = time.Now()
+	assert.NoError(t, faults.inject(FaultInjectionTargetPoll, "testWorkflow", ""))
+	assert.True(t, time.Now().Sub(startTime) < time.Second)
+	faults.stop()
+
+	// No rule, no fault.
+	var noFaults *faultInjector
+	noFaults.stop()
+	assert.Nil(t, newFaultInjector(nil, getLogger(), nil))
+	assert.NoError(t, noFaults.inject(FaultInjectionTargetPoll, "testWorkflow", ""))
+}
+
+func TestFaultInjectionRulesValidation(t *testing.T) {
+	assert.NoError(t, validateFaultInjectionRules([]FaultInjectionRule{{Target: FaultInjectionTargetActivityExecution}}))
+	assert.Error(t, validateFaultInjectionRules([]FaultInjectionRule{{Target: FaultInjectionTarget(100)}}))
+	assert.Error(t, validateFaultInjectionRules([]FaultInjectionRule{{Probability: 1.5}}))
+	assert.Error(t, validateFaultInjectionRules([]FaultInjectionRule{{Latency: -time.Second}}))
+	assert.Error(t, validateFaultInjectionRules([]FaultInjectionRule{{MaxCount: -1}}))
+	assert.Panics(t, func() {
+		mockCtrl := gomock.NewController(t)
+		NewWorker(workflowservicetest.NewMockClient(mockCtrl), "testDomain", "testTaskList", WorkerOptions{
+			FaultInjectionRules: []FaultInjectionRule{{Probability: -1}},
+		})
+	})
+}
+
+func TestFaultInjectionActivityExecution(t *testing.T) {
+	a := &testActivityDeadline{}
+	hostEnv := newRegistry()
+	hostEnv.addActivity(a.ActivityType().Name, a)
+	mockCtrl := gomock.NewController(t)
+	mockService := workflowservicetest.NewMockClient(mockCtrl)
+
+	params := workerExecutionParameters{
+		Logger: getLogger(),
+		FaultInjector: newFaultInjector([]FaultInjectionRule{{
+			Target:       FaultInjectionTargetActivityExecution,
+			ActivityType: "test",
+			Error:        errors.New("injected"),
+		}}, getLogger(), nil),
+	}
+	activityHandler := newActivityTaskHandler(mockService, "testDomain", params, hostEnv)
+	result, err := activityHandler.Execute(&s.PollForActivityTaskResponse{
+		TaskToken:                     []byte("token"),
+		WorkflowExecution:             &s.WorkflowExecution{WorkflowId: common.StringPtr("wID"), RunId: common.StringPtr("rID")},
+		ActivityType:                  &s.ActivityType{Name: common.StringPtr("test")},
+		ActivityId:                    common.StringPtr("0"),
+		ScheduledTimestamp:            common.Int64Ptr(time.Now().UnixNano()),
+		ScheduleToCloseTimeoutSeconds: common.Int32Ptr(10),
+		StartedTimestamp:              common.Int64Ptr(time.Now().UnixNano()),
+		StartToCloseTimeoutSeconds:    common.Int32Ptr(10),
+	})
+	require.NoError(t, err)
+	failedRequest, ok := result.(*s.RespondActivityTaskFailedRequest)
+	require.True(t, ok)
+	assert.Equal(t, []byte("injected"), failedRequest.Details)
+}
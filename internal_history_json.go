@@ -0,0 +1,247 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	s "go.uber.org/cadence/.gen/go/shared"
+)
+
+// historyJSON is the JSON object of a history, see HistoryJSONFormatVersion.
+type historyJSON struct {
+	FormatVersion int               `json:"formatVersion,omitempty"`
+	Events        []json.RawMessage `json:"events"`
+}
+
+var (
+	historyEventType = reflect.TypeOf(s.HistoryEvent{})
+	payloadType      = reflect.TypeOf([]byte(nil))
+)
+
+func exportHistoryJSON(w io.Writer, history *s.History, options HistoryJSONOptions) error {
+	if history == nil {
+		return fmt.Errorf("nil history")
+	}
+	exported := historyJSON{
+		FormatVersion: HistoryJSONFormatVersion,
+		Events:        make([]json.RawMessage, 0, len(history.Events)),
+	}
+	for _, event := range history.Events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if options.DecodePayloads {
+			if data, err = convertPayloads(historyEventType, data, decodePayload); err != nil {
+				return err
+			}
+		}
+		exported.Events = append(exported.Events, data)
+	}
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func importHistoryJSON(r io.Reader) (*s.History, error) {
+	var imported historyJSON
+	if err := json.NewDecoder(r).Decode(&imported); err != nil {
+		return nil, err
+	}
+	if imported.FormatVersion > HistoryJSONFormatVersion {
+		return nil, fmt.Errorf("unsupported history JSON format version %d", imported.FormatVersion)
+	}
+	history := &s.History{Events: make([]*s.HistoryEvent, 0, len(imported.Events))}
+	for i, data := range imported.Events {
+		data, err := convertPayloads(historyEventType, data, encodePayload)
+		if err != nil {
+			return nil, fmt.Errorf("history event %d: %v", i, err)
+		}
+		event := &s.HistoryEvent{}
+		if err := json.Unmarshal(data, event); err != nil {
+			return nil, fmt.Errorf("history event %d: %v", i, err)
+		}
+		history.Events = append(history.Events, event)
+	}
+	return history, nil
+}
+
+func importHistoryJSONFile(fileName string) (*s.History, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return importHistoryJSON(file)
+}
+
+// convertPayloads returns the JSON value of type t with the values of its payload fields replaced by convert. The
+// fields of the structs are kept in order.
+func convertPayloads(
+	t reflect.Type,
+	data json.RawMessage,
+	convert func(json.RawMessage) (json.RawMessage, error),
+) (json.RawMessage, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == payloadType {
+		return convert(data)
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return data, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			value, ok := fields[name]
+			if name == "" || name == "-" || !ok {
+				continue
+			}
+			value, err := convertPayloads(field.Type, value, convert)
+			if err != nil {
+				return nil, err
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+
+	case reflect.Slice:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return nil, err
+		}
+		for i, element := range elements {
+			element, err := convertPayloads(t.Elem(), element, convert)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return json.Marshal(elements)
+
+	case reflect.Map:
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		for key, entry := range entries {
+			entry, err := convertPayloads(t.Elem(), entry, convert)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = entry
+		}
+		return json.Marshal(entries)
+
+	default:
+		return data, nil
+	}
+}
+
+// decodePayload converts the base64 string of a payload to the array of the JSON values it holds, when the values
+// encode back to the payload.
+func decodePayload(data json.RawMessage) (json.RawMessage, error) {
+	var payload []byte
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if len(payload) == 0 {
+		return data, nil
+	}
+	var values []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	for decoder.More() {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return data, nil
+		}
+		values = append(values, value)
+	}
+	decoded, err := json.Marshal(values)
+	if err != nil {
+		return data, nil
+	}
+	// The values are written escaped, the payload is kept as base64 if it doesn't hold them in the same form.
+	encoded, err := encodePayload(decoded)
+	if err != nil || !bytes.Equal(encoded, data) {
+		return data, nil
+	}
+	return decoded, nil
+}
+
+// encodePayload converts the array of the JSON values of a decoded payload to the base64 string of the payload.
+func encodePayload(data json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return data, nil
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(trimmed, &values); err != nil {
+		return nil, err
+	}
+	payload, err := encodePayloadValues(values)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
+
+// encodePayloadValues encodes the JSON values like the json encoding of the workflow and activity arguments and
+// results, each value in compact form followed by a newline.
+func encodePayloadValues(values []json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, value := range values {
+		if err := json.Compact(&buf, value); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
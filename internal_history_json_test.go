@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+)
+
+type historyJSONTestValue struct {
+	Name  string
+	Count int
+	HTML  string
+}
+
+func createHistoryJSONTestHistory(t *testing.T) *s.History {
+	input, err := jsonEncoding{}.Marshal([]interface{}{"hello", historyJSONTestValue{Name: "name", Count: 3, HTML: "<a&b>"}})
+	require.NoError(t, err)
+	history := createReplayTestHistory("replayTestActivity")
+	history.Events[0].Timestamp = common.Int64Ptr(1500000000000000000)
+	history.Events[0].WorkflowExecutionStartedEventAttributes.Input = input
+	// a payload that doesn't hold JSON values
+	history.Events[6].ActivityTaskCompletedEventAttributes.Result = []byte{0, 1, 2, 255}
+	// a payload that holds a JSON value not encoded like the arguments and results
+	history.Events[10].WorkflowExecutionCompletedEventAttributes.Result = []byte(`{ "a": 1 }`)
+	return history
+}
+
+func TestHistoryJSON_RoundTrip(t *testing.T) {
+	for _, decodePayloads := range []bool{false, true} {
+		history := createHistoryJSONTestHistory(t)
+		var buf bytes.Buffer
+		require.NoError(t, ExportHistoryJSON(&buf, history, HistoryJSONOptions{DecodePayloads: decodePayloads}))
+		exported := buf.String()
+		require.Contains(t, exported, `"formatVersion": 1`)
+		require.Contains(t, exported, `"eventType": "WorkflowExecutionStarted"`)
+		require.Contains(t, exported, `"eventType": "ActivityTaskScheduled"`)
+		require.Equal(t, decodePayloads, strings.Contains(exported, `"hello"`), exported)
+
+		imported, err := ImportHistoryJSON(strings.NewReader(exported))
+		require.NoError(t, err)
+		require.True(t, history.Equals(imported), exported)
+	}
+}
+
+func TestHistoryJSON_DecodedPayloads(t *testing.T) {
+	history := createHistoryJSONTestHistory(t)
+	var buf bytes.Buffer
+	require.NoError(t, ExportHistoryJSON(&buf, history, HistoryJSONOptions{DecodePayloads: true}))
+
+	var exported struct {
+		Events []map[string]json.RawMessage
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	require.Equal(t, 11, len(exported.Events))
+
+	var started struct {
+		Input []json.RawMessage
+	}
+	require.NoError(t, json.Unmarshal(exported.Events[0]["workflowExecutionStartedEventAttributes"], &started))
+	require.Equal(t, 2, len(started.Input))
+	var value historyJSONTestValue
+	require.NoError(t, json.Unmarshal(started.Input[1], &value))
+	require.Equal(t, historyJSONTestValue{Name: "name", Count: 3, HTML: "<a&b>"}, value)
+
+	// the payloads that don't hold JSON values in the encoded form are kept as base64
+	var completed struct {
+		Result string
+	}
+	require.NoError(t, json.Unmarshal(exported.Events[6]["activityTaskCompletedEventAttributes"], &completed))
+	require.Equal(t, "AAEC/w==", completed.Result)
+	require.NoError(t, json.Unmarshal(exported.Events[10]["workflowExecutionCompletedEventAttributes"], &completed))
+	require.Equal(t, "eyAiYSI6IDEgfQ==", completed.Result)
+}
+
+func TestHistoryJSON_Import(t *testing.T) {
+	// the plain JSON encoding of a history
+	history := createHistoryJSONTestHistory(t)
+	data, err := json.Marshal(history)
+	require.NoError(t, err)
+	imported, err := ImportHistoryJSON(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.True(t, history.Equals(imported))
+
+	_, err = ImportHistoryJSON(strings.NewReader(`{"formatVersion": 2, "events": []}`))
+	require.Error(t, err)
+	_, err = ImportHistoryJSON(strings.NewReader(`{"events": [{"eventType": "NoSuchEvent"}]}`))
+	require.Error(t, err)
+	_, err = ImportHistoryJSON(strings.NewReader(`not json`))
+	require.Error(t, err)
+}
+
+func TestHistoryJSON_Replay(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ExportHistoryJSON(&buf, createReplayTestHistory("replayTestActivity"),
+		HistoryJSONOptions{DecodePayloads: true}))
+	file, err := ioutil.TempFile("", "history")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	replayer := newReplayTestWorkflowReplayer()
+	require.NoError(t, replayer.ReplayWorkflowHistoryFromJSONFile(nil, file.Name()))
+
+	testSuite := WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflowWithOptions(replayTestWorkflow, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"})
+	require.NoError(t, env.ReplayWorkflowHistoryFromJSONFile(file.Name()))
+}
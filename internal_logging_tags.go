@@ -39,4 +39,6 @@ const (
 	tagChangeID        = "ChangeID"
 	tagVersion         = "Version"
 	tagChildWorkflowID = "ChildWorkflowID"
+	tagSignalName      = "SignalName"
+	tagFaultTarget     = "FaultTarget"
 )
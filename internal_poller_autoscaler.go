@@ -0,0 +1,125 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"sync"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/common/metrics"
+)
+
+// pollerScaleDownEmptyPolls is the number of consecutive empty polls after which a poller is removed.
+const pollerScaleDownEmptyPolls = 2
+
+type (
+	// pollerAutoScaler adjusts the number of pollers of a worker to the load of its task list, between the min and max
+	// poller counts. A poller is added when a poll reports a backlog while the worker has free task slots, and one is
+	// removed after consecutive empty polls.
+	pollerAutoScaler struct {
+		sync.Mutex
+		minPollerCount int
+		maxPollerCount int
+		pollerCount    int
+		emptyPolls     int
+		gauge          tally.Gauge
+	}
+
+	// pollerScaling is what a poller does after its poll.
+	pollerScaling int
+)
+
+const (
+	pollerScalingNone pollerScaling = iota
+	pollerScalingUp                 // start one more poller
+	pollerScalingDown               // stop the poller
+)
+
+func newPollerAutoScaler(minPollerCount, maxPollerCount int, metricsScope tally.Scope) *pollerAutoScaler {
+	if minPollerCount > maxPollerCount {
+		minPollerCount = maxPollerCount
+	}
+	s := &pollerAutoScaler{
+		minPollerCount: minPollerCount,
+		maxPollerCount: maxPollerCount,
+		pollerCount:    minPollerCount,
+		gauge:          metricsScope.Gauge(metrics.PollerCount),
+	}
+	s.gauge.Update(float64(s.pollerCount))
+	return s
+}
+
+// getPollerCount returns the current number of pollers.
+func (s *pollerAutoScaler) getPollerCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.pollerCount
+}
+
+// recordPoll records the result of a poll and returns how the number of pollers changes. A failed poll doesn't change
+// it, as the worker backs off from the service.
+func (s *pollerAutoScaler) recordPoll(task interface{}, err error, hasFreeSlots bool) pollerScaling {
+	if err != nil {
+		return pollerScalingNone
+	}
+	isEmpty, backlog := getPolledTaskBacklog(task)
+
+	s.Lock()
+	defer s.Unlock()
+	if isEmpty {
+		s.emptyPolls++
+		if s.emptyPolls < pollerScaleDownEmptyPolls || s.pollerCount <= s.minPollerCount {
+			return pollerScalingNone
+		}
+		s.emptyPolls = 0
+		s.pollerCount--
+		s.gauge.Update(float64(s.pollerCount))
+		return pollerScalingDown
+	}
+
+	s.emptyPolls = 0
+	if backlog <= 0 || !hasFreeSlots || s.pollerCount >= s.maxPollerCount {
+		return pollerScalingNone
+	}
+	s.pollerCount++
+	s.gauge.Update(float64(s.pollerCount))
+	return pollerScalingUp
+}
+
+// getPolledTaskBacklog tells whether a polled task is empty, and the backlog of its task list. Decision tasks carry
+// the backlog hint of the service. Activity tasks don't, so a polled activity task is taken as a sign of backlog.
+func getPolledTaskBacklog(task interface{}) (isEmpty bool, backlog int64) {
+	switch t := task.(type) {
+	case *workflowTask:
+		if t.task == nil {
+			return true, 0
+		}
+		return false, t.task.GetBacklogCountHint()
+	case *activityTask:
+		if t.task == nil {
+			return true, 0
+		}
+		return false, 1
+	}
+	return task == nil, 0
+}
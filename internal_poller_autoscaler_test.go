@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/cadence/common/metrics"
+)
+
+func TestPollerAutoScaler(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	scaler := newPollerAutoScaler(1, 3, scope)
+	assertGauge := func(expected int) {
+		gauges := scope.Snapshot().Gauges()
+		require.Equal(t, 1, len(gauges))
+		for _, g := range gauges {
+			assert.Equal(t, metrics.PollerCount, g.Name())
+			assert.Equal(t, float64(expected), g.Value())
+		}
+	}
+	assert.Equal(t, 1, scaler.getPollerCount())
+	assertGauge(1)
+
+	backlogged := &workflowTask{task: &shared.PollForDecisionTaskResponse{BacklogCountHint: common.Int64Ptr(10)}}
+	idle := &workflowTask{task: &shared.PollForDecisionTaskResponse{BacklogCountHint: common.Int64Ptr(0)}}
+	empty := &workflowTask{}
+
+	// Grows while the task list is backlogged and the worker has free slots, up to the max.
+	assert.Equal(t, pollerScalingUp, scaler.recordPoll(backlogged, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(backlogged, nil, false))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(idle, nil, true))
+	assert.Equal(t, pollerScalingUp, scaler.recordPoll(&activityTask{task: &shared.PollForActivityTaskResponse{}}, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(backlogged, nil, true))
+	assert.Equal(t, 3, scaler.getPollerCount())
+	assertGauge(3)
+
+	// Failed polls don't change the number of pollers.
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(nil, errors.New("poll failed"), true))
+
+	// Shrinks after consecutive empty polls, down to the min.
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(empty, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(idle, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(empty, nil, true))
+	assert.Equal(t, pollerScalingDown, scaler.recordPoll(&activityTask{}, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(empty, nil, true))
+	assert.Equal(t, pollerScalingDown, scaler.recordPoll(empty, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(empty, nil, true))
+	assert.Equal(t, pollerScalingNone, scaler.recordPoll(empty, nil, true))
+	assert.Equal(t, 1, scaler.getPollerCount())
+	assertGauge(1)
+}
+
+// testAutoScaledTaskPoller returns the tasks sent to it and counts the pollers waiting for a task.
+type testAutoScaledTaskPoller struct {
+	tasks   chan interface{}
+	polling int32
+}
+
+func (p *testAutoScaledTaskPoller) PollTask() (interface{}, error) {
+	atomic.AddInt32(&p.polling, 1)
+	defer atomic.AddInt32(&p.polling, -1)
+	return <-p.tasks, nil
+}
+
+func (p *testAutoScaledTaskPoller) ProcessTask(task interface{}) error {
+	return nil
+}
+
+func TestBaseWorkerPollerAutoScaling(t *testing.T) {
+	poller := &testAutoScaledTaskPoller{tasks: make(chan interface{})}
+	bw := newBaseWorker(baseWorkerOptions{
+		pollerCount:       3,
+		minPollerCount:    1,
+		autoScalePollers:  true,
+		maxConcurrentTask: 10,
+		maxTaskPerSecond:  1000,
+		taskWorker:        poller,
+		workerType:        "TestWorker",
+	}, getLogger(), tally.NoopScope)
+	bw.Start()
+	defer close(poller.tasks)
+	defer bw.Stop()
+
+	// waitForPollers waits until the given number of pollers are running and polling.
+	waitForPollers := func(expected int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt32(&poller.polling) != int32(expected) || bw.autoScaler.getPollerCount() != expected {
+			require.True(t, time.Now().Before(deadline), "expected %v pollers, got %v polling",
+				expected, atomic.LoadInt32(&poller.polling))
+			time.Sleep(time.Millisecond)
+		}
+	}
+	backlogged := &workflowTask{task: &shared.PollForDecisionTaskResponse{BacklogCountHint: common.Int64Ptr(10)}}
+
+	waitForPollers(1)
+	poller.tasks <- backlogged
+	waitForPollers(2)
+	poller.tasks <- backlogged
+	waitForPollers(3)
+	poller.tasks <- backlogged
+	waitForPollers(3)
+	assert.Equal(t, 3, bw.stats().PollerCount)
+
+	for i := 0; i < 2*pollerScaleDownEmptyPolls; i++ {
+		poller.tasks <- &workflowTask{}
+	}
+	waitForPollers(1)
+	assert.Equal(t, 1, bw.stats().PollerCount)
+}
+
+func TestPollerAutoScalerOptions(t *testing.T) {
+	assert.NoError(t, validateWorkerOptions(WorkerOptions{EnablePollerAutoScaler: true}))
+	assert.NoError(t, validateWorkerOptions(WorkerOptions{MinConcurrentActivityTaskPollers: 5}))
+	assert.Error(t, validateWorkerOptions(WorkerOptions{EnablePollerAutoScaler: true, MinConcurrentActivityTaskPollers: 5}))
+	assert.Error(t, validateWorkerOptions(WorkerOptions{MinConcurrentDecisionTaskPollers: -1}))
+
+	options := fillWorkerOptionsDefaults(WorkerOptions{})
+	assert.Equal(t, defaultMinConcurrentPollRoutineSize, options.MinConcurrentActivityTaskPollers)
+	assert.Equal(t, defaultMinConcurrentPollRoutineSize, options.MinConcurrentDecisionTaskPollers)
+}
@@ -31,6 +31,10 @@ import (
 )
 
 // ** This is for internal stress testing framework **
+//
+// Deprecated: the pressure points, configured with the test tags of the BackgroundActivityContext of a worker, are
+// superseded by WorkerOptions.FaultInjectionRules and won't get new features. They are kept as they are for the stress
+// tests that still set them; new tests should inject faults with the rules.
 
 // PressurePoints
 const (
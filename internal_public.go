@@ -77,17 +77,24 @@ func NewWorkflowTaskWorker(
 	taskList string,
 	options WorkerOptions,
 ) (worker Worker) {
+	if err := validateWorkerOptions(options); err != nil {
+		panic(err)
+	}
 	wOptions := fillWorkerOptionsDefaults(options)
 	workerParams := workerExecutionParameters{
-		TaskList:                  taskList,
-		ConcurrentPollRoutineSize: defaultConcurrentPollRoutineSize,
-		Identity:                  wOptions.Identity,
-		MetricsScope:              wOptions.MetricsScope,
-		Logger:                    wOptions.Logger,
+		TaskList:                            taskList,
+		ConcurrentPollRoutineSize:           wOptions.MaxConcurrentDecisionTaskPollers,
+		PollerAutoScaler:                    wOptions.EnablePollerAutoScaler,
+		MinConcurrentPollRoutineSize:        wOptions.MinConcurrentDecisionTaskPollers,
+		ConcurrentDecisionTaskExecutionSize: wOptions.MaxConcurrentDecisionTaskExecutionSize,
+		MaxDecisionTaskExecutionPerSecond:   wOptions.MaxDecisionTaskExecutionPerSecond,
+		Identity:                            wOptions.Identity,
+		MetricsScope:                        wOptions.MetricsScope,
+		Logger:                              wOptions.Logger,
 	}
 
 	processTestTags(&wOptions, &workerParams)
-	return newWorkflowTaskWorkerInternal(taskHandler, service, domain, workerParams)
+	return newWorkflowTaskWorkerInternal(taskHandler, service, domain, workerParams, getHostEnvironment())
 }
 
 // NewActivityTaskWorker returns instance of an activity task handler worker.
@@ -99,10 +106,15 @@ func NewActivityTaskWorker(
 	taskList string,
 	options WorkerOptions,
 ) Worker {
+	if err := validateWorkerOptions(options); err != nil {
+		panic(err)
+	}
 	wOptions := fillWorkerOptionsDefaults(options)
 	workerParams := workerExecutionParameters{
 		TaskList:                        taskList,
-		ConcurrentPollRoutineSize:       defaultConcurrentPollRoutineSize,
+		ConcurrentPollRoutineSize:       wOptions.MaxConcurrentActivityTaskPollers,
+		PollerAutoScaler:                wOptions.EnablePollerAutoScaler,
+		MinConcurrentPollRoutineSize:    wOptions.MinConcurrentActivityTaskPollers,
 		ConcurrentActivityExecutionSize: wOptions.MaxConcurrentActivityExecutionSize,
 		MaxActivityExecutionPerSecond:   wOptions.MaxActivityExecutionPerSecond,
 		Identity:                        wOptions.Identity,
@@ -110,10 +122,11 @@ func NewActivityTaskWorker(
 		Logger:                          wOptions.Logger,
 		EnableLoggingInReplay:           wOptions.EnableLoggingInReplay,
 		UserContext:                     wOptions.BackgroundActivityContext,
+		WorkerStopTimeout:               wOptions.WorkerStopTimeout,
 	}
 
 	processTestTags(&wOptions, &workerParams)
-	return newActivityTaskWorker(taskHandler, service, domain, workerParams)
+	return newActivityTaskWorker(taskHandler, service, domain, workerParams, make(chan struct{}), getHostEnvironment())
 }
 
 // NewWorkflowTaskHandler creates an instance of a WorkflowTaskHandler from a decision poll response
@@ -137,7 +150,7 @@ func NewActivityTaskHandler(service workflowserviceclient.Interface, identity st
 		Logger:   logger,
 	}
 	ensureRequiredParams(&params)
-	return newActivityTaskHandler(service, params, getHostEnvironment())
+	return newActivityTaskHandler(service, "", params, getHostEnvironment())
 }
 
 // AddWorkflowRegistrationInterceptor adds interceptor that is called for each RegisterWorkflow call.
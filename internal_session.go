@@ -0,0 +1,257 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/cadence/common/backoff"
+)
+
+const (
+	sessionCreationActivityName    = "internalSessionCreationActivity"
+	sessionCreationTaskListSuffix  = "__internal_session_creation"
+	sessionContextKey              = "session"
+	sessionEnvironmentContextKey   = contextKey("sessionEnvironment")
+	defaultSessionHeartbeatTimeout = 20 * time.Second
+)
+
+type (
+	// session is the state of a session, shared by all the contexts derived from the session context.
+	session struct {
+		info     SessionInfo
+		taskList string     // task list served by the worker host of the session
+		cancel   CancelFunc // cancels the session creation activity
+	}
+
+	// sessionCreationResponse is signaled to the workflow by the session creation activity once it runs.
+	sessionCreationResponse struct {
+		TaskList string
+		HostName string
+	}
+
+	// sessionEnvironment is passed by the session worker to the session creation activity.
+	sessionEnvironment struct {
+		resourceTaskList string
+		hostName         string
+		identity         string
+		service          workflowserviceclient.Interface
+	}
+
+	// sessionWorker runs the session creation activities, and the activities of the sessions created on this host.
+	sessionWorker struct {
+		creationWorker Worker
+		resourceWorker Worker
+	}
+)
+
+func createSession(ctx Context, options SessionOptions) (Context, error) {
+	if sess := getSession(ctx); sess != nil && sess.info.SessionState == SessionStateOpen {
+		return nil, errors.New("context already has an open session")
+	}
+	if options.CreationTimeout <= 0 {
+		return nil, errors.New("missing or negative CreationTimeout")
+	}
+	if options.ExecutionTimeout <= 0 {
+		return nil, errors.New("missing or negative ExecutionTimeout")
+	}
+	if options.HeartbeatTimeout < 0 {
+		return nil, errors.New("invalid negative HeartbeatTimeout")
+	}
+	if options.HeartbeatTimeout == 0 {
+		options.HeartbeatTimeout = defaultSessionHeartbeatTimeout
+	}
+
+	var sessionID string
+	if err := SideEffect(ctx, func(ctx Context) interface{} {
+		return uuid.New()
+	}).Get(&sessionID); err != nil {
+		return nil, err
+	}
+
+	// The session creation activity is scheduled on the session creation task list of the workers polling the
+	// activity task list of ctx. It uses the session ID as its activity ID, and as the name of the signal it sends.
+	taskList := GetWorkflowInfo(ctx).TaskListName
+	if p := getActivityOptions(ctx); p != nil && p.TaskListName != "" {
+		taskList = p.TaskListName
+	}
+	creationCtx, cancel := WithCancel(WithValue(ctx, sessionContextKey, nil))
+	creationCtx = WithActivityOptions(creationCtx, ActivityOptions{
+		TaskList:               getSessionCreationTaskList(taskList),
+		ScheduleToStartTimeout: options.CreationTimeout,
+		StartToCloseTimeout:    options.ExecutionTimeout,
+		HeartbeatTimeout:       options.HeartbeatTimeout,
+		ActivityID:             sessionID,
+	})
+	creationFuture := ExecuteActivity(creationCtx, sessionCreationActivityName)
+
+	var response sessionCreationResponse
+	var err error
+	NewSelector(ctx).AddReceive(GetSignalChannel(ctx, sessionID), func(c Channel, more bool) {
+		c.Receive(ctx, &response)
+	}).AddFuture(creationFuture, func(f Future) {
+		if err = f.Get(ctx, nil); err == nil {
+			err = errors.New("session creation activity completed before creating the session")
+		}
+	}).Select(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	sess := &session{
+		info: SessionInfo{
+			SessionID:    sessionID,
+			HostName:     response.HostName,
+			SessionState: SessionStateOpen,
+		},
+		taskList: response.TaskList,
+		cancel:   cancel,
+	}
+	Go(ctx, func(ctx Context) {
+		// The session creation activity runs until the session is completed, so it only ends on its own when the
+		// session has failed.
+		creationFuture.Get(ctx, nil)
+		if sess.info.SessionState == SessionStateOpen {
+			sess.info.SessionState = SessionStateFailed
+		}
+	})
+	return WithValue(ctx, sessionContextKey, sess), nil
+}
+
+func completeSession(ctx Context) {
+	sess := getSession(ctx)
+	if sess == nil || sess.info.SessionState != SessionStateOpen {
+		return
+	}
+	sess.info.SessionState = SessionStateClosed
+	sess.cancel()
+}
+
+func getSession(ctx Context) *session {
+	sess, _ := ctx.Value(sessionContextKey).(*session)
+	return sess
+}
+
+func getSessionCreationTaskList(taskList string) string {
+	return taskList + sessionCreationTaskListSuffix
+}
+
+// getSessionResourceTaskList returns the task list of the activities of the sessions created on this host.
+func getSessionResourceTaskList(taskList string) string {
+	return taskList + "@" + getWorkerTaskList()
+}
+
+// sessionCreationActivity creates a session on the worker host that runs it. It signals the task list of the host to
+// the workflow and runs until the workflow completes the session, which cancels the activity. The session worker
+// heartbeats it, so that the workflow can tell when the host is gone.
+func sessionCreationActivity(ctx context.Context) error {
+	env, ok := ctx.Value(sessionEnvironmentContextKey).(*sessionEnvironment)
+	if !ok {
+		return errors.New("session creation activity is not run by a session worker")
+	}
+	response := sessionCreationResponse{TaskList: env.resourceTaskList, HostName: env.hostName}
+	if err := env.signalWorkflow(ctx, GetActivityInfo(ctx), response); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (env *sessionEnvironment) signalWorkflow(ctx context.Context, info ActivityInfo, response sessionCreationResponse) error {
+	input, err := getHostEnvironment().encodeArg(response)
+	if err != nil {
+		return err
+	}
+	request := &s.SignalWorkflowExecutionRequest{
+		Domain: common.StringPtr(info.WorkflowDomain),
+		WorkflowExecution: &s.WorkflowExecution{
+			WorkflowId: common.StringPtr(info.WorkflowExecution.ID),
+			RunId:      common.StringPtr(info.WorkflowExecution.RunID),
+		},
+		SignalName: common.StringPtr(info.ActivityID),
+		Input:      input,
+		Identity:   common.StringPtr(env.identity),
+	}
+	return backoff.Retry(ctx,
+		func() error {
+			tchCtx, cancel, opt := newChannelContext(ctx)
+			defer cancel()
+			return env.service.SignalWorkflowExecution(tchCtx, request, opt...)
+		}, serviceOperationRetryPolicy, isServiceTransientError)
+}
+
+// newSessionWorker returns the workers of the sessions created on this host. The session creation activities are
+// polled from the session creation task list by a worker that runs at most maxConcurrentSessionExecutionSize of them,
+// and the activities of the sessions from the task list of this host by a worker sharing the registry of the
+// activity worker.
+func newSessionWorker(
+	service workflowserviceclient.Interface,
+	domain string,
+	params workerExecutionParameters,
+	env *hostEnvImpl,
+	maxConcurrentSessionExecutionSize int,
+) *sessionWorker {
+	resourceParams := params
+	resourceParams.TaskList = getSessionResourceTaskList(params.TaskList)
+
+	userContext := params.UserContext
+	if userContext == nil {
+		userContext = context.Background()
+	}
+	sessionEnv := &sessionEnvironment{
+		resourceTaskList: resourceParams.TaskList,
+		hostName:         getHostName(),
+		identity:         params.Identity,
+		service:          service,
+	}
+	creationParams := params
+	creationParams.TaskList = getSessionCreationTaskList(params.TaskList)
+	creationParams.ConcurrentActivityExecutionSize = maxConcurrentSessionExecutionSize
+	creationParams.AutoHeartBeat = true
+	creationParams.UserContext = context.WithValue(userContext, sessionEnvironmentContextKey, sessionEnv)
+	creationEnv := newRegistry()
+	creationEnv.addActivityFn(sessionCreationActivityName, sessionCreationActivity)
+
+	return &sessionWorker{
+		creationWorker: newActivityWorker(service, domain, creationParams, nil, creationEnv),
+		resourceWorker: newActivityWorker(service, domain, resourceParams, nil, env),
+	}
+}
+
+func (sw *sessionWorker) Start() error {
+	if err := sw.resourceWorker.Start(); err != nil {
+		return err
+	}
+	if err := sw.creationWorker.Start(); err != nil {
+		sw.resourceWorker.Stop()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	"go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/yarpc"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+	WorkflowTestSuite
+	sessionOptions SessionOptions
+}
+
+func TestSessionTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionTestSuite))
+}
+
+func (s *SessionTestSuite) SetupTest() {
+	s.sessionOptions = SessionOptions{
+		ExecutionTimeout: time.Hour,
+		CreationTimeout:  time.Minute,
+	}
+}
+
+func testSessionActivity(ctx context.Context, name string) (string, error) {
+	return "hello_" + name, nil
+}
+
+func (s *SessionTestSuite) newSessionWorkflowContext(ctx Context) Context {
+	return WithActivityOptions(ctx, ActivityOptions{
+		ScheduleToStartTimeout: time.Minute,
+		StartToCloseTimeout:    time.Minute,
+	})
+}
+
+func (s *SessionTestSuite) Test_Session() {
+	var infos []SessionInfo
+	workflowFn := func(ctx Context) ([]string, error) {
+		ctx = s.newSessionWorkflowContext(ctx)
+		s.Nil(GetSessionInfo(ctx))
+		sessionCtx, err := CreateSession(ctx, s.sessionOptions)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, *GetSessionInfo(sessionCtx))
+
+		_, err = CreateSession(sessionCtx, s.sessionOptions)
+		s.Error(err)
+
+		var results []string
+		for _, name := range []string{"download", "process"} {
+			var result string
+			if err := ExecuteActivity(sessionCtx, testSessionActivity, name).Get(sessionCtx, &result); err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		CompleteSession(sessionCtx)
+		infos = append(infos, *GetSessionInfo(sessionCtx))
+		return results, nil
+	}
+
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(testSessionActivity)
+	env.ExecuteWorkflow(workflowFn)
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	var results []string
+	s.NoError(env.GetWorkflowResult(&results))
+	s.Equal([]string{"hello_download", "hello_process"}, results)
+	s.Len(infos, 2)
+	s.NotEmpty(infos[0].SessionID)
+	s.Equal(getHostName(), infos[0].HostName)
+	s.Equal(SessionStateOpen, infos[0].SessionState)
+	s.Equal(infos[0].SessionID, infos[1].SessionID)
+	s.Equal(SessionStateClosed, infos[1].SessionState)
+}
+
+func (s *SessionTestSuite) Test_SessionFailed() {
+	env := s.NewTestWorkflowEnvironment()
+	var sessionID string
+	var state SessionState
+	workflowFn := func(ctx Context) error {
+		ctx = s.newSessionWorkflowContext(ctx)
+		sessionCtx, err := CreateSession(ctx, s.sessionOptions)
+		if err != nil {
+			return err
+		}
+		sessionID = GetSessionInfo(sessionCtx).SessionID
+		if err := Sleep(ctx, 2*time.Minute); err != nil {
+			return err
+		}
+		state = GetSessionInfo(sessionCtx).SessionState
+		return ExecuteActivity(sessionCtx, testSessionActivity, "download").Get(sessionCtx, nil)
+	}
+
+	// The worker host of the session stops heartbeating for it.
+	env.RegisterDelayedCallback(func() {
+		handle := env.impl.activities[sessionID]
+		delete(env.impl.activities, sessionID)
+		handle.callback(nil, NewTimeoutError(shared.TimeoutTypeHeartbeat))
+	}, time.Minute)
+
+	env.RegisterActivity(testSessionActivity)
+	env.ExecuteWorkflow(workflowFn)
+	s.True(env.IsWorkflowCompleted())
+	s.Equal(ErrSessionFailed, env.GetWorkflowError())
+	s.Equal(SessionStateFailed, state)
+}
+
+func (s *SessionTestSuite) Test_SessionClosed() {
+	workflowFn := func(ctx Context) error {
+		sessionCtx, err := CreateSession(s.newSessionWorkflowContext(ctx), s.sessionOptions)
+		if err != nil {
+			return err
+		}
+		CompleteSession(sessionCtx)
+		return ExecuteActivity(sessionCtx, testSessionActivity, "download").Get(sessionCtx, nil)
+	}
+
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(testSessionActivity)
+	env.ExecuteWorkflow(workflowFn)
+	s.True(env.IsWorkflowCompleted())
+	s.Equal(ErrSessionClosed, env.GetWorkflowError())
+}
+
+func (s *SessionTestSuite) Test_CreateSessionOptions() {
+	workflowFn := func(ctx Context) error {
+		_, err := CreateSession(s.newSessionWorkflowContext(ctx), SessionOptions{ExecutionTimeout: time.Hour})
+		return err
+	}
+
+	env := s.NewTestWorkflowEnvironment()
+	env.ExecuteWorkflow(workflowFn)
+	s.True(env.IsWorkflowCompleted())
+	s.EqualError(env.GetWorkflowError(), "missing or negative CreationTimeout")
+}
+
+func TestSessionCreationActivity(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	sessionEnv := &sessionEnvironment{
+		resourceTaskList: getSessionResourceTaskList("tl"),
+		hostName:         "host",
+		identity:         "identity",
+		service:          service,
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), sessionEnvironmentContextKey, sessionEnv))
+	task := &shared.PollForActivityTaskResponse{
+		TaskToken: []byte("token"),
+		WorkflowExecution: &shared.WorkflowExecution{
+			WorkflowId: common.StringPtr("wID"),
+			RunId:      common.StringPtr("rID")},
+		ActivityType: &shared.ActivityType{Name: common.StringPtr(sessionCreationActivityName)},
+		ActivityId:   common.StringPtr("sessionID"),
+	}
+	ctx = withActivityTask(ctx, task, "domain", nil, getLogger(), nil, nil)
+
+	service.EXPECT().SignalWorkflowExecution(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).
+		Do(func(ctx context.Context, request *shared.SignalWorkflowExecutionRequest, opts ...yarpc.CallOption) {
+			require.Equal(t, "domain", request.GetDomain())
+			require.Equal(t, "wID", request.WorkflowExecution.GetWorkflowId())
+			require.Equal(t, "rID", request.WorkflowExecution.GetRunId())
+			require.Equal(t, "sessionID", request.GetSignalName())
+			var response sessionCreationResponse
+			require.NoError(t, getHostEnvironment().decodeArg(request.Input, &response))
+			require.Equal(t, sessionCreationResponse{TaskList: sessionEnv.resourceTaskList, HostName: "host"}, response)
+			// The session is completed by the workflow.
+			cancel()
+		})
+
+	require.Equal(t, context.Canceled, sessionCreationActivity(ctx))
+}
@@ -0,0 +1,352 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/cadence/common/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultShadowWorkflowStartTimeWindow = 24 * time.Hour
+	defaultShadowSamplingRate            = 1.0
+	defaultShadowScanInterval            = 5 * time.Minute
+	defaultShadowMaxExecutionsPerScan    = 1000
+
+	shadowListPageSize = 100
+)
+
+// shadowWorker periodically lists the recent workflow executions of a domain and replays their history with the
+// workflows of its registry, without sending any decision.
+type shadowWorker struct {
+	client        Client
+	domain        string
+	options       ShadowOptions
+	replayOptions replayOptions
+	hostEnv       *hostEnvImpl
+	logger        *zap.Logger
+	metricsScope  tally.Scope
+	random        *rand.Rand
+
+	stopC    chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// shadowExecution is a workflow execution listed by a scan of the shadow worker.
+type shadowExecution struct {
+	workflowType string
+	execution    *s.WorkflowExecution
+}
+
+func newShadowWorker(
+	client Client,
+	domain string,
+	options ShadowOptions,
+	replayOptions replayOptions,
+	hostEnv *hostEnvImpl,
+	logger *zap.Logger,
+	metricsScope tally.Scope,
+) *shadowWorker {
+	return &shadowWorker{
+		client:        client,
+		domain:        domain,
+		options:       fillShadowOptionsDefaults(options),
+		replayOptions: replayOptions,
+		hostEnv:       hostEnv,
+		logger:        logger,
+		metricsScope:  metricsScope,
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		stopC:         make(chan struct{}),
+	}
+}
+
+func fillShadowOptionsDefaults(options ShadowOptions) ShadowOptions {
+	if options.WorkflowStartTimeWindow == 0 {
+		options.WorkflowStartTimeWindow = defaultShadowWorkflowStartTimeWindow
+	}
+	if options.SamplingRate == 0 {
+		options.SamplingRate = defaultShadowSamplingRate
+	}
+	if options.ScanInterval == 0 {
+		options.ScanInterval = defaultShadowScanInterval
+	}
+	if options.MaxExecutionsPerScan == 0 {
+		options.MaxExecutionsPerScan = defaultShadowMaxExecutionsPerScan
+	}
+	return options
+}
+
+func validateShadowOptions(options ShadowOptions) error {
+	switch options.ExecutionStatus {
+	case ShadowExecutionStatusAll, ShadowExecutionStatusOpen, ShadowExecutionStatusClosed:
+	default:
+		return errors.New("invalid ShadowOptions.ExecutionStatus")
+	}
+	if options.WorkflowStartTimeWindow < 0 {
+		return errors.New("negative ShadowOptions.WorkflowStartTimeWindow")
+	}
+	if options.SamplingRate < 0 || options.SamplingRate > 1 {
+		return errors.New("ShadowOptions.SamplingRate is not between 0 and 1")
+	}
+	if options.ScanInterval < 0 {
+		return errors.New("negative ShadowOptions.ScanInterval")
+	}
+	if options.MaxExecutionsPerScan < 0 {
+		return errors.New("negative ShadowOptions.MaxExecutionsPerScan")
+	}
+	return nil
+}
+
+func (sw *shadowWorker) Start() error {
+	if len(sw.getWorkflowTypes()) == 0 {
+		sw.logger.Warn("Starting shadow worker without any workflows. Workflows must be registered before start.")
+	}
+	sw.wg.Add(1)
+	go sw.run()
+	sw.logger.Info("Started shadow worker")
+	return nil
+}
+
+// Stop interrupts the running scan and waits for it to return.
+func (sw *shadowWorker) Stop() {
+	sw.stopOnce.Do(func() {
+		close(sw.stopC)
+	})
+	sw.wg.Wait()
+}
+
+func (sw *shadowWorker) run() {
+	defer sw.wg.Done()
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-sw.stopC:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		result := sw.scan(ctx)
+		interrupted := ctx.Err() != nil
+		cancel()
+		if interrupted {
+			return
+		}
+		if sw.options.OnScanCompleted != nil {
+			sw.options.OnScanCompleted(result)
+		}
+
+		select {
+		case <-sw.stopC:
+			return
+		case <-time.After(sw.options.ScanInterval):
+		}
+	}
+}
+
+// getWorkflowTypes returns the workflow types whose executions are replayed.
+func (sw *shadowWorker) getWorkflowTypes() []string {
+	if len(sw.options.WorkflowTypes) > 0 {
+		return sw.options.WorkflowTypes
+	}
+	workflowTypes := sw.hostEnv.getRegisteredWorkflowTypes()
+	sort.Strings(workflowTypes)
+	return workflowTypes
+}
+
+// scan replays the sampled executions of each workflow type started within the window, up to the max executions per
+// scan.
+func (sw *shadowWorker) scan(ctx context.Context) ShadowScanResult {
+	result := ShadowScanResult{StartTime: time.Now()}
+	startTimeFilter := &s.StartTimeFilter{
+		EarliestTime: common.Int64Ptr(result.StartTime.Add(-sw.options.WorkflowStartTimeWindow).UnixNano()),
+		LatestTime:   common.Int64Ptr(result.StartTime.UnixNano()),
+	}
+
+	for _, workflowType := range sw.getWorkflowTypes() {
+		if ctx.Err() != nil || sw.isScanFull(&result) {
+			break
+		}
+		typeFilter := &s.WorkflowTypeFilter{Name: common.StringPtr(workflowType)}
+		var err error
+		if sw.options.ExecutionStatus != ShadowExecutionStatusClosed {
+			err = sw.replayExecutions(ctx, sw.listOpenExecutions(startTimeFilter, typeFilter), &result)
+		}
+		if err == nil && sw.options.ExecutionStatus != ShadowExecutionStatusOpen {
+			err = sw.replayExecutions(ctx, sw.listClosedExecutions(startTimeFilter, typeFilter), &result)
+		}
+		if err != nil {
+			sw.logger.Error("Unable to list workflow executions to shadow.",
+				zap.String(tagWorkflowType, workflowType), zap.Error(err))
+			result.ListError = err
+			break
+		}
+	}
+
+	result.EndTime = time.Now()
+	sw.metricsScope.Timer(metrics.ShadowScanLatency).Record(result.EndTime.Sub(result.StartTime))
+	sw.logger.Info("Shadow worker completed a scan.",
+		zap.Int("Listed", result.Listed),
+		zap.Int("Replayed", result.Replayed),
+		zap.Int("Succeeded", result.Succeeded),
+		zap.Int("NonDeterministic", len(result.NonDeterministic)),
+		zap.Int("Failed", len(result.Failed)))
+	return result
+}
+
+// isScanFull tells whether the scan has replayed the max executions per scan.
+func (sw *shadowWorker) isScanFull(result *ShadowScanResult) bool {
+	return result.Replayed >= sw.options.MaxExecutionsPerScan
+}
+
+// shadowListFunc lists a page of workflow executions, and returns the token of the next page.
+type shadowListFunc func(ctx context.Context, nextPageToken []byte) ([]*s.WorkflowExecutionInfo, []byte, error)
+
+// replayExecutions lists the executions page by page and replays the sampled ones as they are listed, so that the
+// executions of a scan are not all held in memory. It stops listing once the scan is full.
+func (sw *shadowWorker) replayExecutions(ctx context.Context, list shadowListFunc, result *ShadowScanResult) error {
+	var nextPageToken []byte
+	for {
+		infos, token, err := list(ctx, nextPageToken)
+		if err != nil {
+			return err
+		}
+		for _, execution := range getShadowExecutions(infos) {
+			if ctx.Err() != nil || sw.isScanFull(result) {
+				return nil
+			}
+			result.Listed++
+			if sw.random.Float64() >= sw.options.SamplingRate {
+				continue
+			}
+			result.Replayed++
+			sw.replay(ctx, execution, result)
+		}
+		nextPageToken = token
+		if len(nextPageToken) == 0 {
+			return nil
+		}
+	}
+}
+
+func (sw *shadowWorker) listOpenExecutions(
+	startTimeFilter *s.StartTimeFilter,
+	typeFilter *s.WorkflowTypeFilter,
+) shadowListFunc {
+	return func(ctx context.Context, nextPageToken []byte) ([]*s.WorkflowExecutionInfo, []byte, error) {
+		response, err := sw.client.ListOpenWorkflow(ctx, &s.ListOpenWorkflowExecutionsRequest{
+			Domain:          common.StringPtr(sw.domain),
+			MaximumPageSize: common.Int32Ptr(shadowListPageSize),
+			NextPageToken:   nextPageToken,
+			StartTimeFilter: startTimeFilter,
+			TypeFilter:      typeFilter,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return response.Executions, response.NextPageToken, nil
+	}
+}
+
+func (sw *shadowWorker) listClosedExecutions(
+	startTimeFilter *s.StartTimeFilter,
+	typeFilter *s.WorkflowTypeFilter,
+) shadowListFunc {
+	return func(ctx context.Context, nextPageToken []byte) ([]*s.WorkflowExecutionInfo, []byte, error) {
+		response, err := sw.client.ListClosedWorkflow(ctx, &s.ListClosedWorkflowExecutionsRequest{
+			Domain:          common.StringPtr(sw.domain),
+			MaximumPageSize: common.Int32Ptr(shadowListPageSize),
+			NextPageToken:   nextPageToken,
+			StartTimeFilter: startTimeFilter,
+			TypeFilter:      typeFilter,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return response.Executions, response.NextPageToken, nil
+	}
+}
+
+func getShadowExecutions(infos []*s.WorkflowExecutionInfo) []shadowExecution {
+	var executions []shadowExecution
+	for _, info := range infos {
+		if info == nil || info.Execution == nil || info.Type == nil {
+			continue
+		}
+		executions = append(executions, shadowExecution{workflowType: info.Type.GetName(), execution: info.Execution})
+	}
+	return executions
+}
+
+// replay fetches the history of the execution and replays it, the outcome is added to the result.
+func (sw *shadowWorker) replay(ctx context.Context, execution shadowExecution, result *ShadowScanResult) {
+	metricsScope := tagScope(sw.metricsScope, tagWorkflowType, execution.workflowType)
+	workflowID := execution.execution.GetWorkflowId()
+	runID := execution.execution.GetRunId()
+	logger := sw.logger.With(
+		zap.String(tagWorkflowType, execution.workflowType),
+		zap.String(tagWorkflowID, workflowID),
+		zap.String(tagRunID, runID))
+
+	history, err := sw.client.GetWorkflowHistory(ctx, workflowID, runID)
+	if err == nil {
+		// The replay logs the mismatches as errors of a decision task, the shadow worker logs them itself.
+		err = replayWorkflowHistory(zap.NewNop(), sw.hostEnv, execution.execution, history, sw.replayOptions)
+	}
+
+	failure := ShadowReplayFailure{
+		WorkflowType:      execution.workflowType,
+		WorkflowExecution: WorkflowExecution{ID: workflowID, RunID: runID},
+		Error:             err,
+	}
+	switch err := err.(type) {
+	case nil:
+		result.Succeeded++
+		metricsScope.Counter(metrics.ShadowReplaySucceedCounter).Inc(1)
+	case *NonDeterministicError:
+		result.NonDeterministic = append(result.NonDeterministic, failure)
+		metricsScope.Counter(metrics.ShadowReplayNonDeterministicCounter).Inc(1)
+		logger.Error("Shadow replay and history mismatch.",
+			zap.Error(err),
+			zap.String("StackTrace", err.StackTrace()))
+	default:
+		if ctx.Err() != nil {
+			// The worker is stopping, the replay didn't fail.
+			return
+		}
+		result.Failed = append(result.Failed, failure)
+		metricsScope.Counter(metrics.ShadowReplayFailedCounter).Inc(1)
+		logger.Warn("Shadow replay failed.", zap.Error(err))
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/zap"
+)
+
+func createShadowTestExecutionInfo(runID string) *s.WorkflowExecutionInfo {
+	return &s.WorkflowExecutionInfo{
+		Execution: &s.WorkflowExecution{WorkflowId: common.StringPtr("shadowTestID"), RunId: common.StringPtr(runID)},
+		Type:      &s.WorkflowType{Name: common.StringPtr("ReplayTestWorkflow")},
+	}
+}
+
+func TestShadowWorker(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	service.EXPECT().ListOpenWorkflowExecutions(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.ListOpenWorkflowExecutionsResponse{
+			Executions: []*s.WorkflowExecutionInfo{createShadowTestExecutionInfo("run1")},
+		}, nil).Times(1)
+	gomock.InOrder(
+		service.EXPECT().ListClosedWorkflowExecutions(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&s.ListClosedWorkflowExecutionsResponse{
+				Executions:    []*s.WorkflowExecutionInfo{createShadowTestExecutionInfo("run2")},
+				NextPageToken: []byte("token"),
+			}, nil).Times(1),
+		service.EXPECT().ListClosedWorkflowExecutions(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&s.ListClosedWorkflowExecutionsResponse{
+				Executions: []*s.WorkflowExecutionInfo{
+					createShadowTestExecutionInfo("run3"),
+					createShadowTestExecutionInfo("run4"),
+				},
+			}, nil).Times(1),
+	)
+
+	runningHistory := createReplayTestHistory("replayTestActivity")
+	runningHistory.Events = runningHistory.Events[:9]
+	gomock.InOrder(
+		service.EXPECT().GetWorkflowExecutionHistory(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&s.GetWorkflowExecutionHistoryResponse{History: runningHistory}, nil).Times(1),
+		service.EXPECT().GetWorkflowExecutionHistory(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&s.GetWorkflowExecutionHistoryResponse{
+				History: createReplayTestHistory("replayTestActivity"),
+			}, nil).Times(1),
+		service.EXPECT().GetWorkflowExecutionHistory(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&s.GetWorkflowExecutionHistoryResponse{History: createReplayTestHistory("otherActivity")}, nil).
+			Times(1),
+		service.EXPECT().GetWorkflowExecutionHistory(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, &s.EntityNotExistsError{Message: "not found"}).Times(1),
+	)
+
+	results := make(chan ShadowScanResult, 1)
+	worker := NewWorker(service, "testDomain", "testTaskList", WorkerOptions{
+		Logger:             zap.NewNop(),
+		EnableShadowWorker: true,
+		ShadowOptions: ShadowOptions{
+			WorkflowTypes: []string{"ReplayTestWorkflow"},
+			OnScanCompleted: func(result ShadowScanResult) {
+				results <- result
+			},
+		},
+	})
+	worker.(WorkerRegistry).RegisterWorkflowWithOptions(replayTestWorkflow, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"})
+	require.NoError(t, worker.Start())
+
+	var result ShadowScanResult
+	select {
+	case result = <-results:
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "shadow scan not completed")
+	}
+	worker.Stop()
+	mockCtrl.Finish()
+
+	require.NoError(t, result.ListError)
+	require.Equal(t, 4, result.Listed)
+	require.Equal(t, 4, result.Replayed)
+	require.Equal(t, 2, result.Succeeded)
+	require.Equal(t, 1, len(result.NonDeterministic))
+	require.Equal(t, "run3", result.NonDeterministic[0].WorkflowExecution.RunID)
+	require.Equal(t, "ReplayTestWorkflow", result.NonDeterministic[0].WorkflowType)
+	_, ok := result.NonDeterministic[0].Error.(*NonDeterministicError)
+	require.True(t, ok)
+	require.Equal(t, 1, len(result.Failed))
+	require.Equal(t, "run4", result.Failed[0].WorkflowExecution.RunID)
+	require.False(t, result.EndTime.Before(result.StartTime))
+}
+
+func TestShadowWorker_ListError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	service.EXPECT().ListClosedWorkflowExecutions(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &s.BadRequestError{Message: "bad request"}).Times(1)
+
+	registry := newRegistry()
+	require.NoError(t, registry.RegisterWorkflowWithOptions(replayTestWorkflow, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"}))
+	sw := newShadowWorker(NewClient(service, "testDomain", nil), "testDomain",
+		ShadowOptions{ExecutionStatus: ShadowExecutionStatusClosed}, replayOptions{}, registry, zap.NewNop(), tally.NoopScope)
+	result := sw.scan(context.Background())
+	require.Error(t, result.ListError)
+	require.Equal(t, 0, result.Listed)
+	require.Equal(t, 0, result.Replayed)
+}
+
+func TestShadowWorker_MaxExecutionsPerScan(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	// The scan is full before the end of the first page, so the next page is not listed.
+	service.EXPECT().ListClosedWorkflowExecutions(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.ListClosedWorkflowExecutionsResponse{
+			Executions: []*s.WorkflowExecutionInfo{
+				createShadowTestExecutionInfo("run1"),
+				createShadowTestExecutionInfo("run2"),
+				createShadowTestExecutionInfo("run3"),
+			},
+			NextPageToken: []byte("token"),
+		}, nil).Times(1)
+	service.EXPECT().GetWorkflowExecutionHistory(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.GetWorkflowExecutionHistoryResponse{History: createReplayTestHistory("replayTestActivity")}, nil).
+		Times(2)
+
+	registry := newRegistry()
+	require.NoError(t, registry.RegisterWorkflowWithOptions(replayTestWorkflow, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"}))
+	sw := newShadowWorker(NewClient(service, "testDomain", nil), "testDomain", ShadowOptions{
+		WorkflowTypes:        []string{"ReplayTestWorkflow", "OtherWorkflow"},
+		ExecutionStatus:      ShadowExecutionStatusClosed,
+		MaxExecutionsPerScan: 2,
+	}, replayOptions{}, registry, zap.NewNop(), tally.NoopScope)
+	result := sw.scan(context.Background())
+	mockCtrl.Finish()
+
+	require.NoError(t, result.ListError)
+	require.Equal(t, 2, result.Listed)
+	require.Equal(t, 2, result.Replayed)
+	require.Equal(t, 2, result.Succeeded)
+}
+
+func TestShadowWorker_BlockedWorkflow(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	service.EXPECT().ListClosedWorkflowExecutions(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.ListClosedWorkflowExecutionsResponse{
+			Executions: []*s.WorkflowExecutionInfo{createShadowTestExecutionInfo("run1")},
+		}, nil).Times(1)
+	service.EXPECT().GetWorkflowExecutionHistory(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.GetWorkflowExecutionHistoryResponse{History: createReplayTestHistory("replayTestActivity")}, nil).
+		Times(1)
+
+	// The workflow blocks without yielding, the replay is failed by the deadlock detection of the worker.
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	defer close(unblock)
+	blockedWorkflow := func(ctx Context) error {
+		close(started)
+		<-unblock
+		return nil
+	}
+	interceptorFactory := &testWorkflowInterceptorFactory{}
+	worker := NewWorker(service, "testDomain", "testTaskList", WorkerOptions{
+		Logger:               zap.NewNop(),
+		WorkflowInterceptors: []WorkflowInterceptorFactory{interceptorFactory},
+		EnableShadowWorker:   true,
+		ShadowOptions: ShadowOptions{
+			WorkflowTypes:   []string{"ReplayTestWorkflow"},
+			ExecutionStatus: ShadowExecutionStatusClosed,
+		},
+	})
+	worker.(WorkerRegistry).RegisterWorkflowWithOptions(blockedWorkflow, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"})
+	require.NoError(t, worker.Start())
+
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "shadow replay not started")
+	}
+	stopped := make(chan struct{})
+	go func() {
+		worker.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * defaultDeadlockDetectionTimeout):
+		require.FailNow(t, "shadow worker not stopped")
+	}
+	mockCtrl.Finish()
+	require.Equal(t, []string{"ExecuteWorkflow"}, interceptorFactory.calls)
+
+	registry := newRegistry()
+	registry.RegisterWorkflowWithOptions(func(ctx Context) error {
+		<-unblock
+		return nil
+	}, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"})
+	err := replayWorkflowHistory(zap.NewNop(), registry, nil, createReplayTestHistory("replayTestActivity"),
+		replayOptions{deadlockDetectionTimeout: defaultDeadlockDetectionTimeout})
+	require.IsType(t, &PanicError{}, err)
+	require.Contains(t, err.Error(), "Potential deadlock detected")
+}
+
+func TestValidateShadowOptions(t *testing.T) {
+	require.NoError(t, validateShadowOptions(ShadowOptions{}))
+	require.NoError(t, validateShadowOptions(ShadowOptions{SamplingRate: 0.5, ExecutionStatus: ShadowExecutionStatusOpen}))
+	require.Error(t, validateShadowOptions(ShadowOptions{SamplingRate: 1.5}))
+	require.Error(t, validateShadowOptions(ShadowOptions{SamplingRate: -0.5}))
+	require.Error(t, validateShadowOptions(ShadowOptions{ExecutionStatus: ShadowExecutionStatus(10)}))
+	require.Error(t, validateShadowOptions(ShadowOptions{ScanInterval: -time.Second}))
+	require.Error(t, validateShadowOptions(ShadowOptions{WorkflowStartTimeWindow: -time.Second}))
+	require.Error(t, validateShadowOptions(ShadowOptions{MaxExecutionsPerScan: -1}))
+
+	options := fillShadowOptionsDefaults(ShadowOptions{})
+	require.Equal(t, defaultShadowWorkflowStartTimeWindow, options.WorkflowStartTimeWindow)
+	require.Equal(t, defaultShadowSamplingRate, options.SamplingRate)
+	require.Equal(t, defaultShadowScanInterval, options.ScanInterval)
+	require.Equal(t, defaultShadowMaxExecutionsPerScan, options.MaxExecutionsPerScan)
+}
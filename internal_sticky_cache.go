@@ -0,0 +1,232 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common/cache"
+	"go.uber.org/cadence/common/metrics"
+)
+
+const (
+	defaultStickyCacheSize = 10000
+
+	// estimatedHistoryEventSize is the estimated memory that a cached workflow execution uses for the state built
+	// from a history event, not counting the payloads of the event.
+	estimatedHistoryEventSize = 512
+)
+
+// stickyWorkflowCache caches the state of the workflow executions processed by the workers, so that their next
+// decision tasks don't replay the full history.
+type stickyWorkflowCache struct {
+	cache   cache.PurgeableCache
+	hits    int64
+	lookups int64
+}
+
+var (
+	stickyCacheLock    sync.Mutex
+	stickyCacheOptions = StickyCacheOptions{Size: defaultStickyCacheSize}
+	processStickyCache *stickyWorkflowCache
+	// The caches in use, purged by PurgeStickyCache.
+	stickyCaches = make(map[*stickyWorkflowCache]struct{})
+)
+
+func setStickyCacheOptions(options StickyCacheOptions) {
+	if err := validateStickyCacheOptions(options); err != nil {
+		panic(err)
+	}
+	stickyCacheLock.Lock()
+	defer stickyCacheLock.Unlock()
+	if processStickyCache != nil {
+		panic("sticky cache is already in use, set its options before creating the workers")
+	}
+	stickyCacheOptions = fillStickyCacheOptionsDefaults(options)
+}
+
+// getProcessStickyCache returns the cache shared by the workers that don't have their own cache.
+func getProcessStickyCache() *stickyWorkflowCache {
+	stickyCacheLock.Lock()
+	defer stickyCacheLock.Unlock()
+	if processStickyCache == nil {
+		processStickyCache = newStickyWorkflowCacheLocked(stickyCacheOptions)
+	}
+	return processStickyCache
+}
+
+// newStickyWorkflowCache returns the own cache of a worker, it is closed when the worker stops.
+func newStickyWorkflowCache(options StickyCacheOptions) *stickyWorkflowCache {
+	stickyCacheLock.Lock()
+	defer stickyCacheLock.Unlock()
+	return newStickyWorkflowCacheLocked(fillStickyCacheOptionsDefaults(options))
+}
+
+func newStickyWorkflowCacheLocked(options StickyCacheOptions) *stickyWorkflowCache {
+	c := &stickyWorkflowCache{
+		cache: cache.New(options.Size, &cache.Options{
+			RemovedFunc: func(cachedEntity interface{}) {
+				wc := cachedEntity.(*workflowExecutionContext)
+				wc.onEviction()
+			},
+			EvictedFunc: func(cachedEntity interface{}) {
+				wc := cachedEntity.(*workflowExecutionContext)
+				wc.wth.metricsScope.Counter(metrics.StickyCacheEviction).Inc(1)
+			},
+			IdleTimeout:  options.IdleTimeout,
+			MaxTotalSize: options.MaxMemoryBytes,
+			SizeFunc: func(cachedEntity interface{}) int64 {
+				return cachedEntity.(*workflowExecutionContext).getEstimatedSize()
+			},
+		}).(cache.PurgeableCache),
+	}
+	stickyCaches[c] = struct{}{}
+	return c
+}
+
+// purgeStickyCaches evicts the workflow executions of all the caches in use.
+func purgeStickyCaches() {
+	stickyCacheLock.Lock()
+	caches := make([]*stickyWorkflowCache, 0, len(stickyCaches))
+	for c := range stickyCaches {
+		caches = append(caches, c)
+	}
+	stickyCacheLock.Unlock()
+
+	for _, c := range caches {
+		c.cache.Purge()
+	}
+}
+
+func fillStickyCacheOptionsDefaults(options StickyCacheOptions) StickyCacheOptions {
+	if options.Size == 0 {
+		options.Size = defaultStickyCacheSize
+	}
+	return options
+}
+
+func validateStickyCacheOptions(options StickyCacheOptions) error {
+	if options.Size < 0 {
+		return errors.New("negative sticky cache Size")
+	}
+	if options.MaxMemoryBytes < 0 {
+		return errors.New("negative sticky cache MaxMemoryBytes")
+	}
+	if options.IdleTimeout < 0 {
+		return errors.New("negative sticky cache IdleTimeout")
+	}
+	return nil
+}
+
+func (c *stickyWorkflowCache) getWorkflowContext(runID string) *workflowExecutionContext {
+	o := c.cache.Get(runID)
+	if o == nil {
+		return nil
+	}
+	wc := o.(*workflowExecutionContext)
+	return wc
+}
+
+func (c *stickyWorkflowCache) putWorkflowContext(runID string, wc *workflowExecutionContext) (*workflowExecutionContext, error) {
+	existing, err := c.cache.PutIfNotExist(runID, wc)
+	if err != nil {
+		return nil, err
+	}
+	return existing.(*workflowExecutionContext), nil
+}
+
+func (c *stickyWorkflowCache) removeWorkflowContext(runID string) {
+	c.cache.Delete(runID)
+}
+
+// updateWorkflowContextSize re-evaluates the estimated size of a cached workflow execution after a decision task.
+func (c *stickyWorkflowCache) updateWorkflowContextSize(runID string) {
+	// The cache re-evaluates the size of an entry when it is accessed.
+	c.cache.Get(runID)
+}
+
+func (c *stickyWorkflowCache) size() int {
+	return c.cache.Size()
+}
+
+// recordLookup records whether a decision task found a valid cached state, and returns the hit ratio of the cache.
+func (c *stickyWorkflowCache) recordLookup(hit bool) float64 {
+	hits := atomic.LoadInt64(&c.hits)
+	if hit {
+		hits = atomic.AddInt64(&c.hits, 1)
+	}
+	lookups := atomic.AddInt64(&c.lookups, 1)
+	return float64(hits) / float64(lookups)
+}
+
+// close purges the own cache of a stopped worker.
+func (c *stickyWorkflowCache) close() {
+	stickyCacheLock.Lock()
+	delete(stickyCaches, c)
+	stickyCacheLock.Unlock()
+	c.cache.Purge()
+}
+
+// estimateHistoryEventSize returns the estimated memory that a cached workflow execution uses for the state built
+// from a history event.
+func estimateHistoryEventSize(event *s.HistoryEvent) int64 {
+	size := int64(estimatedHistoryEventSize)
+	if attributes := event.WorkflowExecutionStartedEventAttributes; attributes != nil {
+		size += int64(len(attributes.Input))
+	}
+	if attributes := event.ActivityTaskCompletedEventAttributes; attributes != nil {
+		size += int64(len(attributes.Result))
+	}
+	if attributes := event.MarkerRecordedEventAttributes; attributes != nil {
+		size += int64(len(attributes.Details))
+	}
+	if attributes := event.WorkflowExecutionSignaledEventAttributes; attributes != nil {
+		size += int64(len(attributes.Input))
+	}
+	if attributes := event.ChildWorkflowExecutionCompletedEventAttributes; attributes != nil {
+		size += int64(len(attributes.Result))
+	}
+	return size
+}
+
+// getStackTraces returns the stack traces of the cached workflow executions. It waits for the decision tasks in
+// progress, as the state of a workflow execution is locked while it processes a decision task.
+func (c *stickyWorkflowCache) getStackTraces() []cachedWorkflowStackTrace {
+	var stackTraces []cachedWorkflowStackTrace
+	for _, o := range c.cache.Values() {
+		wc := o.(*workflowExecutionContext)
+		wc.Lock()
+		if !wc.isDestroyed() {
+			stackTraces = append(stackTraces, cachedWorkflowStackTrace{
+				WorkflowExecution: wc.workflowInfo.WorkflowExecution,
+				WorkflowType:      wc.workflowInfo.WorkflowType.Name,
+				StackTrace:        wc.eventHandler.StackTrace(),
+			})
+		}
+		wc.Unlock()
+	}
+	return stackTraces
+}
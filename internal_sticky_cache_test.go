@@ -0,0 +1,97 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	s "go.uber.org/cadence/.gen/go/shared"
+)
+
+func TestStickyCacheOptions(t *testing.T) {
+	assert.Error(t, validateStickyCacheOptions(StickyCacheOptions{Size: -1}))
+	assert.Error(t, validateStickyCacheOptions(StickyCacheOptions{MaxMemoryBytes: -1}))
+	assert.Error(t, validateStickyCacheOptions(StickyCacheOptions{IdleTimeout: -time.Second}))
+	assert.Equal(t, defaultStickyCacheSize, fillStickyCacheOptionsDefaults(StickyCacheOptions{}).Size)
+
+	// The process cache can't be configured once it is in use.
+	getProcessStickyCache()
+	assert.Panics(t, func() { SetStickyCacheOptions(StickyCacheOptions{Size: 10}) })
+}
+
+func TestWorkerStickyCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	aw := NewWorker(service, "testDomain", "testTaskList", WorkerOptions{}).(*aggregatedWorker)
+	assert.Nil(t, aw.workflowWorker.(*workflowWorker).executionParameters.StickyCache)
+
+	aw = NewWorker(service, "testDomain", "testTaskList", WorkerOptions{
+		StickyCache: StickyCacheOptions{Size: 10, MaxMemoryBytes: 1 << 20},
+	}).(*aggregatedWorker)
+	stickyCache := aw.workflowWorker.(*workflowWorker).executionParameters.StickyCache
+	assert.NotNil(t, stickyCache)
+	assert.NotEqual(t, getProcessStickyCache(), stickyCache)
+
+	wth := &workflowTaskHandlerImpl{metricsScope: tally.NoopScope}
+	_, err := stickyCache.putWorkflowContext("runID1", &workflowExecutionContext{wth: wth})
+	assert.NoError(t, err)
+	_, err = getProcessStickyCache().putWorkflowContext("runID2", &workflowExecutionContext{wth: wth})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stickyCache.size())
+
+	PurgeStickyCache()
+	assert.Equal(t, 0, stickyCache.size())
+	assert.Nil(t, getProcessStickyCache().getWorkflowContext("runID2"))
+
+	stickyCache.close()
+	stickyCacheLock.Lock()
+	_, ok := stickyCaches[stickyCache]
+	stickyCacheLock.Unlock()
+	assert.False(t, ok)
+}
+
+func TestStickyCacheHitRatio(t *testing.T) {
+	stickyCache := newStickyWorkflowCache(StickyCacheOptions{})
+	defer stickyCache.close()
+	assert.Equal(t, 0.0, stickyCache.recordLookup(false))
+	assert.Equal(t, 0.5, stickyCache.recordLookup(true))
+	assert.Equal(t, 2.0/3, stickyCache.recordLookup(true))
+}
+
+func TestEstimateHistoryEventSize(t *testing.T) {
+	event := &s.HistoryEvent{
+		WorkflowExecutionSignaledEventAttributes: &s.WorkflowExecutionSignaledEventAttributes{Input: make([]byte, 100)},
+	}
+	assert.Equal(t, int64(estimatedHistoryEventSize+100), estimateHistoryEventSize(event))
+	assert.Equal(t, int64(estimatedHistoryEventSize), estimateHistoryEventSize(&s.HistoryEvent{}))
+
+	wc := &workflowExecutionContext{}
+	wc.addEstimatedSize(estimateHistoryEventSize(event))
+	assert.Equal(t, int64(estimatedHistoryEventSize+100), wc.getEstimatedSize())
+	wc.destroyCachedState()
+	assert.Equal(t, int64(0), wc.getEstimatedSize())
+}
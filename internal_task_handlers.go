@@ -29,6 +29,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/uber-go/tally"
@@ -36,7 +37,6 @@ import (
 	s "go.uber.org/cadence/.gen/go/shared"
 	"go.uber.org/cadence/common"
 	"go.uber.org/cadence/common/backoff"
-	"go.uber.org/cadence/common/cache"
 	"go.uber.org/cadence/common/metrics"
 	"go.uber.org/cadence/common/util"
 	"go.uber.org/zap"
@@ -44,8 +44,6 @@ import (
 
 const (
 	defaultHeartBeatIntervalInSec = 10 * 60
-
-	defaultStickyCacheSize = 10000
 )
 
 type (
@@ -76,6 +74,7 @@ type (
 
 	// workflowExecutionContext is the cached workflow state for sticky execution
 	workflowExecutionContext struct {
+		estimatedSize int64 // accessed atomically by the sticky cache, first field for 64-bit alignment
 		sync.Mutex
 		workflowStartTime time.Time
 		runID             string
@@ -93,19 +92,24 @@ type (
 
 	// workflowTaskHandlerImpl is the implementation of WorkflowTaskHandler
 	workflowTaskHandlerImpl struct {
-		domain                 string
-		metricsScope           tally.Scope
-		ppMgr                  pressurePointMgr
-		logger                 *zap.Logger
-		identity               string
-		enableLoggingInReplay  bool
-		disableStickyExecution bool
-		hostEnv                *hostEnvImpl
+		domain                   string
+		metricsScope             tally.Scope
+		ppMgr                    pressurePointMgr
+		logger                   *zap.Logger
+		identity                 string
+		enableLoggingInReplay    bool
+		disableStickyExecution   bool
+		hostEnv                  *hostEnvImpl
+		workflowInterceptors     []WorkflowInterceptorFactory
+		deadlockDetectionTimeout time.Duration
+		nonDeterministicPolicy   NonDeterministicWorkflowPolicy
+		stickyCache              *stickyWorkflowCache
 	}
 
 	activityProvider func(name string) activity
 	// activityTaskHandlerImpl is the implementation of ActivityTaskHandler
 	activityTaskHandlerImpl struct {
+		domain           string
 		taskListName     string
 		identity         string
 		service          workflowserviceclient.Interface
@@ -114,6 +118,10 @@ type (
 		userContext      context.Context
 		hostEnv          *hostEnvImpl
 		activityProvider activityProvider
+		interceptors     []ActivityInterceptorFactory
+		workerStopCh     <-chan struct{}
+		autoHeartBeat    bool
+		faults           *faultInjector
 	}
 
 	// history wrapper method to help information about events.
@@ -325,45 +333,24 @@ func newWorkflowTaskHandler(
 	hostEnv *hostEnvImpl,
 ) WorkflowTaskHandler {
 	ensureRequiredParams(&params)
-	return &workflowTaskHandlerImpl{
-		domain:                 domain,
-		logger:                 params.Logger,
-		ppMgr:                  ppMgr,
-		metricsScope:           params.MetricsScope,
-		identity:               params.Identity,
-		enableLoggingInReplay:  params.EnableLoggingInReplay,
-		disableStickyExecution: params.DisableStickyExecution,
-		hostEnv:                hostEnv,
+	stickyCache := params.StickyCache
+	if stickyCache == nil {
+		stickyCache = getProcessStickyCache()
 	}
-}
-
-// TODO: need a better eviction policy based on memory usage
-var workflowCache = cache.New(defaultStickyCacheSize, &cache.Options{
-	RemovedFunc: func(cachedEntity interface{}) {
-		wc := cachedEntity.(*workflowExecutionContext)
-		wc.onEviction()
-	},
-})
-
-func getWorkflowContext(runID string) *workflowExecutionContext {
-	o := workflowCache.Get(runID)
-	if o == nil {
-		return nil
-	}
-	wc := o.(*workflowExecutionContext)
-	return wc
-}
-
-func putWorkflowContext(runID string, wc *workflowExecutionContext) (*workflowExecutionContext, error) {
-	existing, err := workflowCache.PutIfNotExist(runID, wc)
-	if err != nil {
-		return nil, err
+	return &workflowTaskHandlerImpl{
+		domain:                   domain,
+		logger:                   params.Logger,
+		ppMgr:                    ppMgr,
+		metricsScope:             params.MetricsScope,
+		identity:                 params.Identity,
+		enableLoggingInReplay:    params.EnableLoggingInReplay,
+		disableStickyExecution:   params.DisableStickyExecution,
+		hostEnv:                  hostEnv,
+		workflowInterceptors:     params.WorkflowInterceptors,
+		deadlockDetectionTimeout: params.DeadlockDetectionTimeout,
+		nonDeterministicPolicy:   params.NonDeterministicWorkflowPolicy,
+		stickyCache:              stickyCache,
 	}
-	return existing.(*workflowExecutionContext), nil
-}
-
-func removeWorkflowContext(runID string) {
-	workflowCache.Delete(runID)
 }
 
 func (w *workflowExecutionContext) release() {
@@ -383,6 +370,14 @@ func (w *workflowExecutionContext) onEviction() {
 	w.Unlock()
 }
 
+func (w *workflowExecutionContext) getEstimatedSize() int64 {
+	return atomic.LoadInt64(&w.estimatedSize)
+}
+
+func (w *workflowExecutionContext) addEstimatedSize(size int64) {
+	atomic.AddInt64(&w.estimatedSize, size)
+}
+
 func (w *workflowExecutionContext) isDestroyed() bool {
 	return w.eventHandler == nil
 }
@@ -392,6 +387,7 @@ func (w *workflowExecutionContext) destroyCachedState() {
 	w.result = nil
 	w.err = nil
 	w.previousStartedEventID = 0
+	atomic.StoreInt64(&w.estimatedSize, 0)
 	if w.eventHandler != nil {
 		w.eventHandler.Close()
 		w.eventHandler = nil
@@ -406,7 +402,9 @@ func (w *workflowExecutionContext) resetWorkflowState() {
 		w.wth.logger,
 		w.wth.enableLoggingInReplay,
 		w.wth.metricsScope,
-		w.wth.hostEnv)
+		w.wth.hostEnv,
+		w.wth.workflowInterceptors,
+		w.wth.deadlockDetectionTimeout)
 }
 
 func resetHistory(task *s.PollForDecisionTaskResponse, historyIterator HistoryIterator) (*s.History, error) {
@@ -462,7 +460,7 @@ func (wth *workflowTaskHandlerImpl) getOrCreateWorkflowContext(task *s.PollForDe
 	workflowContext = nil
 	if task.Query == nil {
 		// TODO: we don't use cached workflow context for query task. Will address that shortly.
-		workflowContext = getWorkflowContext(runID)
+		workflowContext = wth.stickyCache.getWorkflowContext(runID)
 	}
 
 	if workflowContext != nil {
@@ -476,10 +474,12 @@ func (wth *workflowTaskHandlerImpl) getOrCreateWorkflowContext(task *s.PollForDe
 				zap.Int64("TaskPreviousStartedEventID", task.GetPreviousStartedEventId()))
 
 			wth.metricsScope.Counter(metrics.StickyCacheStall).Inc(1)
+			wth.reportStickyCacheHit(false)
 			workflowContext.destroyCachedState()
 		} else {
 			// we have a valid cached state
 			wth.metricsScope.Counter(metrics.StickyCacheHit).Inc(1)
+			wth.reportStickyCacheHit(true)
 			skipReplayCheck = true
 		}
 	} else {
@@ -487,6 +487,7 @@ func (wth *workflowTaskHandlerImpl) getOrCreateWorkflowContext(task *s.PollForDe
 			// we are getting partial history task, but cached state was already evicted.
 			// we need to reset history so we get events from beginning to replay/rebuild the state
 			wth.metricsScope.Counter(metrics.StickyCacheMiss).Inc(1)
+			wth.reportStickyCacheHit(false)
 			if h, err = resetHistory(task, historyIterator); err != nil {
 				return
 			}
@@ -496,7 +497,7 @@ func (wth *workflowTaskHandlerImpl) getOrCreateWorkflowContext(task *s.PollForDe
 		}
 
 		if !wth.disableStickyExecution && task.Query == nil {
-			workflowContext, _ = putWorkflowContext(runID, workflowContext)
+			workflowContext, _ = wth.stickyCache.putWorkflowContext(runID, workflowContext)
 		}
 		workflowContext.Lock()
 	}
@@ -517,6 +518,11 @@ func (wth *workflowTaskHandlerImpl) getOrCreateWorkflowContext(task *s.PollForDe
 	return
 }
 
+// reportStickyCacheHit reports the hit ratio of the sticky cache after a decision task looked up its cached state.
+func (wth *workflowTaskHandlerImpl) reportStickyCacheHit(hit bool) {
+	wth.metricsScope.Gauge(metrics.StickyCacheHitRatio).Update(wth.stickyCache.recordLookup(hit))
+}
+
 // ProcessWorkflowTask processes each all the events of the workflow task.
 func (wth *workflowTaskHandlerImpl) ProcessWorkflowTask(
 	task *s.PollForDecisionTaskResponse,
@@ -553,8 +559,11 @@ func (wth *workflowTaskHandlerImpl) ProcessWorkflowTask(
 			// error to indicate the close failure case. This should be rear case. For now, always remove the cache, and
 			// if the close decision failed, the next decision will have to rebuild the state.
 			workflowContext.destroyCachedState()
-			removeWorkflowContext(runID)
+			wth.stickyCache.removeWorkflowContext(runID)
+		} else if task.Query == nil && !wth.disableStickyExecution {
+			wth.stickyCache.updateWorkflowContextSize(runID)
 		}
+		wth.metricsScope.Gauge(metrics.StickyCacheSize).Update(float64(wth.stickyCache.size()))
 
 		workflowContext.release()
 	}()
@@ -604,6 +613,7 @@ ProcessEvents:
 			if err != nil {
 				return nil, "", err
 			}
+			workflowContext.addEstimatedSize(estimateHistoryEventSize(event))
 
 			if eventDecisions != nil {
 				if !isInReplay {
@@ -623,9 +633,26 @@ ProcessEvents:
 
 	if !skipReplayCheck {
 		// check if decisions from reply matches to the history events
-		if err := matchReplayWithHistory(replayDecisions, respondEvents); err != nil {
-			wth.logger.Error("Replay and history mismatch.", zap.Error(err))
-			return nil, "", err
+		if ndErr := matchReplayWithHistory(replayDecisions, respondEvents); ndErr != nil {
+			ndErr.stackTrace = eventHandler.StackTrace()
+			wth.metricsScope.Counter(metrics.NonDeterministicErrorCounter).Inc(1)
+			wth.logger.Error("Replay and history mismatch.",
+				zap.String(tagWorkflowType, task.WorkflowType.GetName()),
+				zap.String(tagWorkflowID, workflowID),
+				zap.String(tagRunID, runID),
+				zap.Error(ndErr),
+				zap.String("StackTrace", ndErr.StackTrace()))
+
+			switch wth.nonDeterministicPolicy {
+			case NonDeterministicWorkflowPolicyFailWorkflow:
+				// The decisions of the current workflow code are discarded, only the workflow failure is reported.
+				decisions = []*s.Decision{}
+				workflowContext.completeWorkflow(nil, ndErr)
+			case NonDeterministicWorkflowPolicyLogAndContinue:
+				// Keep going with the decisions made by the current workflow code.
+			default:
+				return nil, "", ndErr
+			}
 		}
 	}
 
@@ -721,7 +748,7 @@ func isVersionMarkerEvent(e *s.HistoryEvent) bool {
 	return false
 }
 
-func matchReplayWithHistory(replayDecisions []*s.Decision, historyEvents []*s.HistoryEvent) error {
+func matchReplayWithHistory(replayDecisions []*s.Decision, historyEvents []*s.HistoryEvent) *NonDeterministicError {
 	di := 0
 	hi := 0
 	hSize := len(historyEvents)
@@ -746,16 +773,18 @@ matchLoop:
 			continue matchLoop
 		}
 		if d == nil {
-			return fmt.Errorf("nondeterministic workflow: missing replay decision for %s", util.HistoryEventToString(e))
+			return newNonDeterministicError(fmt.Sprintf("missing replay decision for %s", util.HistoryEventToString(e)),
+				replayDecisions, historyEvents, di, hi)
 		}
 
 		if e == nil {
-			return fmt.Errorf("nondeterministic workflow: extra replay decision for %s", util.DecisionToString(d))
+			return newNonDeterministicError(fmt.Sprintf("extra replay decision for %s", util.DecisionToString(d)),
+				replayDecisions, historyEvents, di, hi)
 		}
 
 		if !isDecisionMatchEvent(d, e, false) {
-			return fmt.Errorf("nondeterministic workflow: history event is %s, replay decision is %s",
-				util.HistoryEventToString(e), util.DecisionToString(d))
+			return newNonDeterministicError(fmt.Sprintf("history event is %s, replay decision is %s",
+				util.HistoryEventToString(e), util.DecisionToString(d)), replayDecisions, historyEvents, di, hi)
 		}
 
 		di++
@@ -764,6 +793,73 @@ matchLoop:
 	return nil
 }
 
+// newNonDeterministicError creates the error with the replay decisions and the history events rendered side by side
+// in the order they are matched. The row of the mismatched pair at the given indexes is marked with ">>".
+func newNonDeterministicError(
+	reason string,
+	replayDecisions []*s.Decision,
+	historyEvents []*s.HistoryEvent,
+	mismatchedDecision, mismatchedEvent int,
+) *NonDeterministicError {
+	var rows [][2]string
+	markedRow := -1
+	di := 0
+	hi := 0
+	for hi < len(historyEvents) || di < len(replayDecisions) {
+		var e *s.HistoryEvent
+		if hi < len(historyEvents) {
+			e = historyEvents[hi]
+		}
+		var d *s.Decision
+		if di < len(replayDecisions) {
+			d = replayDecisions[di]
+		}
+
+		var row [2]string
+		switch {
+		case isVersionMarkerEvent(e):
+			row[1] = util.HistoryEventToString(e)
+			hi++
+		case isVersionMarkerDecision(d):
+			row[0] = util.DecisionToString(d)
+			di++
+		default:
+			if di == mismatchedDecision && hi == mismatchedEvent {
+				markedRow = len(rows)
+			}
+			if d != nil {
+				row[0] = util.DecisionToString(d)
+				di++
+			}
+			if e != nil {
+				row[1] = util.HistoryEventToString(e)
+				hi++
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	rows = append([][2]string{{"REPLAY DECISIONS", "HISTORY EVENTS"}}, rows...)
+	if markedRow >= 0 {
+		markedRow++ // header row
+	}
+	width := 0
+	for _, row := range rows {
+		if len(row[0]) > width {
+			width = len(row[0])
+		}
+	}
+	var buf bytes.Buffer
+	for i, row := range rows {
+		marker := "  "
+		if i == markedRow {
+			marker = ">>"
+		}
+		fmt.Fprintf(&buf, "%s %-*s | %s\n", marker, width, row[0], row[1])
+	}
+	return &NonDeterministicError{reason: reason, mismatch: buf.String()}
+}
+
 func isDecisionMatchEvent(d *s.Decision, e *s.HistoryEvent, strictMode bool) bool {
 	switch d.GetDecisionType() {
 	case s.DecisionTypeScheduleActivityTask:
@@ -984,19 +1080,22 @@ func (wth *workflowTaskHandlerImpl) reportAnyMetrics(event *s.HistoryEvent, isIn
 
 func newActivityTaskHandler(
 	service workflowserviceclient.Interface,
+	domain string,
 	params workerExecutionParameters,
 	env *hostEnvImpl,
 ) ActivityTaskHandler {
-	return newActivityTaskHandlerWithCustomProvider(service, params, env, nil)
+	return newActivityTaskHandlerWithCustomProvider(service, domain, params, env, nil)
 }
 
 func newActivityTaskHandlerWithCustomProvider(
 	service workflowserviceclient.Interface,
+	domain string,
 	params workerExecutionParameters,
 	env *hostEnvImpl,
 	activityProvider activityProvider,
 ) ActivityTaskHandler {
 	return &activityTaskHandlerImpl{
+		domain:           domain,
 		taskListName:     params.TaskList,
 		identity:         params.Identity,
 		service:          service,
@@ -1005,6 +1104,10 @@ func newActivityTaskHandlerWithCustomProvider(
 		userContext:      params.UserContext,
 		hostEnv:          env,
 		activityProvider: activityProvider,
+		interceptors:     params.ActivityInterceptors,
+		workerStopCh:     params.WorkerStopChannel,
+		autoHeartBeat:    params.AutoHeartBeat,
+		faults:           params.FaultInjector,
 	}
 }
 
@@ -1018,13 +1121,18 @@ type cadenceInvoker struct {
 	heartBeatTimeoutInSec int32       // The heart beat interval configured for this activity.
 	hbBatchEndTimer       *time.Timer // Whether we started a batch of operations that need to be reported in the cycle. This gets started on a user call.
 	lastDetailsToReport   *[]byte
+	lastDetails           []byte // The last details recorded by the user, reported by the auto heartbeat.
 	closeCh               chan struct{}
+	faults                *faultInjector
+	activityType          string
 }
 
 func (i *cadenceInvoker) Heartbeat(details []byte) error {
 	i.Lock()
 	defer i.Unlock()
 
+	i.lastDetails = details
+
 	if i.hbBatchEndTimer != nil {
 		// If we have started batching window, keep track of last reported progress.
 		i.lastDetailsToReport = &details
@@ -1079,7 +1187,10 @@ func (i *cadenceInvoker) Heartbeat(details []byte) error {
 
 func (i *cadenceInvoker) internalHeartBeat(details []byte) (bool, error) {
 	isActivityCancelled := false
-	err := recordActivityHeartbeat(context.Background(), i.service, i.identity, i.taskToken, details, i.retryPolicy)
+	err := i.faults.inject(FaultInjectionTargetHeartbeat, "", i.activityType)
+	if err == nil {
+		err = recordActivityHeartbeat(context.Background(), i.service, i.identity, i.taskToken, details, i.retryPolicy)
+	}
 
 	switch err.(type) {
 	case *CanceledError:
@@ -1099,6 +1210,30 @@ func (i *cadenceInvoker) internalHeartBeat(details []byte) (bool, error) {
 	return isActivityCancelled, err
 }
 
+// autoHeartbeat heartbeats with the last recorded details at half of the heartbeat timeout until
+// the invoker is closed. The heartbeats go through the batching window of Heartbeat, so they don't
+// add calls to the service when the activity is heartbeating on its own.
+func (i *cadenceInvoker) autoHeartbeat() {
+	if i.heartBeatTimeoutInSec <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(i.heartBeatTimeoutInSec) * time.Second / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-i.closeCh:
+			return
+		}
+
+		i.Lock()
+		details := i.lastDetails
+		i.Unlock()
+		i.Heartbeat(details)
+	}
+}
+
 func (i *cadenceInvoker) Close() {
 	i.Lock()
 	defer i.Unlock()
@@ -1116,6 +1251,16 @@ func newServiceInvoker(
 	cancelHandler func(),
 	heartBeatTimeoutInSec int32,
 ) ServiceInvoker {
+	return newCadenceInvoker(taskToken, identity, service, cancelHandler, heartBeatTimeoutInSec)
+}
+
+func newCadenceInvoker(
+	taskToken []byte,
+	identity string,
+	service workflowserviceclient.Interface,
+	cancelHandler func(),
+	heartBeatTimeoutInSec int32,
+) *cadenceInvoker {
 	return &cadenceInvoker{
 		taskToken:             taskToken,
 		identity:              identity,
@@ -1140,10 +1285,16 @@ func (ath *activityTaskHandlerImpl) Execute(t *s.PollForActivityTaskResponse) (r
 	if rootCtx == nil {
 		rootCtx = context.Background()
 	}
-	canCtx, cancel := context.WithCancel(rootCtx)
-	invoker := newServiceInvoker(t.TaskToken, ath.identity, ath.service, cancel, t.GetHeartbeatTimeoutSeconds())
+	canCtx, cancel := newWorkerStopContext(rootCtx, ath.workerStopCh)
+	defer cancel()
+	invoker := newCadenceInvoker(t.TaskToken, ath.identity, ath.service, cancel, t.GetHeartbeatTimeoutSeconds())
+	invoker.faults = ath.faults
+	invoker.activityType = t.ActivityType.GetName()
 	defer invoker.Close()
-	ctx := WithActivityTask(canCtx, t, invoker, ath.logger, ath.metricsScope)
+	if ath.autoHeartBeat {
+		go invoker.autoHeartbeat()
+	}
+	ctx := withActivityTask(canCtx, t, ath.domain, invoker, ath.logger, ath.metricsScope, ath.interceptors)
 	activityType := *t.ActivityType
 	activityImplementation := ath.getActivity(activityType.GetName())
 	if activityImplementation == nil {
@@ -1165,18 +1316,12 @@ func (ath *activityTaskHandlerImpl) Execute(t *s.PollForActivityTaskResponse) (r
 		}
 	}()
 
-	var deadline time.Time
-	scheduleToCloseDeadline := time.Unix(0, t.GetScheduledTimestamp()).Add(time.Duration(t.GetScheduleToCloseTimeoutSeconds()) * time.Second)
-	startToCloseDeadline := time.Unix(0, t.GetStartedTimestamp()).Add(time.Duration(t.GetStartToCloseTimeoutSeconds()) * time.Second)
-	// Minimum of the two deadlines.
-	if scheduleToCloseDeadline.Before(startToCloseDeadline) {
-		deadline = scheduleToCloseDeadline
-	} else {
-		deadline = startToCloseDeadline
-	}
-	ctx, dlCancelFunc := context.WithDeadline(ctx, deadline)
+	ctx, dlCancelFunc := context.WithDeadline(ctx, getActivityEnv(ctx).deadline)
 
-	output, err := activityImplementation.Execute(ctx, t.Input)
+	var output []byte
+	if err = ath.faults.inject(FaultInjectionTargetActivityExecution, "", activityType.GetName()); err == nil {
+		output, err = activityImplementation.Execute(ctx, t.Input)
+	}
 
 	dlCancelFunc()
 	if <-ctx.Done(); ctx.Err() == context.DeadlineExceeded {
@@ -323,6 +323,28 @@ func (t *TaskHandlersTestSuite) TestWorkflowTask_NondeterministicDetection() {
 	t.Error(err)
 	t.Nil(request)
 	t.Contains(err.Error(), "nondeterministic")
+	ndErr, ok := err.(*NonDeterministicError)
+	t.True(ok)
+	t.Contains(ndErr.Error(), ">> ScheduleActivityTask: ")
+	t.Contains(ndErr.Error(), "some-other-activity")
+
+	// the workflow fails with the mismatch
+	params.NonDeterministicWorkflowPolicy = NonDeterministicWorkflowPolicyFailWorkflow
+	taskHandler = newWorkflowTaskHandler(testDomain, params, nil, getHostEnvironment())
+	request, _, err = taskHandler.ProcessWorkflowTask(task, nil, false)
+	t.NoError(err)
+	response = request.(*s.RespondDecisionTaskCompletedRequest)
+	t.Equal(1, len(response.Decisions))
+	t.Equal(s.DecisionTypeFailWorkflowExecution, response.Decisions[0].GetDecisionType())
+	t.Contains(string(response.Decisions[0].FailWorkflowExecutionDecisionAttributes.Details), "some-other-activity")
+
+	// the mismatch is ignored
+	params.NonDeterministicWorkflowPolicy = NonDeterministicWorkflowPolicyLogAndContinue
+	taskHandler = newWorkflowTaskHandler(testDomain, params, nil, getHostEnvironment())
+	request, _, err = taskHandler.ProcessWorkflowTask(task, nil, false)
+	t.NoError(err)
+	response = request.(*s.RespondDecisionTaskCompletedRequest)
+	t.NotNil(response)
 }
 
 func (t *TaskHandlersTestSuite) TestWorkflowTask_CancelActivityBeforeSent() {
@@ -505,7 +527,7 @@ func (t *TaskHandlersTestSuite) TestActivityExecutionDeadline() {
 		wep := workerExecutionParameters{
 			Logger: t.logger,
 		}
-		activityHandler := newActivityTaskHandler(mockService, wep, hostEnv)
+		activityHandler := newActivityTaskHandler(mockService, "", wep, hostEnv)
 		pats := &s.PollForActivityTaskResponse{
 			TaskToken: []byte("token"),
 			WorkflowExecution: &s.WorkflowExecution{
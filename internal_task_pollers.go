@@ -60,6 +60,17 @@ type (
 		ProcessTask(interface{}) error
 	}
 
+	// taskThrottler is implemented by the task pollers that hold back the tasks over some limits before processing
+	// them. The base worker doesn't count the tasks waiting for the limits towards its concurrent tasks.
+	taskThrottler interface {
+		// tryThrottleTask returns the function releasing the limits once the task is processed, and false if the
+		// task is over the limits.
+		tryThrottleTask(task interface{}) (release func(), ok bool)
+		// throttleTask waits until the task is within the limits, and returns the function releasing them once the
+		// task is processed.
+		throttleTask(ctx context.Context, task interface{}) (release func(), err error)
+	}
+
 	// workflowTaskPoller implements polling/processing a workflow task
 	workflowTaskPoller struct {
 		domain       string
@@ -69,6 +80,7 @@ type (
 		taskHandler  WorkflowTaskHandler
 		metricsScope tally.Scope
 		logger       *zap.Logger
+		faults       *faultInjector
 
 		disableStickyExecution       bool
 		StickyScheduleToStartTimeout time.Duration
@@ -88,6 +100,9 @@ type (
 		taskHandler  ActivityTaskHandler
 		metricsScope tally.Scope
 		logger       *zap.Logger
+		faults       *faultInjector
+		hostEnv      *hostEnvImpl
+		throttler    *activityThrottler
 	}
 
 	historyIteratorImpl struct {
@@ -98,6 +113,8 @@ type (
 		service       workflowserviceclient.Interface
 		metricsScope  tally.Scope
 		maxEventID    int64
+		faults        *faultInjector
+		workflowType  string
 	}
 )
 
@@ -147,6 +164,7 @@ func newWorkflowTaskPoller(taskHandler WorkflowTaskHandler, service workflowserv
 		taskHandler:  taskHandler,
 		metricsScope: params.MetricsScope,
 		logger:       params.Logger,
+		faults:       params.FaultInjector,
 
 		disableStickyExecution:       params.DisableStickyExecution,
 		StickyScheduleToStartTimeout: params.StickyScheduleToStartTimeout,
@@ -160,6 +178,11 @@ func (wtp *workflowTaskPoller) PollTask() (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if workflowTask.task != nil {
+		if err := wtp.faults.inject(FaultInjectionTargetPoll, workflowTask.task.WorkflowType.GetName(), ""); err != nil {
+			return nil, err
+		}
+	}
 
 	return workflowTask, nil
 }
@@ -184,6 +207,11 @@ func (wtp *workflowTaskPoller) ProcessTask(task interface{}) error {
 	}
 	wtp.metricsScope.Timer(metrics.DecisionExecutionLatency).Record(time.Now().Sub(executionStartTime))
 
+	if err := wtp.faults.inject(FaultInjectionTargetRespond, workflowTask.task.WorkflowType.GetName(), ""); err != nil {
+		wtp.metricsScope.Counter(metrics.DecisionResponseFailedCounter).Inc(1)
+		return err
+	}
+
 	ctx := context.Background()
 	responseStartTime := time.Now()
 	// Respond task completion.
@@ -334,6 +362,8 @@ func (wtp *workflowTaskPoller) poll() (*workflowTask, error) {
 		service:       wtp.service,
 		metricsScope:  wtp.metricsScope,
 		maxEventID:    response.GetStartedEventId(),
+		faults:        wtp.faults,
+		workflowType:  response.WorkflowType.GetName(),
 	}
 	task := &workflowTask{
 		task:            response,
@@ -356,6 +386,9 @@ func (h *historyIteratorImpl) GetNextPage() (*s.History, error) {
 			h.metricsScope)
 	}
 
+	if err := h.faults.inject(FaultInjectionTargetHistoryFetch, h.workflowType, ""); err != nil {
+		return nil, err
+	}
 	history, token, err := h.iteratorFunc(h.nextPageToken)
 	if err != nil {
 		return nil, err
@@ -421,7 +454,7 @@ func newGetHistoryPageFunc(
 }
 
 func newActivityTaskPoller(taskHandler ActivityTaskHandler, service workflowserviceclient.Interface,
-	domain string, params workerExecutionParameters) *activityTaskPoller {
+	domain string, params workerExecutionParameters, hostEnv *hostEnvImpl) *activityTaskPoller {
 	return &activityTaskPoller{
 		taskHandler:  taskHandler,
 		service:      metrics.NewWorkflowServiceWrapper(service, params.MetricsScope),
@@ -429,7 +462,10 @@ func newActivityTaskPoller(taskHandler ActivityTaskHandler, service workflowserv
 		taskListName: params.TaskList,
 		identity:     params.Identity,
 		logger:       params.Logger,
-		metricsScope: params.MetricsScope}
+		metricsScope: params.MetricsScope,
+		faults:       params.FaultInjector,
+		hostEnv:      hostEnv,
+		throttler:    newActivityThrottler(params.MetricsScope)}
 }
 
 // Poll for a single activity task from the service
@@ -476,9 +512,45 @@ func (atp *activityTaskPoller) PollTask() (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if activityTask.task != nil {
+		if err := atp.faults.inject(FaultInjectionTargetPoll, "", activityTask.task.ActivityType.GetName()); err != nil {
+			return nil, err
+		}
+	}
 	return activityTask, nil
 }
 
+// tryThrottleTask acquires the limits of the activity type of the task if it is within them.
+func (atp *activityTaskPoller) tryThrottleTask(task interface{}) (func(), bool) {
+	t := task.(*activityTask).task
+	if t == nil {
+		return func() {}, true
+	}
+	activityType := t.ActivityType.GetName()
+	return atp.throttler.tryAcquire(activityType, atp.getActivityLimits(activityType))
+}
+
+// throttleTask waits until the activity type of the task is within its limits, or until the activity times out. The
+// activity heartbeats while it waits, so that it doesn't time out for the missing heartbeats.
+func (atp *activityTaskPoller) throttleTask(ctx context.Context, task interface{}) (func(), error) {
+	t := task.(*activityTask).task
+	ctx, cancel := context.WithDeadline(ctx, getActivityTaskDeadline(t))
+	defer cancel()
+	invoker := newCadenceInvoker(t.TaskToken, atp.identity, atp.service, func() {}, t.GetHeartbeatTimeoutSeconds())
+	defer invoker.Close()
+	go invoker.autoHeartbeat()
+
+	activityType := t.ActivityType.GetName()
+	return atp.throttler.acquire(ctx, activityType, atp.getActivityLimits(activityType))
+}
+
+func (atp *activityTaskPoller) getActivityLimits(activityType string) activityLimits {
+	if atp.hostEnv == nil {
+		return activityLimits{}
+	}
+	return atp.hostEnv.getActivityLimits(activityType)
+}
+
 // ProcessTask processes a new task
 func (atp *activityTaskPoller) ProcessTask(task interface{}) error {
 	activityTask := task.(*activityTask)
@@ -505,7 +577,10 @@ func (atp *activityTaskPoller) ProcessTask(task interface{}) error {
 	}
 
 	responseStartTime := time.Now()
-	reportErr := reportActivityComplete(context.Background(), atp.service, request, atp.metricsScope)
+	reportErr := atp.faults.inject(FaultInjectionTargetRespond, "", activityTask.task.ActivityType.GetName())
+	if reportErr == nil {
+		reportErr = reportActivityComplete(context.Background(), atp.service, request, atp.metricsScope)
+	}
 	if reportErr != nil {
 		atp.metricsScope.Counter(metrics.ActivityResponseFailedCounter).Inc(1)
 		traceLog(func() {
@@ -47,4 +47,116 @@ type (
 		// If the timer is not started then it is a no-operation.
 		RequestCancelTimer(timerID string)
 	}
+
+	// resettableTimerImpl keeps at most one pending timer. Resets that move the deadline later only update the
+	// deadline, the pending timer is re-armed for the remaining time when it fires.
+	resettableTimerImpl struct {
+		*futureImpl
+		env      workflowEnvironment
+		timer    *timerInfo // pending timer, nil if none
+		fireTime time.Time  // when the pending timer fires
+		deadline time.Time  // when the resettable timer should fire
+	}
+
+	tickerImpl struct {
+		channel Channel
+		cancel  CancelFunc
+	}
 )
+
+func (t *resettableTimerImpl) Reset(d time.Duration) {
+	if t.IsReady() {
+		return
+	}
+	if d <= 0 {
+		t.cancelTimer()
+		t.Set(true, nil)
+		return
+	}
+
+	t.deadline = t.env.Now().Add(d)
+	if t.timer != nil && !t.fireTime.After(t.deadline) {
+		// pending timer fires before the new deadline, it will be re-armed then.
+		return
+	}
+	t.cancelTimer()
+	t.startTimer(d)
+}
+
+func (t *resettableTimerImpl) Stop() {
+	if t.IsReady() {
+		return
+	}
+	t.cancelTimer()
+	t.Set(nil, ErrCanceled)
+}
+
+func (t *resettableTimerImpl) startTimer(d time.Duration) {
+	// timer resolution is in seconds, round up so that the timer never fires before the deadline.
+	if r := d % time.Second; r != 0 {
+		d += time.Second - r
+	}
+
+	var info *timerInfo
+	info = t.env.NewTimer(d, func(r []byte, e error) {
+		if info == nil || t.timer != info {
+			// stale callback of a canceled timer.
+			return
+		}
+		t.timer = nil
+		t.handleTimerFired(e)
+	})
+	t.timer = info
+	t.fireTime = t.env.Now().Add(d)
+}
+
+func (t *resettableTimerImpl) cancelTimer() {
+	if t.timer == nil {
+		return
+	}
+	timerID := t.timer.timerID
+	t.timer = nil
+	t.env.RequestCancelTimer(timerID)
+}
+
+func (t *resettableTimerImpl) handleTimerFired(err error) {
+	if t.IsReady() {
+		return
+	}
+	if err != nil {
+		t.Set(nil, err)
+		return
+	}
+	if remaining := t.deadline.Sub(t.env.Now()); remaining > 0 {
+		t.startTimer(remaining)
+		return
+	}
+	t.Set(true, nil)
+}
+
+func (t *tickerImpl) Chan() Channel {
+	return t.channel
+}
+
+func (t *tickerImpl) Stop() {
+	t.cancel()
+}
+
+func (t *tickerImpl) run(ctx Context, startTime time.Time, interval time.Duration) {
+	defer t.channel.Close()
+	for {
+		// ticks are scheduled from the start time rather than from the previous tick, so that they don't drift.
+		now := Now(ctx)
+		next := startTime.Add((now.Sub(startTime)/interval + 1) * interval)
+		if now.Before(startTime) {
+			// the division truncates toward zero, so step back from the start time to the first tick after now.
+			next = startTime.Add(-((startTime.Sub(now) - 1) / interval) * interval)
+		}
+		if err := NewTimer(ctx, next.Sub(now)).Get(ctx, nil); err != nil {
+			// ticker is stopped or ctx is canceled.
+			return
+		}
+		// drop the tick if the previous one was not received yet.
+		t.channel.SendAsync(Now(ctx))
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	s "go.uber.org/cadence/.gen/go/shared"
+)
+
+type (
+	tracingWorkflowInterceptorFactory struct {
+		tracer opentracing.Tracer
+	}
+
+	// tracingWorkflowInterceptor creates the spans of a workflow execution. The span contexts travel in the headers
+	// of the requests and decisions, next to the inputs which are left unchanged. Spans are only created when the
+	// workflow is not replaying, so a decision that is replayed on another worker doesn't report its spans twice. The
+	// decision matcher ignores the headers, so replayed decisions without them still match the history. The parent
+	// of the spans is the span of the workflow execution when the execution was not replayed by the worker.
+	tracingWorkflowInterceptor struct {
+		WorkflowInterceptorBase
+		tracer opentracing.Tracer
+		info   *WorkflowInfo
+		parent opentracing.SpanContext
+	}
+
+	tracingActivityInterceptorFactory struct {
+		tracer opentracing.Tracer
+	}
+
+	tracingActivityInterceptor struct {
+		ActivityInterceptorBase
+		tracer opentracing.Tracer
+		info   *ActivityInfo
+	}
+)
+
+var _ WorkflowInterceptor = (*tracingWorkflowInterceptor)(nil)
+var _ ActivityInterceptor = (*tracingActivityInterceptor)(nil)
+
+// getWorkflowInterceptorFactories returns the workflow interceptors of the worker, the tracing interceptor is the
+// outermost one so its spans cover the other interceptors.
+func getWorkflowInterceptorFactories(options WorkerOptions) []WorkflowInterceptorFactory {
+	if options.Tracer == nil {
+		return options.WorkflowInterceptors
+	}
+	factories := []WorkflowInterceptorFactory{&tracingWorkflowInterceptorFactory{tracer: options.Tracer}}
+	return append(factories, options.WorkflowInterceptors...)
+}
+
+// getActivityInterceptorFactories returns the activity interceptors of the worker with the tracing interceptor as
+// the outermost one.
+func getActivityInterceptorFactories(options WorkerOptions) []ActivityInterceptorFactory {
+	if options.Tracer == nil {
+		return options.ActivityInterceptors
+	}
+	factories := []ActivityInterceptorFactory{&tracingActivityInterceptorFactory{tracer: options.Tracer}}
+	return append(factories, options.ActivityInterceptors...)
+}
+
+// startClientSpan starts the span of a client call, the span is a child of the span in the context if there is one.
+// The returned context holds the span, the transport propagates it to the service in the headers of the request. The
+// workers get the span context from the header returned by injectSpanContext.
+func startClientSpan(ctx context.Context, tracer opentracing.Tracer, operationName string,
+	opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	span := tracer.StartSpan(operationName, opts...)
+	ext.SpanKindRPCClient.Set(span)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// injectSpanContext returns a header that carries the span context in the text map format of the tracer, nil if the
+// tracer can't inject it.
+func injectSpanContext(tracer opentracing.Tracer, spanContext opentracing.SpanContext) *s.Header {
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(spanContext, opentracing.TextMap, carrier); err != nil || len(carrier) == 0 {
+		return nil
+	}
+	header := &s.Header{Fields: make(map[string][]byte, len(carrier))}
+	for key, value := range carrier {
+		header.Fields[key] = []byte(value)
+	}
+	return header
+}
+
+// extractSpanContext returns the span context carried by the header, nil if there is none.
+func extractSpanContext(tracer opentracing.Tracer, header *s.Header) opentracing.SpanContext {
+	if header == nil || len(header.Fields) == 0 {
+		return nil
+	}
+	carrier := make(opentracing.TextMapCarrier, len(header.Fields))
+	for key, value := range header.Fields {
+		carrier[key] = string(value)
+	}
+	spanContext, err := tracer.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		return nil
+	}
+	return spanContext
+}
+
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("event", "error", "message", err.Error())
+	}
+	span.Finish()
+}
+
+func (f *tracingWorkflowInterceptorFactory) NewInterceptor(info *WorkflowInfo, next WorkflowInterceptor) WorkflowInterceptor {
+	return &tracingWorkflowInterceptor{
+		WorkflowInterceptorBase: WorkflowInterceptorBase{Next: next},
+		tracer:                  f.tracer,
+		info:                    info,
+	}
+}
+
+func (t *tracingWorkflowInterceptor) ExecuteWorkflow(ctx Context, workflowType string, input []byte) (result []byte, err error) {
+	if IsReplaying(ctx) {
+		return t.Next.ExecuteWorkflow(ctx, workflowType, input)
+	}
+
+	// The workflow span is a child of the span that started the workflow, either the span of Client.StartWorkflow or
+	// the StartChildWorkflow span of the parent workflow.
+	span := t.tracer.StartSpan("RunWorkflow:"+workflowType,
+		opentracing.ChildOf(extractSpanContext(t.tracer, getIncomingHeader(ctx))),
+		opentracing.Tag{Key: tagWorkflowType, Value: workflowType},
+		opentracing.Tag{Key: tagWorkflowID, Value: t.info.WorkflowExecution.ID},
+		opentracing.Tag{Key: tagRunID, Value: t.info.WorkflowExecution.RunID})
+	t.parent = span.Context()
+	// The span is also finished when the workflow coroutines are destroyed before the workflow completes.
+	defer func() { finishSpan(span, err) }()
+	return t.Next.ExecuteWorkflow(ctx, workflowType, input)
+}
+
+// ExecuteActivity sends the context of the StartActivity span in the header of the scheduled activity, so the span of
+// the activity execution is its child.
+func (t *tracingWorkflowInterceptor) ExecuteActivity(ctx Context, activityType string, args ...interface{}) Future {
+	span := t.recordSpan(ctx, "StartActivity:"+activityType, t.parent,
+		opentracing.Tag{Key: tagActivityType, Value: activityType})
+	return t.Next.ExecuteActivity(t.withOutgoingSpan(ctx, span), activityType, args...)
+}
+
+// ExecuteChildWorkflow sends the context of the StartChildWorkflow span in the header of the started child workflow,
+// so the span of the child workflow execution is its child.
+func (t *tracingWorkflowInterceptor) ExecuteChildWorkflow(ctx Context, childWorkflowType string, args ...interface{}) ChildWorkflowFuture {
+	span := t.recordSpan(ctx, "StartChildWorkflow:"+childWorkflowType, t.parent,
+		opentracing.Tag{Key: tagWorkflowType, Value: childWorkflowType})
+	return t.Next.ExecuteChildWorkflow(t.withOutgoingSpan(ctx, span), childWorkflowType, args...)
+}
+
+// HandleSignal records the receipt of a signal as a child of the span of Client.SignalWorkflow when the signal carries
+// it, or of the workflow execution otherwise.
+func (t *tracingWorkflowInterceptor) HandleSignal(ctx Context, signalName string, input EncodedValue) {
+	parent := extractSpanContext(t.tracer, getIncomingHeader(ctx))
+	if parent == nil {
+		parent = t.parent
+	}
+	t.recordSpan(ctx, "HandleSignal:"+signalName, parent, opentracing.Tag{Key: tagSignalName, Value: signalName})
+	t.Next.HandleSignal(ctx, signalName, input)
+}
+
+// withOutgoingSpan returns a context that sends the span context in the header of the started activity or child
+// workflow.
+func (t *tracingWorkflowInterceptor) withOutgoingSpan(ctx Context, span opentracing.SpanContext) Context {
+	if span == nil {
+		return ctx
+	}
+	return WithValue(ctx, outgoingHeaderContextKey, injectSpanContext(t.tracer, span))
+}
+
+// recordSpan creates a span for a call of the workflow when it is not replaying and returns its context, nil when
+// the workflow is replaying. The span is finished right away as the workflow doesn't block on the call.
+func (t *tracingWorkflowInterceptor) recordSpan(ctx Context, operationName string, parent opentracing.SpanContext,
+	tags ...opentracing.Tag) opentracing.SpanContext {
+	if IsReplaying(ctx) {
+		return nil
+	}
+	opts := []opentracing.StartSpanOption{
+		opentracing.ChildOf(parent),
+		opentracing.Tag{Key: tagWorkflowID, Value: t.info.WorkflowExecution.ID},
+		opentracing.Tag{Key: tagRunID, Value: t.info.WorkflowExecution.RunID},
+	}
+	for _, tag := range tags {
+		opts = append(opts, tag)
+	}
+	span := t.tracer.StartSpan(operationName, opts...)
+	span.Finish()
+	return span.Context()
+}
+
+func (f *tracingActivityInterceptorFactory) NewInterceptor(info *ActivityInfo, next ActivityInterceptor) ActivityInterceptor {
+	return &tracingActivityInterceptor{
+		ActivityInterceptorBase: ActivityInterceptorBase{Next: next},
+		tracer:                  f.tracer,
+		info:                    info,
+	}
+}
+
+// ExecuteActivity creates the span of the activity execution, tagged with the workflow execution that scheduled it.
+// The span is a child of the StartActivity span of the workflow sent in the header of the activity task. It is
+// available to the activity through opentracing.SpanFromContext.
+func (t *tracingActivityInterceptor) ExecuteActivity(ctx context.Context, activityType string, args ...interface{}) (interface{}, error) {
+	var header *s.Header
+	if env, ok := ctx.Value(activityEnvContextKey).(*activityEnvironment); ok {
+		header = env.header
+	}
+	span := t.tracer.StartSpan("RunActivity:"+activityType,
+		opentracing.ChildOf(extractSpanContext(t.tracer, header)),
+		opentracing.Tag{Key: tagActivityType, Value: activityType},
+		opentracing.Tag{Key: tagActivityID, Value: t.info.ActivityID},
+		opentracing.Tag{Key: tagWorkflowID, Value: t.info.WorkflowExecution.ID},
+		opentracing.Tag{Key: tagRunID, Value: t.info.WorkflowExecution.RunID})
+	result, err := t.Next.ExecuteActivity(opentracing.ContextWithSpan(ctx, span), activityType, args...)
+	finishSpan(span, err)
+	return result, err
+}
@@ -49,17 +49,23 @@ import (
 const (
 	// Set to 2 pollers for now, can adjust later if needed. The typical RTT (round-trip time) is below 1ms within data
 	// center. And the poll API latency is about 5ms. With 2 poller, we could achieve around 300~400 RPS.
-	defaultConcurrentPollRoutineSize = 2
+	defaultConcurrentPollRoutineSize    = 2
+	defaultMinConcurrentPollRoutineSize = 1
 
 	defaultMaxConcurrentActivityExecutionSize = 1000   // Large concurrent activity execution size (1k)
 	defaultMaxActivityExecutionRate           = 100000 // Large activity execution rate (unlimited)
 
-	defaultMaxConcurrentWorkflowExecutionSize = 50     // hardcoded max workflow execution size.
+	defaultMaxConcurrentWorkflowExecutionSize = 50     // Default max concurrent decision task execution size.
 	defaultMaxWorkflowExecutionRate           = 100000 // Large workflow execution rate (unlimited)
+
+	defaultDeadlockDetectionTimeout = time.Second // Workflow code is expected to yield well within a second.
+
+	defaultMaxConcurrentSessionExecutionSize = 1000 // Large concurrent session execution size (1k)
 )
 
 // Assert that structs do indeed implement the interfaces
 var _ Worker = (*aggregatedWorker)(nil)
+var _ WorkerRegistry = (*aggregatedWorker)(nil)
 
 type (
 
@@ -72,6 +78,7 @@ type (
 	// And worker is mapped 1:1 with task list. If the user want's to poll multiple
 	// task list names they might have to manage 'n' workers for 'n' task lists.
 	workflowWorker struct {
+		workerRegistration
 		executionParameters workerExecutionParameters
 		workflowService     workflowserviceclient.Interface
 		domain              string
@@ -83,12 +90,20 @@ type (
 	// ActivityWorker wraps the code for hosting activity types.
 	// TODO: Worker doing heartbeating automatically while activity task is running
 	activityWorker struct {
+		workerRegistration
 		executionParameters workerExecutionParameters
 		workflowService     workflowserviceclient.Interface
 		domain              string
 		poller              taskPoller
 		worker              *baseWorker
 		identity            string
+		stopC               chan struct{}
+		stopOnce            sync.Once
+	}
+
+	// workerRegistration implements WorkerRegistry on the registry of the worker.
+	workerRegistration struct {
+		hostEnv *hostEnvImpl
 	}
 
 	// Worker overrides.
@@ -105,12 +120,28 @@ type (
 		// Defines how many concurrent poll requests for the task list by this worker.
 		ConcurrentPollRoutineSize int
 
+		// Scales the number of concurrent poll requests between MinConcurrentPollRoutineSize and
+		// ConcurrentPollRoutineSize.
+		PollerAutoScaler bool
+
+		// Defines the min number of concurrent poll requests when the pollers are autoscaled.
+		MinConcurrentPollRoutineSize int
+
 		// Defines how many concurrent executions for task list by this worker.
 		ConcurrentActivityExecutionSize int
 
 		// Defines rate limiting on number of activity tasks that can be executed per second.
 		MaxActivityExecutionPerSecond float64
 
+		// Defines how many concurrent decision task executions by this worker.
+		ConcurrentDecisionTaskExecutionSize int
+
+		// Defines rate limiting on number of decision tasks that can be executed per second.
+		MaxDecisionTaskExecutionPerSecond float64
+
+		// Heartbeat the activities that have a heartbeat timeout on behalf of the user.
+		AutoHeartBeat bool
+
 		// User can provide an identity for the debuggability. If not provided the framework has
 		// a default option.
 		Identity string
@@ -129,6 +160,33 @@ type (
 		DisableStickyExecution bool
 
 		StickyScheduleToStartTimeout time.Duration
+
+		// Cache of the sticky workflow executions of the worker, nil to use the cache of the process.
+		StickyCache *stickyWorkflowCache
+
+		// The StickyCache is shared with other workers, its owner closes it rather than the worker.
+		SharedStickyCache bool
+
+		// Interceptors applied to every workflow execution.
+		WorkflowInterceptors []WorkflowInterceptorFactory
+
+		// Interceptors applied to every activity execution.
+		ActivityInterceptors []ActivityInterceptorFactory
+
+		// Time a workflow coroutine may run without yielding before the decision fails, zero disables the check.
+		DeadlockDetectionTimeout time.Duration
+
+		// What to do when the replay of a workflow doesn't match its history.
+		NonDeterministicWorkflowPolicy NonDeterministicWorkflowPolicy
+
+		// How long a stopped worker waits for its running tasks to complete.
+		WorkerStopTimeout time.Duration
+
+		// Closed when the worker is stopped, to cancel the context of the running activities.
+		WorkerStopChannel <-chan struct{}
+
+		// Injects the faults of the fault injection rules of the worker, nil when there are none.
+		FaultInjector *faultInjector
 	}
 )
 
@@ -143,18 +201,22 @@ func newWorkflowWorker(
 	return newWorkflowWorkerInternal(service, domain, params, ppMgr, nil, hostEnv)
 }
 
+func getDefaultLogger() *zap.Logger {
+	config := zap.NewProductionConfig()
+	// set default time formatter to "2006-01-02T15:04:05.000Z0700"
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	//config.Level.SetLevel(zapcore.DebugLevel)
+	logger, _ := config.Build()
+	return logger
+}
+
 func ensureRequiredParams(params *workerExecutionParameters) {
 	if params.Identity == "" {
 		params.Identity = getWorkerIdentity(params.TaskList)
 	}
 	if params.Logger == nil {
 		// create default logger if user does not supply one.
-		config := zap.NewProductionConfig()
-		// set default time formatter to "2006-01-02T15:04:05.000Z0700"
-		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-		//config.Level.SetLevel(zapcore.DebugLevel)
-		logger, _ := config.Build()
-		params.Logger = logger
+		params.Logger = getDefaultLogger()
 		params.Logger.Info("No logger configured for cadence worker. Created default one.")
 	}
 
@@ -162,6 +224,12 @@ func ensureRequiredParams(params *workerExecutionParameters) {
 		params.MetricsScope = tally.NoopScope
 		params.Logger.Info("No metrics scope configured for cadence worker. Use NoopScope as default.")
 	}
+	if params.ConcurrentDecisionTaskExecutionSize == 0 {
+		params.ConcurrentDecisionTaskExecutionSize = defaultMaxConcurrentWorkflowExecutionSize
+	}
+	if params.MaxDecisionTaskExecutionPerSecond == 0 {
+		params.MaxDecisionTaskExecutionPerSecond = defaultMaxWorkflowExecutionRate
+	}
 }
 
 // verifyDomainExist does a DescribeDomain operation on the specified domain with backoff/retry
@@ -212,7 +280,7 @@ func newWorkflowWorkerInternal(
 	} else {
 		taskHandler = newWorkflowTaskHandler(domain, params, ppMgr, hostEnv)
 	}
-	return newWorkflowTaskWorkerInternal(taskHandler, service, domain, params)
+	return newWorkflowTaskWorkerInternal(taskHandler, service, domain, params, hostEnv)
 }
 
 func newWorkflowTaskWorkerInternal(
@@ -220,6 +288,7 @@ func newWorkflowTaskWorkerInternal(
 	service workflowserviceclient.Interface,
 	domain string,
 	params workerExecutionParameters,
+	hostEnv *hostEnvImpl,
 ) Worker {
 	ensureRequiredParams(&params)
 	poller := newWorkflowTaskPoller(
@@ -230,16 +299,20 @@ func newWorkflowTaskWorkerInternal(
 	)
 	worker := newBaseWorker(baseWorkerOptions{
 		pollerCount:       params.ConcurrentPollRoutineSize,
-		maxConcurrentTask: defaultMaxConcurrentWorkflowExecutionSize,
-		maxTaskPerSecond:  defaultMaxWorkflowExecutionRate,
+		minPollerCount:    params.MinConcurrentPollRoutineSize,
+		autoScalePollers:  params.PollerAutoScaler,
+		maxConcurrentTask: params.ConcurrentDecisionTaskExecutionSize,
+		maxTaskPerSecond:  params.MaxDecisionTaskExecutionPerSecond,
 		taskWorker:        poller,
 		identity:          params.Identity,
-		workerType:        "DecisionWorker"},
+		workerType:        "DecisionWorker",
+		stopTimeout:       params.WorkerStopTimeout},
 		params.Logger,
 		params.MetricsScope,
 	)
 
 	return &workflowWorker{
+		workerRegistration:  workerRegistration{hostEnv: hostEnv},
 		executionParameters: params,
 		workflowService:     service,
 		poller:              poller,
@@ -271,6 +344,9 @@ func (ww *workflowWorker) Run() error {
 // Shutdown the worker.
 func (ww *workflowWorker) Stop() {
 	ww.worker.Stop()
+	if ww.executionParameters.StickyCache != nil && !ww.executionParameters.SharedStickyCache {
+		ww.executionParameters.StickyCache.close()
+	}
 }
 
 func newActivityWorker(
@@ -281,14 +357,16 @@ func newActivityWorker(
 	env *hostEnvImpl,
 ) Worker {
 	ensureRequiredParams(&params)
+	workerStopChannel := make(chan struct{})
+	params.WorkerStopChannel = workerStopChannel
 	// Get a activity task handler.
 	var taskHandler ActivityTaskHandler
 	if overrides != nil && overrides.activityTaskHandler != nil {
 		taskHandler = overrides.activityTaskHandler
 	} else {
-		taskHandler = newActivityTaskHandler(service, params, env)
+		taskHandler = newActivityTaskHandler(service, domain, params, env)
 	}
-	return newActivityTaskWorker(taskHandler, service, domain, params)
+	return newActivityTaskWorker(taskHandler, service, domain, params, workerStopChannel, env)
 }
 
 func newActivityTaskWorker(
@@ -296,6 +374,8 @@ func newActivityTaskWorker(
 	service workflowserviceclient.Interface,
 	domain string,
 	workerParams workerExecutionParameters,
+	workerStopChannel chan struct{},
+	hostEnv *hostEnvImpl,
 ) (worker Worker) {
 	ensureRequiredParams(&workerParams)
 
@@ -304,28 +384,34 @@ func newActivityTaskWorker(
 		service,
 		domain,
 		workerParams,
+		hostEnv,
 	)
 
 	base := newBaseWorker(
 		baseWorkerOptions{
 			pollerCount:       workerParams.ConcurrentPollRoutineSize,
+			minPollerCount:    workerParams.MinConcurrentPollRoutineSize,
+			autoScalePollers:  workerParams.PollerAutoScaler,
 			maxConcurrentTask: workerParams.ConcurrentActivityExecutionSize,
 			maxTaskPerSecond:  workerParams.MaxActivityExecutionPerSecond,
 			taskWorker:        poller,
 			identity:          workerParams.Identity,
 			workerType:        "ActivityWorker",
+			stopTimeout:       workerParams.WorkerStopTimeout,
 		},
 		workerParams.Logger,
 		workerParams.MetricsScope,
 	)
 
 	return &activityWorker{
+		workerRegistration:  workerRegistration{hostEnv: hostEnv},
 		executionParameters: workerParams,
 		workflowService:     service,
 		worker:              base,
 		poller:              poller,
 		identity:            workerParams.Identity,
 		domain:              domain,
+		stopC:               workerStopChannel,
 	}
 }
 
@@ -351,25 +437,60 @@ func (aw *activityWorker) Run() error {
 
 // Shutdown the worker.
 func (aw *activityWorker) Stop() {
+	// The running activities are cancelled before the worker waits for them, so that they can wrap up and report
+	// their results within the WorkerStopTimeout. Without a timeout they are left running, as the worker doesn't
+	// wait for them.
+	if aw.executionParameters.WorkerStopTimeout > 0 {
+		aw.stopOnce.Do(func() {
+			close(aw.stopC)
+		})
+	}
 	aw.worker.Stop()
 }
 
+// RegisterWorkflow registers the workflow function with the registry of the worker.
+func (r workerRegistration) RegisterWorkflow(workflowFunc interface{}) {
+	r.RegisterWorkflowWithOptions(workflowFunc, RegisterWorkflowOptions{})
+}
+
+// RegisterWorkflowWithOptions registers the workflow function with options with the registry of the worker.
+func (r workerRegistration) RegisterWorkflowWithOptions(workflowFunc interface{}, options RegisterWorkflowOptions) {
+	if err := r.hostEnv.RegisterWorkflowWithOptions(workflowFunc, options); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterActivity registers the activity function with the registry of the worker.
+func (r workerRegistration) RegisterActivity(activityFunc interface{}) {
+	r.RegisterActivityWithOptions(activityFunc, RegisterActivityOptions{})
+}
+
+// RegisterActivityWithOptions registers the activity function with options with the registry of the worker.
+func (r workerRegistration) RegisterActivityWithOptions(activityFunc interface{}, options RegisterActivityOptions) {
+	if err := r.hostEnv.RegisterActivityWithOptions(activityFunc, options); err != nil {
+		panic(err)
+	}
+}
+
 type workerFunc func(ctx Context, input []byte) ([]byte, error)
 type activityFunc func(ctx context.Context, input []byte) ([]byte, error)
 
 type interceptorFn func(name string, workflow interface{}) (string, interface{})
 
-// hostEnvImpl is the implementation of hostEnv
+// hostEnvImpl is the implementation of hostEnv. It is the registry of workflow and activity functions, either the
+// global one or the one of a worker, which falls back to the global one for the functions it doesn't have.
 type hostEnvImpl struct {
 	sync.Mutex
 	workflowFuncMap                  map[string]interface{}
 	workflowAliasMap                 map[string]string
 	activityFuncMap                  map[string]activity
 	activityAliasMap                 map[string]string
+	activityLimitsMap                map[string]activityLimits
 	encoding                         encoding
 	tEncoding                        encoding
 	activityRegistrationInterceptors []interceptorFn
 	workflowRegistrationInterceptors []interceptorFn
+	fallback                         *hostEnvImpl
 }
 
 func (th *hostEnvImpl) AddWorkflowRegistrationInterceptor(i interceptorFn) {
@@ -399,12 +520,15 @@ func (th *hostEnvImpl) AddActivityRegistrationInterceptor(i interceptorFn) {
 	// to already registered functions.
 	th.Lock()
 	funcMapCopy := th.activityFuncMap // used to call listener outside of the lock.
+	limitsMapCopy := th.activityLimitsMap
 	th.activityRegistrationInterceptors = append(th.activityRegistrationInterceptors, i)
 	th.activityFuncMap = make(map[string]activity) // clear map
+	th.activityLimitsMap = make(map[string]activityLimits)
 	th.Unlock()
 	for w, a := range funcMapCopy {
 		intw, intf := i(w, a.GetFunction())
 		th.addActivity(intw, &activityExecutor{intw, intf})
+		th.addActivityLimits(intw, limitsMapCopy[w])
 	}
 }
 
@@ -428,14 +552,14 @@ func (th *hostEnvImpl) RegisterWorkflowWithOptions(
 		registerName = alias
 	}
 	// Check if already registered
-	if _, ok := th.getWorkflowFn(registerName); ok {
+	if th.hasWorkflowFn(registerName) {
 		return fmt.Errorf("workflow name \"%v\" is already registered", registerName)
 	}
 	// Register args with encoding.
 	if err := th.registerEncodingTypes(fnType); err != nil {
 		return err
 	}
-	registerName, af = th.invokeInterceptors(registerName, af, th.workflowRegistrationInterceptors)
+	registerName, af = th.invokeInterceptors(registerName, af, th.root().workflowRegistrationInterceptors)
 	th.addWorkflowFn(registerName, af)
 	if len(alias) > 0 {
 		th.addWorkflowAlias(fnName, alias)
@@ -451,8 +575,17 @@ func (th *hostEnvImpl) RegisterActivityWithOptions(
 	af interface{},
 	options RegisterActivityOptions,
 ) error {
-	// Validate that it is a function
+	if options.MaxExecutionsPerSecond < 0 {
+		return errors.New("negative MaxExecutionsPerSecond")
+	}
+	if options.MaxConcurrentExecutions < 0 {
+		return errors.New("negative MaxConcurrentExecutions")
+	}
 	fnType := reflect.TypeOf(af)
+	if fnType != nil && fnType.Kind() == reflect.Ptr && fnType.Elem().Kind() == reflect.Struct {
+		return th.registerActivityStructWithOptions(af, options)
+	}
+	// Validate that it is a function
 	if err := validateFnFormat(fnType, false); err != nil {
 		return err
 	}
@@ -462,29 +595,71 @@ func (th *hostEnvImpl) RegisterActivityWithOptions(
 	if len(alias) > 0 {
 		registerName = alias
 	}
+	if err := th.registerActivityFn(af, registerName, options); err != nil {
+		return err
+	}
+	if len(alias) > 0 {
+		th.addActivityAlias(fnName, alias)
+	}
+	return nil
+}
+
+// registerActivityFn adds the validated activity function to the registry under the given name, with the limits of
+// the options.
+func (th *hostEnvImpl) registerActivityFn(af interface{}, registerName string, options RegisterActivityOptions) error {
 	// Check if already registered
-	if _, ok := th.getActivityFn(registerName); ok {
+	if th.hasActivity(registerName) {
 		return fmt.Errorf("activity type \"%v\" is already registered", registerName)
 	}
 	// Register args with encoding.
-	if err := th.registerEncodingTypes(fnType); err != nil {
+	if err := th.registerEncodingTypes(reflect.TypeOf(af)); err != nil {
 		return err
 	}
-	registerName, af = th.invokeInterceptors(registerName, af, th.activityRegistrationInterceptors)
+	registerName, af = th.invokeInterceptors(registerName, af, th.root().activityRegistrationInterceptors)
 	th.addActivityFn(registerName, af)
-	if len(alias) > 0 {
-		th.addActivityAlias(fnName, alias)
+	th.addActivityLimits(registerName, activityLimits{
+		maxExecutionsPerSecond:  options.MaxExecutionsPerSecond,
+		maxConcurrentExecutions: options.MaxConcurrentExecutions,
+	})
+	return nil
+}
+
+// registerActivityStructWithOptions registers the exported methods of the struct that have the format of an activity.
+// The activities are named after the method expressions, which both the method values and the method expressions
+// resolve to, or after the method names prefixed with options.Name when set, which are executed by name.
+func (th *hostEnvImpl) registerActivityStructWithOptions(aStruct interface{}, options RegisterActivityOptions) error {
+	structValue := reflect.ValueOf(aStruct)
+	structType := structValue.Type()
+	count := 0
+	for i := 0; i < structType.NumMethod(); i++ {
+		method := structType.Method(i)
+		methodValue := structValue.Method(i)
+		if method.PkgPath != "" || validateFnFormat(methodValue.Type(), false) != nil {
+			continue
+		}
+		registerName := getFunctionName(method.Func.Interface())
+		if len(options.Name) > 0 {
+			registerName = options.Name + method.Name
+		}
+		if err := th.registerActivityFn(methodValue.Interface(), registerName, options); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("expected %v to have at least one exported activity method", structType)
 	}
 	return nil
 }
 
 func (th *hostEnvImpl) invokeInterceptors(name string, f interface{}, interceptors []interceptorFn) (string, interface{}) {
-	th.Lock()
+	root := th.root()
+	root.Lock()
 	var copy []interceptorFn
 	for _, i := range interceptors {
 		copy = append(copy, i)
 	}
-	th.Unlock()
+	root.Unlock()
 	for _, l := range copy {
 		name, f = l(name, f)
 	}
@@ -514,8 +689,11 @@ func (th *hostEnvImpl) addWorkflowAlias(fnName string, alias string) {
 
 func (th *hostEnvImpl) getWorkflowAlias(fnName string) (string, bool) {
 	th.Lock()
-	defer th.Unlock()
 	alias, ok := th.workflowAliasMap[fnName]
+	th.Unlock()
+	if !ok && th.fallback != nil {
+		return th.fallback.getWorkflowAlias(fnName)
+	}
 	return alias, ok
 }
 
@@ -527,25 +705,41 @@ func (th *hostEnvImpl) addWorkflowFn(fnName string, wf interface{}) {
 
 func (th *hostEnvImpl) getWorkflowFn(fnName string) (interface{}, bool) {
 	th.Lock()
-	defer th.Unlock()
 	fn, ok := th.workflowFuncMap[fnName]
+	th.Unlock()
+	if !ok && th.fallback != nil {
+		return th.fallback.getWorkflowFn(fnName)
+	}
 	return fn, ok
 }
 
-func (th *hostEnvImpl) getRegisteredWorkflowTypes() []string {
+// hasWorkflowFn tells whether the workflow is registered in this registry, not counting the fallback.
+func (th *hostEnvImpl) hasWorkflowFn(fnName string) bool {
 	th.Lock()
 	defer th.Unlock()
+	_, ok := th.workflowFuncMap[fnName]
+	return ok
+}
+
+func (th *hostEnvImpl) getRegisteredWorkflowTypes() []string {
+	th.Lock()
 	var r []string
 	for t := range th.workflowFuncMap {
 		r = append(r, t)
 	}
+	th.Unlock()
+	if th.fallback != nil {
+		for _, t := range th.fallback.getRegisteredWorkflowTypes() {
+			if !th.hasWorkflowFn(t) {
+				r = append(r, t)
+			}
+		}
+	}
 	return r
 }
 
 func (th *hostEnvImpl) lenWorkflowFns() int {
-	th.Lock()
-	defer th.Unlock()
-	return len(th.workflowFuncMap)
+	return len(th.getRegisteredWorkflowTypes())
 }
 
 func (th *hostEnvImpl) addActivityAlias(fnName string, alias string) {
@@ -556,8 +750,11 @@ func (th *hostEnvImpl) addActivityAlias(fnName string, alias string) {
 
 func (th *hostEnvImpl) getActivityAlias(fnName string) (string, bool) {
 	th.Lock()
-	defer th.Unlock()
 	alias, ok := th.activityAliasMap[fnName]
+	th.Unlock()
+	if !ok && th.fallback != nil {
+		return th.fallback.getActivityAlias(fnName)
+	}
 	return alias, ok
 }
 
@@ -573,11 +770,42 @@ func (th *hostEnvImpl) addActivityFn(fnName string, af interface{}) {
 
 func (th *hostEnvImpl) getActivity(fnName string) (activity, bool) {
 	th.Lock()
-	defer th.Unlock()
 	a, ok := th.activityFuncMap[fnName]
+	th.Unlock()
+	if !ok && th.fallback != nil {
+		return th.fallback.getActivity(fnName)
+	}
 	return a, ok
 }
 
+// hasActivity tells whether the activity is registered in this registry, not counting the fallback.
+func (th *hostEnvImpl) hasActivity(fnName string) bool {
+	th.Lock()
+	defer th.Unlock()
+	_, ok := th.activityFuncMap[fnName]
+	return ok
+}
+
+func (th *hostEnvImpl) addActivityLimits(fnName string, limits activityLimits) {
+	if limits == (activityLimits{}) {
+		return
+	}
+	th.Lock()
+	defer th.Unlock()
+	th.activityLimitsMap[fnName] = limits
+}
+
+// getActivityLimits returns the limits of the activity, the zero value if it has none.
+func (th *hostEnvImpl) getActivityLimits(fnName string) activityLimits {
+	th.Lock()
+	limits, ok := th.activityLimitsMap[fnName]
+	th.Unlock()
+	if !ok && th.fallback != nil && !th.hasActivity(fnName) {
+		return th.fallback.getActivityLimits(fnName)
+	}
+	return limits
+}
+
 func (th *hostEnvImpl) getActivityFn(fnName string) (interface{}, bool) {
 	if a, ok := th.getActivity(fnName); ok {
 		return a.GetFunction(), ok
@@ -586,20 +814,29 @@ func (th *hostEnvImpl) getActivityFn(fnName string) (interface{}, bool) {
 }
 
 func (th *hostEnvImpl) getRegisteredActivities() []activity {
-	activities := make([]activity, 0, len(th.activityFuncMap))
-	for _, a := range th.activityFuncMap {
-		activities = append(activities, a)
+	var activities []activity
+	for _, t := range th.getRegisteredActivityTypes() {
+		if a, ok := th.getActivity(t); ok {
+			activities = append(activities, a)
+		}
 	}
 	return activities
 }
 
 func (th *hostEnvImpl) getRegisteredActivityTypes() []string {
 	th.Lock()
-	defer th.Unlock()
 	var r []string
 	for t := range th.activityFuncMap {
 		r = append(r, t)
 	}
+	th.Unlock()
+	if th.fallback != nil {
+		for _, t := range th.fallback.getRegisteredActivityTypes() {
+			if !th.hasActivity(t) {
+				r = append(r, t)
+			}
+		}
+	}
 	return r
 }
 
@@ -853,12 +1090,13 @@ var thImpl *hostEnvImpl
 
 func newHostEnvironment() *hostEnvImpl {
 	return &hostEnvImpl{
-		workflowFuncMap:  make(map[string]interface{}),
-		workflowAliasMap: make(map[string]string),
-		activityFuncMap:  make(map[string]activity),
-		activityAliasMap: make(map[string]string),
-		encoding:         jsonEncoding{},
-		tEncoding:        thriftEncoding{},
+		workflowFuncMap:   make(map[string]interface{}),
+		workflowAliasMap:  make(map[string]string),
+		activityFuncMap:   make(map[string]activity),
+		activityAliasMap:  make(map[string]string),
+		activityLimitsMap: make(map[string]activityLimits),
+		encoding:          jsonEncoding{},
+		tEncoding:         thriftEncoding{},
 	}
 }
 
@@ -869,6 +1107,22 @@ func getHostEnvironment() *hostEnvImpl {
 	return thImpl
 }
 
+// newRegistry returns the registry of a single worker. The functions registered with it take precedence over the ones
+// registered globally, so different workers can host different implementations under the same name.
+func newRegistry() *hostEnvImpl {
+	registry := newHostEnvironment()
+	registry.fallback = getHostEnvironment()
+	return registry
+}
+
+// root returns the global registry, which holds the registration interceptors.
+func (th *hostEnvImpl) root() *hostEnvImpl {
+	if th.fallback != nil {
+		return th.fallback
+	}
+	return th
+}
+
 // Wrapper to execute workflow functions.
 type workflowExecutor struct {
 	name string
@@ -916,15 +1170,10 @@ func (ae *activityExecutor) GetFunction() interface{} {
 
 func (ae *activityExecutor) Execute(ctx context.Context, input []byte) ([]byte, error) {
 	fnType := reflect.TypeOf(ae.fn)
-	args := []reflect.Value{}
-
-	// activities optionally might not take context.
-	if fnType.NumIn() > 0 && isActivityContext(fnType.In(0)) {
-		args = append(args, reflect.ValueOf(ctx))
-	}
+	args := []interface{}{}
 
 	if fnType.NumIn() == 1 && isTypeByteSlice(fnType.In(0)) {
-		args = append(args, reflect.ValueOf(input))
+		args = append(args, input)
 	} else {
 		decoded, err := getHostEnvironment().decodeArgs(fnType, input)
 		if err != nil {
@@ -932,21 +1181,34 @@ func (ae *activityExecutor) Execute(ctx context.Context, input []byte) ([]byte,
 				"Unable to decode the activity function input bytes with error: %v for function name: %v",
 				err, ae.name)
 		}
-		args = append(args, decoded...)
+		for _, arg := range decoded {
+			args = append(args, arg.Interface())
+		}
 	}
 
-	// Invoke the activity with arguments.
-	fnValue := reflect.ValueOf(ae.fn)
-	retValues := fnValue.Call(args)
-	return validateFunctionAndGetResults(ae.fn, retValues)
+	result, err := newActivityInterceptorChain(ctx, ae.fn).ExecuteActivity(ctx, ae.name, args...)
+	if result == nil {
+		return nil, err
+	}
+	data, encodeErr := getHostEnvironment().encodeArg(result)
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
+	return data, err
 }
 
 // aggregatedWorker combines management of both workflowWorker and activityWorker worker lifecycle.
 type aggregatedWorker struct {
+	workerRegistration
 	workflowWorker Worker
 	activityWorker Worker
+	sessionWorker  *sessionWorker
+	shadowWorker   *shadowWorker
+	faults         *faultInjector
 	logger         *zap.Logger
-	hostEnv        *hostEnvImpl
+	domain         string
+	taskList       string
+	identity       string
 }
 
 func (aw *aggregatedWorker) Start() error {
@@ -972,6 +1234,21 @@ func (aw *aggregatedWorker) Start() error {
 			return err
 		}
 	}
+	if aw.sessionWorker != nil {
+		if err := aw.sessionWorker.Start(); err != nil {
+			// stop workflow and activity workers.
+			if !isInterfaceNil(aw.workflowWorker) {
+				aw.workflowWorker.Stop()
+			}
+			aw.activityWorker.Stop()
+			return err
+		}
+	}
+	if aw.shadowWorker != nil {
+		if err := aw.shadowWorker.Start(); err != nil {
+			return err
+		}
+	}
 	aw.logger.Info("Started Worker")
 	return nil
 }
@@ -987,17 +1264,37 @@ func (aw *aggregatedWorker) Run() error {
 }
 
 func (aw *aggregatedWorker) Stop() {
-	if !isInterfaceNil(aw.workflowWorker) {
-		aw.workflowWorker.Stop()
-	}
-	if !isInterfaceNil(aw.activityWorker) {
-		aw.activityWorker.Stop()
-	}
+	// The injected latencies don't hold back the stop.
+	aw.faults.stop()
+	// Both workers are stopped at once, so that they wait for their running tasks together.
+	var wg sync.WaitGroup
+	workers := []Worker{aw.workflowWorker, aw.activityWorker}
+	if aw.sessionWorker != nil {
+		workers = append(workers, aw.sessionWorker.creationWorker, aw.sessionWorker.resourceWorker)
+	}
+	for _, w := range workers {
+		if isInterfaceNil(w) {
+			continue
+		}
+		wg.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+			w.Stop()
+		}(w)
+	}
+	if aw.shadowWorker != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			aw.shadowWorker.Stop()
+		}()
+	}
+	wg.Wait()
 	aw.logger.Info("Stopped Worker")
 }
 
 // aggregatedWorker returns an instance to manage the workers. Use defaultConcurrentPollRoutineSize (which is 2) as
-// poller size. The typical RTT (round-trip time) is below 1ms within data center. And the poll API latency is about 5ms.
+// default poller size. The typical RTT (round-trip time) is below 1ms within data center. And the poll API latency is about 5ms.
 // With 2 poller, we could achieve around 300~400 RPS.
 func newAggregatedWorker(
 	service workflowserviceclient.Interface,
@@ -1005,19 +1302,42 @@ func newAggregatedWorker(
 	taskList string,
 	options WorkerOptions,
 ) (worker Worker) {
+	return newAggregatedWorkerWithStickyCache(service, domain, taskList, options, nil)
+}
+
+// newAggregatedWorkerWithStickyCache returns the workers sharing the sticky cache, which the caller closes. The worker
+// uses its own cache instead when its options have one, and the cache of the process when the shared cache is nil.
+func newAggregatedWorkerWithStickyCache(
+	service workflowserviceclient.Interface,
+	domain string,
+	taskList string,
+	options WorkerOptions,
+	sharedStickyCache *stickyWorkflowCache,
+) *aggregatedWorker {
+	if err := validateWorkerOptions(options); err != nil {
+		panic(err)
+	}
 	wOptions := fillWorkerOptionsDefaults(options)
 	workerParams := workerExecutionParameters{
-		TaskList:                        taskList,
-		ConcurrentPollRoutineSize:       defaultConcurrentPollRoutineSize,
-		ConcurrentActivityExecutionSize: wOptions.MaxConcurrentActivityExecutionSize,
-		MaxActivityExecutionPerSecond:   wOptions.MaxActivityExecutionPerSecond,
-		Identity:                        wOptions.Identity,
-		MetricsScope:                    wOptions.MetricsScope,
-		Logger:                          wOptions.Logger,
-		EnableLoggingInReplay:           wOptions.EnableLoggingInReplay,
-		UserContext:                     wOptions.BackgroundActivityContext,
-		DisableStickyExecution:          wOptions.DisableStickyExecution,
-		StickyScheduleToStartTimeout:    wOptions.StickyScheduleToStartTimeout,
+		TaskList:                            taskList,
+		ConcurrentActivityExecutionSize:     wOptions.MaxConcurrentActivityExecutionSize,
+		MaxActivityExecutionPerSecond:       wOptions.MaxActivityExecutionPerSecond,
+		ConcurrentDecisionTaskExecutionSize: wOptions.MaxConcurrentDecisionTaskExecutionSize,
+		MaxDecisionTaskExecutionPerSecond:   wOptions.MaxDecisionTaskExecutionPerSecond,
+		AutoHeartBeat:                       wOptions.AutoHeartBeat,
+		PollerAutoScaler:                    wOptions.EnablePollerAutoScaler,
+		Identity:                            wOptions.Identity,
+		MetricsScope:                        wOptions.MetricsScope,
+		Logger:                              wOptions.Logger,
+		EnableLoggingInReplay:               wOptions.EnableLoggingInReplay,
+		UserContext:                         wOptions.BackgroundActivityContext,
+		DisableStickyExecution:              wOptions.DisableStickyExecution,
+		StickyScheduleToStartTimeout:        wOptions.StickyScheduleToStartTimeout,
+		WorkflowInterceptors:                getWorkflowInterceptorFactories(wOptions),
+		ActivityInterceptors:                getActivityInterceptorFactories(wOptions),
+		DeadlockDetectionTimeout:            getDeadlockDetectionTimeout(wOptions),
+		NonDeterministicWorkflowPolicy:      wOptions.NonDeterministicWorkflowPolicy,
+		WorkerStopTimeout:                   wOptions.WorkerStopTimeout,
 	}
 
 	ensureRequiredParams(&workerParams)
@@ -1030,17 +1350,60 @@ func newAggregatedWorker(
 	logger := workerParams.Logger
 
 	processTestTags(&wOptions, &workerParams)
+	workerParams.FaultInjector = newFaultInjector(wOptions.FaultInjectionRules, logger, workerParams.MetricsScope)
+
+	// The workflow and activity workers poll with their own number of pollers.
+	workflowParams := workerParams
+	workflowParams.ConcurrentPollRoutineSize = wOptions.MaxConcurrentDecisionTaskPollers
+	workflowParams.MinConcurrentPollRoutineSize = wOptions.MinConcurrentDecisionTaskPollers
+	activityParams := workerParams
+	activityParams.ConcurrentPollRoutineSize = wOptions.MaxConcurrentActivityTaskPollers
+	activityParams.MinConcurrentPollRoutineSize = wOptions.MinConcurrentActivityTaskPollers
+
+	hostEnv := newRegistry()
+	// In shadow mode, the worker only replays the histories of the domain and doesn't poll any task.
+	if wOptions.EnableShadowWorker {
+		client := NewClient(service, domain, &ClientOptions{
+			MetricsScope: workerParams.MetricsScope,
+			Identity:     workerParams.Identity,
+		})
+		return &aggregatedWorker{
+			workerRegistration: workerRegistration{hostEnv: hostEnv},
+			shadowWorker: newShadowWorker(
+				client,
+				domain,
+				wOptions.ShadowOptions,
+				// The workflow code is replayed like the worker would run it, so a blocked workflow can't hang the scan.
+				replayOptions{
+					deadlockDetectionTimeout: workerParams.DeadlockDetectionTimeout,
+					workflowInterceptors:     workerParams.WorkflowInterceptors,
+				},
+				hostEnv,
+				logger,
+				workerParams.MetricsScope,
+			),
+			logger:   logger,
+			domain:   domain,
+			taskList: taskList,
+			identity: workerParams.Identity,
+		}
+	}
 
-	hostEnv := getHostEnvironment()
 	// workflow factory.
 	var workflowWorker Worker
 	if !wOptions.DisableWorkflowWorker {
+		if wOptions.StickyCache != (StickyCacheOptions{}) {
+			workflowParams.StickyCache = newStickyWorkflowCache(wOptions.StickyCache)
+		} else if sharedStickyCache != nil {
+			workflowParams.StickyCache = sharedStickyCache
+			workflowParams.SharedStickyCache = true
+		}
 		testTags := getTestTags(wOptions.BackgroundActivityContext)
 		if testTags != nil && len(testTags) > 0 {
 			workflowWorker = newWorkflowWorkerWithPressurePoints(
 				service,
 				domain,
-				workerParams,
+				workflowParams,
 				testTags,
 				hostEnv,
 			)
@@ -1048,7 +1411,7 @@ func newAggregatedWorker(
 			workflowWorker = newWorkflowWorker(
 				service,
 				domain,
-				workerParams,
+				workflowParams,
 				nil,
 				hostEnv,
 			)
@@ -1058,20 +1421,37 @@ func newAggregatedWorker(
 	// activity types.
 	var activityWorker Worker
 
+	// session workers.
+	var sessionWorker *sessionWorker
+
 	if !wOptions.DisableActivityWorker {
 		activityWorker = newActivityWorker(
 			service,
 			domain,
-			workerParams,
+			activityParams,
 			nil,
 			hostEnv,
 		)
+		if wOptions.EnableSessionWorker {
+			sessionWorker = newSessionWorker(
+				service,
+				domain,
+				activityParams,
+				hostEnv,
+				wOptions.MaxConcurrentSessionExecutionSize,
+			)
+		}
 	}
 	return &aggregatedWorker{
-		workflowWorker: workflowWorker,
-		activityWorker: activityWorker,
-		logger:         logger,
-		hostEnv:        hostEnv,
+		workerRegistration: workerRegistration{hostEnv: hostEnv},
+		workflowWorker:     workflowWorker,
+		activityWorker:     activityWorker,
+		sessionWorker:      sessionWorker,
+		faults:             workerParams.FaultInjector,
+		logger:             logger,
+		domain:             domain,
+		taskList:           taskList,
+		identity:           workerParams.Identity,
 	}
 }
 
@@ -1092,6 +1472,8 @@ func processTestTags(wOptions *WorkerOptions, ep *workerExecutionParameters) {
 				case workerOptionsConfigConcurrentPollRoutineSize:
 					if size, err := strconv.Atoi(val); err == nil {
 						ep.ConcurrentPollRoutineSize = size
+						wOptions.MaxConcurrentActivityTaskPollers = size
+						wOptions.MaxConcurrentDecisionTaskPollers = size
 					}
 				}
 			}
@@ -1270,14 +1652,97 @@ func fillWorkerOptionsDefaults(options WorkerOptions) WorkerOptions {
 	if options.StickyScheduleToStartTimeout.Seconds() == 0 {
 		options.StickyScheduleToStartTimeout = stickyDecisionScheduleToStartTimeoutSeconds * time.Second
 	}
+	if options.DeadlockDetectionTimeout == 0 {
+		options.DeadlockDetectionTimeout = defaultDeadlockDetectionTimeout
+	}
+	if options.MaxConcurrentSessionExecutionSize == 0 {
+		options.MaxConcurrentSessionExecutionSize = defaultMaxConcurrentSessionExecutionSize
+	}
+	if options.MaxConcurrentActivityTaskPollers == 0 {
+		options.MaxConcurrentActivityTaskPollers = defaultConcurrentPollRoutineSize
+	}
+	if options.MaxConcurrentDecisionTaskExecutionSize == 0 {
+		options.MaxConcurrentDecisionTaskExecutionSize = defaultMaxConcurrentWorkflowExecutionSize
+	}
+	if options.MaxDecisionTaskExecutionPerSecond == 0 {
+		options.MaxDecisionTaskExecutionPerSecond = defaultMaxWorkflowExecutionRate
+	}
+	if options.MaxConcurrentDecisionTaskPollers == 0 {
+		options.MaxConcurrentDecisionTaskPollers = defaultConcurrentPollRoutineSize
+	}
+	if options.MinConcurrentActivityTaskPollers == 0 {
+		options.MinConcurrentActivityTaskPollers = defaultMinConcurrentPollRoutineSize
+	}
+	if options.MinConcurrentDecisionTaskPollers == 0 {
+		options.MinConcurrentDecisionTaskPollers = defaultMinConcurrentPollRoutineSize
+	}
 	return options
 }
 
+// validateWorkerOptions returns an error if the options of a worker are invalid, the zero values are valid as they
+// are replaced by the default values.
+func validateWorkerOptions(options WorkerOptions) error {
+	if options.MaxConcurrentActivityExecutionSize < 0 {
+		return errors.New("negative MaxConcurrentActivityExecutionSize")
+	}
+	if options.MaxActivityExecutionPerSecond < 0 {
+		return errors.New("negative MaxActivityExecutionPerSecond")
+	}
+	if options.MaxConcurrentActivityTaskPollers < 0 {
+		return errors.New("negative MaxConcurrentActivityTaskPollers")
+	}
+	if options.MaxConcurrentDecisionTaskExecutionSize < 0 {
+		return errors.New("negative MaxConcurrentDecisionTaskExecutionSize")
+	}
+	if options.MaxDecisionTaskExecutionPerSecond < 0 {
+		return errors.New("negative MaxDecisionTaskExecutionPerSecond")
+	}
+	if options.MaxConcurrentDecisionTaskPollers < 0 {
+		return errors.New("negative MaxConcurrentDecisionTaskPollers")
+	}
+	if options.MaxConcurrentSessionExecutionSize < 0 {
+		return errors.New("negative MaxConcurrentSessionExecutionSize")
+	}
+	if options.MinConcurrentActivityTaskPollers < 0 {
+		return errors.New("negative MinConcurrentActivityTaskPollers")
+	}
+	if options.MinConcurrentDecisionTaskPollers < 0 {
+		return errors.New("negative MinConcurrentDecisionTaskPollers")
+	}
+	if err := validateStickyCacheOptions(options.StickyCache); err != nil {
+		return err
+	}
+	if err := validateFaultInjectionRules(options.FaultInjectionRules); err != nil {
+		return err
+	}
+	if err := validateShadowOptions(options.ShadowOptions); err != nil {
+		return err
+	}
+	if options.EnablePollerAutoScaler {
+		filled := fillWorkerOptionsDefaults(options)
+		if filled.MinConcurrentActivityTaskPollers > filled.MaxConcurrentActivityTaskPollers {
+			return errors.New("MinConcurrentActivityTaskPollers is greater than MaxConcurrentActivityTaskPollers")
+		}
+		if filled.MinConcurrentDecisionTaskPollers > filled.MaxConcurrentDecisionTaskPollers {
+			return errors.New("MinConcurrentDecisionTaskPollers is greater than MaxConcurrentDecisionTaskPollers")
+		}
+	}
+	return nil
+}
+
+// getDeadlockDetectionTimeout returns the deadlock detection timeout of the worker, zero if the detection is disabled.
+func getDeadlockDetectionTimeout(options WorkerOptions) time.Duration {
+	if options.DisableDeadlockDetection {
+		return 0
+	}
+	return options.DeadlockDetectionTimeout
+}
+
 type contextKey string
 
 const testTagsContextKey = contextKey("testTags")
 
-// getTestTags returns the test tags in the context.
+// getTestTags returns the test tags in the context. The test tags are deprecated, see internal_pressure_points.go.
 func getTestTags(ctx context.Context) map[string]map[string]string {
 	if ctx != nil {
 		env := ctx.Value(testTagsContextKey)
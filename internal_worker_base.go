@@ -25,9 +25,11 @@ package cadence
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/uber-go/tally"
+	s "go.uber.org/cadence/.gen/go/shared"
 	"go.uber.org/cadence/common/backoff"
 	"go.uber.org/cadence/common/metrics"
 	"go.uber.org/zap"
@@ -62,13 +64,17 @@ type (
 		ExecuteChildWorkflow(options workflowOptions, callback resultHandler, startedHandler func(r WorkflowExecution, e error)) error
 		GetLogger() *zap.Logger
 		GetMetricsScope() tally.Scope
-		RegisterSignalHandler(handler func(name string, input []byte))
+		RegisterSignalHandler(handler func(name string, input []byte, header *s.Header))
 		RegisterQueryHandler(handler func(queryType string, queryArgs []byte) ([]byte, error))
+		IsReplaying() bool
+		GetWorkflowInterceptors() []WorkflowInterceptorFactory
+		GetDeadlockDetectionTimeout() time.Duration
+		GetRegistry() *hostEnvImpl
 	}
 
 	// WorkflowDefinition wraps the code that can execute a workflow.
 	workflowDefinition interface {
-		Execute(env workflowEnvironment, input []byte)
+		Execute(env workflowEnvironment, header *s.Header, input []byte)
 		// Called for each non timed out startDecision event.
 		// Executed after all history events since the previous decision are applied to workflowDefinition
 		OnDecisionTaskStarted()
@@ -82,30 +88,41 @@ type (
 
 	// baseWorkerOptions options to configure base worker.
 	baseWorkerOptions struct {
-		pollerCount       int
+		pollerCount       int // number of pollers, the max number of pollers when autoscaling
+		minPollerCount    int // min number of pollers when autoscaling
+		autoScalePollers  bool
 		maxConcurrentTask int
 		maxTaskPerSecond  float64
 		taskWorker        taskPoller
 		identity          string
 		workerType        string
+		stopTimeout       time.Duration
 	}
 
 	// baseWorker that wraps worker activities.
 	baseWorker struct {
 		options              baseWorkerOptions
-		isWorkerStarted      bool
+		started              int32          // accessed atomically, as the stats of the worker are read concurrently
 		shutdownCh           chan struct{}  // Channel used to shut down the go routines.
 		shutdownWG           sync.WaitGroup // The WaitGroup for shutting down existing routines.
+		taskWG               sync.WaitGroup // The WaitGroup for the tasks being processed.
 		pollLimiter          *rate.Limiter
 		taskLimiter          *rate.Limiter
 		limiterContext       context.Context
 		limiterContextCancel func()
 		retrier              *backoff.ConcurrentRetrier // Service errors back off retrier
+		autoScaler           *pollerAutoScaler          // nil unless the pollers are autoscaled
 		logger               *zap.Logger
 		metricsScope         tally.Scope
 
 		pollerRequestCh chan struct{}
 		taskQueueCh     chan interface{}
+		throttledTaskCh chan struct{} // a token per task waiting for the limits of the task worker without a slot
+
+		statsLock         sync.Mutex
+		runningTasks      map[interface{}]time.Time // The tasks being processed and their start time.
+		lastPollError     error
+		lastPollErrorTime time.Time
 	}
 )
 
@@ -123,7 +140,7 @@ func createPollRetryPolicy() backoff.RetryPolicy {
 
 func newBaseWorker(options baseWorkerOptions, logger *zap.Logger, metricsScope tally.Scope) *baseWorker {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &baseWorker{
+	bw := &baseWorker{
 		options:         options,
 		shutdownCh:      make(chan struct{}),
 		pollLimiter:     rate.NewLimiter(rate.Limit(1000), 1),
@@ -133,28 +150,38 @@ func newBaseWorker(options baseWorkerOptions, logger *zap.Logger, metricsScope t
 		metricsScope:    tagScope(metricsScope, tagWorkerType, options.workerType),
 		pollerRequestCh: make(chan struct{}, options.maxConcurrentTask),
 		taskQueueCh:     make(chan interface{}), // no buffer, so poller only able to poll new task after previous is dispatched.
+		throttledTaskCh: make(chan struct{}, options.maxConcurrentTask),
+		runningTasks:    make(map[interface{}]time.Time),
 
 		limiterContext:       ctx,
 		limiterContextCancel: cancel,
 	}
+	if options.autoScalePollers {
+		bw.autoScaler = newPollerAutoScaler(options.minPollerCount, options.pollerCount, bw.metricsScope)
+	}
+	return bw
 }
 
 // Start starts a fixed set of routines to do the work.
 func (bw *baseWorker) Start() {
-	if bw.isWorkerStarted {
+	if bw.isStarted() {
 		return
 	}
 
 	bw.metricsScope.Counter(metrics.WorkerStartCounter).Inc(1)
 
-	for i := 0; i < bw.options.pollerCount; i++ {
+	pollerCount := bw.options.pollerCount
+	if bw.autoScaler != nil {
+		pollerCount = bw.autoScaler.getPollerCount()
+	}
+	for i := 0; i < pollerCount; i++ {
 		bw.shutdownWG.Add(1)
 		go bw.runPoller()
 	}
 	bw.shutdownWG.Add(1)
 	go bw.runTaskDispatcher()
 
-	bw.isWorkerStarted = true
+	atomic.StoreInt32(&bw.started, 1)
 	traceLog(func() {
 		bw.logger.Info("Started Worker",
 			zap.Int("PollerCount", bw.options.pollerCount),
@@ -164,6 +191,10 @@ func (bw *baseWorker) Start() {
 	})
 }
 
+func (bw *baseWorker) isStarted() bool {
+	return atomic.LoadInt32(&bw.started) == 1
+}
+
 func (bw *baseWorker) isShutdown() bool {
 	select {
 	case <-bw.shutdownCh:
@@ -182,17 +213,24 @@ func (bw *baseWorker) runPoller() {
 		case <-bw.shutdownCh:
 			return
 		case <-bw.pollerRequestCh:
-			ch := make(chan struct{})
-			go func(ch chan struct{}) {
-				bw.pollTask()
-				close(ch)
+			ch := make(chan pollerScaling, 1)
+			go func(ch chan pollerScaling) {
+				ch <- bw.pollTask()
 			}(ch)
 
 			// block until previous poll completed or return immediately when shutdown
 			select {
 			case <-bw.shutdownCh:
 				return
-			case <-ch:
+			case scaling := <-ch:
+				switch scaling {
+				case pollerScalingUp:
+					// The poller is running, so the WaitGroup can't be waited on with a zero count.
+					bw.shutdownWG.Add(1)
+					go bw.runPoller()
+				case pollerScalingDown:
+					return
+				}
 			}
 		}
 	}
@@ -217,36 +255,75 @@ func (bw *baseWorker) runTaskDispatcher() {
 					return
 				}
 			}
+			bw.taskWG.Add(1)
 			go bw.processTask(task)
 		}
 	}
 }
 
-func (bw *baseWorker) pollTask() {
+// pollTask polls a task and dispatches it, and returns how the number of pollers changes when they are autoscaled.
+func (bw *baseWorker) pollTask() pollerScaling {
 	var err error
 	var task interface{}
+	scaling := pollerScalingNone
 	bw.retrier.Throttle()
 	if bw.pollLimiter.Wait(bw.limiterContext) == nil {
 		task, err = bw.options.taskWorker.PollTask()
 		if err != nil && enableVerboseLogging {
 			bw.logger.Debug("Failed to poll for task.", zap.Error(err))
 		}
+		if err != nil {
+			bw.statsLock.Lock()
+			bw.lastPollError = err
+			bw.lastPollErrorTime = time.Now()
+			bw.statsLock.Unlock()
+		}
 		if err != nil && isServiceTransientError(err) {
 			bw.retrier.Failed()
 		} else {
 			bw.retrier.Succeeded()
 		}
+		if bw.autoScaler != nil {
+			// The tokens left in pollerRequestCh are the task slots that no poller is using.
+			scaling = bw.autoScaler.recordPoll(task, err, len(bw.pollerRequestCh) > 0)
+		}
 	}
 
 	if task != nil {
-		bw.taskQueueCh <- task
+		select {
+		case bw.taskQueueCh <- task:
+		case <-bw.shutdownCh:
+			// the task is dropped and times out on the server, as the worker no longer dispatches tasks
+		}
 	} else {
 		bw.pollerRequestCh <- struct{}{} // poll failed, trigger a new pool
 	}
+	return scaling
 }
 
 func (bw *baseWorker) processTask(task interface{}) {
-	err := bw.options.taskWorker.ProcessTask(task)
+	defer bw.taskWG.Done()
+	release, holdsSlot, err := bw.throttleTask(task)
+	if holdsSlot {
+		defer func() { bw.pollerRequestCh <- struct{}{} }()
+	}
+	if err != nil {
+		// the task is dropped and times out on the server, like the tasks polled while the worker stops
+		bw.logger.Info("Task dropped while waiting for the task limits", zap.Error(err))
+		return
+	}
+	defer release()
+
+	bw.statsLock.Lock()
+	bw.runningTasks[task] = time.Now()
+	bw.statsLock.Unlock()
+	defer func() {
+		bw.statsLock.Lock()
+		delete(bw.runningTasks, task)
+		bw.statsLock.Unlock()
+	}()
+
+	err = bw.options.taskWorker.ProcessTask(task)
 	if err != nil {
 		if isClientSideError(err) {
 			bw.logger.Info("Task processing failed with client side error", zap.Error(err))
@@ -254,8 +331,38 @@ func (bw *baseWorker) processTask(task interface{}) {
 			bw.logger.Info("Task processing failed with error", zap.Error(err))
 		}
 	}
+}
+
+// throttleTask waits until the task is within the limits of the task worker, when it implements taskThrottler. The
+// task gives its slot back while it waits, so that it doesn't hold back the other tasks of the worker, unless as many
+// tasks as the worker has slots are already waiting. It returns whether the task holds a slot once it is done waiting.
+func (bw *baseWorker) throttleTask(task interface{}) (release func(), holdsSlot bool, err error) {
+	throttler, ok := bw.options.taskWorker.(taskThrottler)
+	if !ok {
+		return func() {}, true, nil
+	}
+	if release, ok := throttler.tryThrottleTask(task); ok {
+		return release, true, nil
+	}
 
+	select {
+	case bw.throttledTaskCh <- struct{}{}:
+		defer func() { <-bw.throttledTaskCh }()
+	default:
+		release, err = throttler.throttleTask(bw.limiterContext, task)
+		return release, true, err
+	}
 	bw.pollerRequestCh <- struct{}{}
+	if release, err = throttler.throttleTask(bw.limiterContext, task); err != nil {
+		return nil, false, err
+	}
+	select {
+	case <-bw.pollerRequestCh:
+		return release, true, nil
+	case <-bw.shutdownCh:
+		release()
+		return nil, false, context.Canceled
+	}
 }
 
 func (bw *baseWorker) Run() {
@@ -269,7 +376,7 @@ func (bw *baseWorker) Run() {
 
 // Shutdown is a blocking call and cleans up all the resources assosciated with worker.
 func (bw *baseWorker) Stop() {
-	if !bw.isWorkerStarted {
+	if !bw.isStarted() {
 		return
 	}
 	close(bw.shutdownCh)
@@ -283,4 +390,12 @@ func (bw *baseWorker) Stop() {
 			bw.logger.Info("Worker timed out on waiting for shutdown.")
 		})
 	}
+
+	// The tasks already being processed keep running, give them a chance to report their results.
+	if bw.options.stopTimeout > 0 {
+		if success := awaitWaitGroup(&bw.taskWG, bw.options.stopTimeout); !success {
+			bw.logger.Warn("Worker stopped before its running tasks completed.",
+				zap.Duration("WorkerStopTimeout", bw.options.stopTimeout))
+		}
+	}
 }
@@ -0,0 +1,248 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
+	"go.uber.org/zap"
+)
+
+const defaultHealthPollErrorWindow = time.Minute
+
+type (
+	// workerHost implements WorkerHost with an aggregatedWorker per entry.
+	workerHost struct {
+		entries               []WorkerHostEntry
+		workers               []*aggregatedWorker
+		stickyCache           *stickyWorkflowCache // nil when the workers use the cache of the process
+		logger                *zap.Logger
+		healthPollErrorWindow time.Duration
+
+		lock     sync.Mutex
+		started  bool
+		stopOnce sync.Once
+	}
+
+	workerHostKey struct {
+		domain   string
+		taskList string
+	}
+)
+
+func newWorkerHost(
+	service workflowserviceclient.Interface,
+	entries []WorkerHostEntry,
+	options WorkerHostOptions,
+) (*workerHost, error) {
+	if err := validateWorkerHostEntries(entries); err != nil {
+		return nil, err
+	}
+	if err := validateWorkerHostOptions(options); err != nil {
+		return nil, err
+	}
+	options = fillWorkerHostOptionsDefaults(options)
+
+	host := &workerHost{
+		entries:               entries,
+		logger:                options.Logger,
+		healthPollErrorWindow: options.HealthPollErrorWindow,
+	}
+	if options.StickyCache != (StickyCacheOptions{}) {
+		host.stickyCache = newStickyWorkflowCache(options.StickyCache)
+	}
+	for _, entry := range entries {
+		workerOptions := entry.Options
+		if workerOptions.Logger == nil {
+			workerOptions.Logger = options.Logger
+		}
+		if workerOptions.MetricsScope == nil && options.MetricsScope != nil {
+			// The worker tags its scope with the domain.
+			workerOptions.MetricsScope = tagScope(options.MetricsScope, tagTaskList, entry.TaskList)
+		}
+		worker := newAggregatedWorkerWithStickyCache(service, entry.Domain, entry.TaskList, workerOptions, host.stickyCache)
+		if entry.Register != nil {
+			entry.Register(worker)
+		}
+		host.workers = append(host.workers, worker)
+	}
+	return host, nil
+}
+
+func validateWorkerHostEntries(entries []WorkerHostEntry) error {
+	if len(entries) == 0 {
+		return errors.New("no worker host entry")
+	}
+	keys := make(map[workerHostKey]bool, len(entries))
+	for _, entry := range entries {
+		if entry.Domain == "" {
+			return errors.New("worker host entry without Domain")
+		}
+		if entry.TaskList == "" {
+			return fmt.Errorf("worker host entry of domain %s without TaskList", entry.Domain)
+		}
+		key := workerHostKey{domain: entry.Domain, taskList: entry.TaskList}
+		if keys[key] {
+			return fmt.Errorf("duplicate worker host entry of domain %s and task list %s", entry.Domain, entry.TaskList)
+		}
+		keys[key] = true
+		if err := validateWorkerOptions(entry.Options); err != nil {
+			return fmt.Errorf("invalid options of the worker of domain %s and task list %s: %v",
+				entry.Domain, entry.TaskList, err)
+		}
+	}
+	return nil
+}
+
+func validateWorkerHostOptions(options WorkerHostOptions) error {
+	if options.HealthPollErrorWindow < 0 {
+		return errors.New("negative HealthPollErrorWindow")
+	}
+	return validateStickyCacheOptions(options.StickyCache)
+}
+
+func fillWorkerHostOptionsDefaults(options WorkerHostOptions) WorkerHostOptions {
+	if options.Logger == nil {
+		options.Logger = getDefaultLogger()
+	}
+	if options.HealthPollErrorWindow == 0 {
+		options.HealthPollErrorWindow = defaultHealthPollErrorWindow
+	}
+	return options
+}
+
+// Start starts the workers one after the other, and stops the started workers when one fails to start.
+func (h *workerHost) Start() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.started {
+		return nil
+	}
+	for i, worker := range h.workers {
+		if err := worker.Start(); err != nil {
+			stopAggregatedWorkers(h.workers[:i])
+			return fmt.Errorf("unable to start the worker of domain %s and task list %s: %v",
+				h.entries[i].Domain, h.entries[i].TaskList, err)
+		}
+	}
+	h.started = true
+	h.logger.Info("Started worker host", zap.Int("WorkerCount", len(h.workers)))
+	return nil
+}
+
+func (h *workerHost) Run() error {
+	if err := h.Start(); err != nil {
+		return err
+	}
+	d := <-getKillSignal()
+	h.logger.Info("Worker host has been killed", zap.String("Signal", d.String()))
+	h.Stop()
+	return nil
+}
+
+// Stop stops the started workers at once, so that they wait for their running tasks together, then purges the sticky
+// cache of the host.
+func (h *workerHost) Stop() {
+	h.stopOnce.Do(func() {
+		h.lock.Lock()
+		started := h.started
+		h.started = false
+		h.lock.Unlock()
+
+		if started {
+			stopAggregatedWorkers(h.workers)
+		}
+		if h.stickyCache != nil {
+			h.stickyCache.close()
+		}
+		h.logger.Info("Stopped worker host")
+	})
+}
+
+func stopAggregatedWorkers(workers []*aggregatedWorker) {
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker *aggregatedWorker) {
+			defer wg.Done()
+			worker.Stop()
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func (h *workerHost) Workers() []Worker {
+	workers := make([]Worker, len(h.workers))
+	for i, worker := range h.workers {
+		workers[i] = worker
+	}
+	return workers
+}
+
+func (h *workerHost) Health() WorkerHostHealth {
+	h.lock.Lock()
+	health := WorkerHostHealth{Healthy: h.started}
+	h.lock.Unlock()
+
+	now := time.Now()
+	for _, worker := range h.workers {
+		workerHealth := getWorkerHealth(worker.Stats(), now, h.healthPollErrorWindow)
+		health.Healthy = health.Healthy && workerHealth.Healthy
+		health.Workers = append(health.Workers, workerHealth)
+	}
+	return health
+}
+
+// getWorkerHealth returns the health of a worker from its stats. The decision and activity workers that are disabled
+// don't have stats and don't affect the health.
+func getWorkerHealth(stats WorkerStats, now time.Time, pollErrorWindow time.Duration) WorkerHealth {
+	health := WorkerHealth{Domain: stats.Domain, TaskList: stats.TaskList, Healthy: true}
+	taskWorkers := []struct {
+		name  string
+		stats *TaskWorkerStats
+	}{
+		{name: "decision", stats: stats.DecisionWorker},
+		{name: "activity", stats: stats.ActivityWorker},
+	}
+	for _, taskWorker := range taskWorkers {
+		if taskWorker.stats == nil {
+			continue
+		}
+		if !taskWorker.stats.Started {
+			health.Healthy = false
+			health.Reason = fmt.Sprintf("%s worker is not started", taskWorker.name)
+			return health
+		}
+		if taskWorker.stats.LastPollError != "" && now.Sub(taskWorker.stats.LastPollErrorTime) < pollErrorWindow {
+			health.Healthy = false
+			health.Reason = fmt.Sprintf("%s worker failed to poll at %v: %v",
+				taskWorker.name, taskWorker.stats.LastPollErrorTime, taskWorker.stats.LastPollError)
+			return health
+		}
+	}
+	return health
+}
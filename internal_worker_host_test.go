@@ -0,0 +1,193 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/cadence/common/metrics"
+	"go.uber.org/zap"
+)
+
+func TestWorkerHostValidation(t *testing.T) {
+	service := workflowservicetest.NewMockClient(gomock.NewController(t))
+	testCases := []struct {
+		name    string
+		entries []WorkerHostEntry
+		options WorkerHostOptions
+	}{
+		{name: "no entry"},
+		{name: "no domain", entries: []WorkerHostEntry{{TaskList: "tl"}}},
+		{name: "no task list", entries: []WorkerHostEntry{{Domain: "domain"}}},
+		{
+			name:    "duplicate entry",
+			entries: []WorkerHostEntry{{Domain: "domain", TaskList: "tl"}, {Domain: "domain", TaskList: "tl"}},
+		},
+		{
+			name: "invalid worker options",
+			entries: []WorkerHostEntry{
+				{Domain: "domain", TaskList: "tl", Options: WorkerOptions{MaxConcurrentActivityExecutionSize: -1}},
+			},
+		},
+		{
+			name:    "invalid sticky cache",
+			entries: []WorkerHostEntry{{Domain: "domain", TaskList: "tl"}},
+			options: WorkerHostOptions{StickyCache: StickyCacheOptions{Size: -1}},
+		},
+		{
+			name:    "negative health poll error window",
+			entries: []WorkerHostEntry{{Domain: "domain", TaskList: "tl"}},
+			options: WorkerHostOptions{HealthPollErrorWindow: -time.Second},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := NewWorkerHost(service, tc.entries, tc.options)
+			require.Error(t, err)
+			require.Nil(t, host)
+		})
+	}
+}
+
+func TestWorkerHost(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	domain := "testDomain"
+	domainStatus := s.DomainStatusRegistered
+	service.EXPECT().DescribeDomain(gomock.Any(), gomock.Any(), gomock.Any()).Return(&s.DescribeDomainResponse{
+		DomainInfo: &s.DomainInfo{Name: common.StringPtr(domain), Status: &domainStatus},
+	}, nil).AnyTimes()
+	service.EXPECT().PollForDecisionTask(gomock.Any(), gomock.Any()).
+		Return(&s.PollForDecisionTaskResponse{}, nil).AnyTimes()
+	service.EXPECT().PollForActivityTask(gomock.Any(), gomock.Any()).
+		Return(&s.PollForActivityTaskResponse{}, nil).AnyTimes()
+
+	scope := tally.NewTestScope("", nil)
+	var registered []string
+	register := func(registry WorkerRegistry) {
+		registered = append(registered, registry.(*aggregatedWorker).taskList)
+	}
+	host, err := NewWorkerHost(service, []WorkerHostEntry{
+		{Domain: domain, TaskList: "taskList1", Register: register},
+		{Domain: domain, TaskList: "taskList2", Register: register, Options: WorkerOptions{DisableActivityWorker: true}},
+	}, WorkerHostOptions{Logger: zap.NewNop(), MetricsScope: scope, StickyCache: StickyCacheOptions{Size: 10}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"taskList1", "taskList2"}, registered)
+
+	wh := host.(*workerHost)
+	require.NotNil(t, wh.stickyCache)
+	for _, worker := range wh.workers {
+		require.Equal(t, wh.stickyCache, worker.workflowWorker.(*workflowWorker).getStickyCache())
+	}
+	require.Len(t, host.Workers(), 2)
+	require.False(t, host.Health().Healthy)
+
+	require.NoError(t, host.Start())
+	health := host.Health()
+	require.True(t, health.Healthy)
+	require.Equal(t, []WorkerHealth{
+		{Domain: domain, TaskList: "taskList1", Healthy: true},
+		{Domain: domain, TaskList: "taskList2", Healthy: true},
+	}, health.Workers)
+
+	// The metrics of each worker are tagged with its domain and task list.
+	startedTaskLists := make(map[string]bool)
+	for _, counter := range scope.Snapshot().Counters() {
+		if counter.Name() == metrics.WorkerStartCounter {
+			require.Equal(t, domain, counter.Tags()[tagDomain])
+			startedTaskLists[counter.Tags()[tagTaskList]] = true
+		}
+	}
+	require.Equal(t, map[string]bool{"taskList1": true, "taskList2": true}, startedTaskLists)
+
+	host.Stop()
+	require.False(t, host.Health().Healthy)
+	host.Stop()
+}
+
+func TestWorkerHostStartFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	service.EXPECT().DescribeDomain(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, &s.EntityNotExistsError{Message: "domain not found"}).AnyTimes()
+
+	host, err := NewWorkerHost(service, []WorkerHostEntry{{Domain: "unknownDomain", TaskList: "taskList"}},
+		WorkerHostOptions{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	require.Error(t, host.Start())
+	require.False(t, host.Health().Healthy)
+	host.Stop()
+}
+
+func TestGetWorkerHealth(t *testing.T) {
+	now := time.Now()
+	window := time.Minute
+	testCases := []struct {
+		name    string
+		stats   WorkerStats
+		healthy bool
+	}{
+		{name: "no task worker", stats: WorkerStats{}, healthy: true},
+		{
+			name: "started",
+			stats: WorkerStats{
+				DecisionWorker: &TaskWorkerStats{Started: true},
+				ActivityWorker: &TaskWorkerStats{Started: true},
+			},
+			healthy: true,
+		},
+		{
+			name:  "activity worker not started",
+			stats: WorkerStats{DecisionWorker: &TaskWorkerStats{Started: true}, ActivityWorker: &TaskWorkerStats{}},
+		},
+		{
+			name: "recent poll error",
+			stats: WorkerStats{DecisionWorker: &TaskWorkerStats{
+				Started:           true,
+				LastPollError:     "poll failed",
+				LastPollErrorTime: now.Add(-time.Second),
+			}},
+		},
+		{
+			name: "old poll error",
+			stats: WorkerStats{DecisionWorker: &TaskWorkerStats{
+				Started:           true,
+				LastPollError:     "poll failed",
+				LastPollErrorTime: now.Add(-2 * window),
+			}},
+			healthy: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			health := getWorkerHealth(tc.stats, now, window)
+			require.Equal(t, tc.healthy, health.Healthy)
+			require.Equal(t, tc.healthy, health.Reason == "")
+		})
+	}
+}
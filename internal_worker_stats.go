@@ -0,0 +1,213 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	s "go.uber.org/cadence/.gen/go/shared"
+)
+
+type (
+	// workerStatsHandler serves the stats of a worker, see NewWorkerStatsHandler.
+	workerStatsHandler struct {
+		worker Worker
+	}
+
+	// workerStatsResponse is the JSON response of workerStatsHandler.
+	workerStatsResponse struct {
+		WorkerStats
+		StackTraces []cachedWorkflowStackTrace `json:",omitempty"`
+	}
+
+	// cachedWorkflowStackTrace is the stack trace of a workflow execution in the sticky cache.
+	cachedWorkflowStackTrace struct {
+		WorkflowExecution WorkflowExecution
+		WorkflowType      string
+		StackTrace        string
+	}
+
+	// stackTraceProvider is implemented by the workers that can dump the stack traces of their cached workflows.
+	stackTraceProvider interface {
+		getCachedWorkflowStackTraces() []cachedWorkflowStackTrace
+	}
+)
+
+func (h *workerStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.worker.(WorkerStatsProvider)
+	if !ok {
+		http.Error(w, "worker doesn't provide stats", http.StatusNotImplemented)
+		return
+	}
+	response := workerStatsResponse{WorkerStats: provider.Stats()}
+	if r.URL.Query().Get("stack_trace") == "true" {
+		if p, ok := h.worker.(stackTraceProvider); ok {
+			response.StackTraces = p.getCachedWorkflowStackTraces()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Stats returns the stats of the workflow and activity workers.
+func (aw *aggregatedWorker) Stats() WorkerStats {
+	stats := WorkerStats{
+		Domain:   aw.domain,
+		TaskList: aw.taskList,
+		Identity: aw.identity,
+	}
+	if p, ok := aw.workflowWorker.(WorkerStatsProvider); ok && !isInterfaceNil(aw.workflowWorker) {
+		workflowStats := p.Stats()
+		stats.WorkflowTypes = workflowStats.WorkflowTypes
+		stats.DecisionWorker = workflowStats.DecisionWorker
+		stats.StickyCacheSize = workflowStats.StickyCacheSize
+	}
+	if p, ok := aw.activityWorker.(WorkerStatsProvider); ok && !isInterfaceNil(aw.activityWorker) {
+		activityStats := p.Stats()
+		stats.ActivityTypes = activityStats.ActivityTypes
+		stats.ActivityWorker = activityStats.ActivityWorker
+	}
+	return stats
+}
+
+func (aw *aggregatedWorker) getCachedWorkflowStackTraces() []cachedWorkflowStackTrace {
+	if p, ok := aw.workflowWorker.(stackTraceProvider); ok {
+		return p.getCachedWorkflowStackTraces()
+	}
+	return nil
+}
+
+// Stats returns the stats of the decision task worker.
+func (ww *workflowWorker) Stats() WorkerStats {
+	workflowTypes := ww.hostEnv.getRegisteredWorkflowTypes()
+	sort.Strings(workflowTypes)
+	return WorkerStats{
+		Domain:          ww.domain,
+		TaskList:        ww.executionParameters.TaskList,
+		Identity:        ww.identity,
+		WorkflowTypes:   workflowTypes,
+		DecisionWorker:  ww.worker.stats(),
+		StickyCacheSize: ww.getStickyCache().size(),
+	}
+}
+
+func (ww *workflowWorker) getCachedWorkflowStackTraces() []cachedWorkflowStackTrace {
+	return ww.getStickyCache().getStackTraces()
+}
+
+// getStickyCache returns the sticky cache used by the worker.
+func (ww *workflowWorker) getStickyCache() *stickyWorkflowCache {
+	if ww.executionParameters.StickyCache != nil {
+		return ww.executionParameters.StickyCache
+	}
+	return getProcessStickyCache()
+}
+
+// Stats returns the stats of the activity task worker.
+func (aw *activityWorker) Stats() WorkerStats {
+	activityTypes := aw.hostEnv.getRegisteredActivityTypes()
+	sort.Strings(activityTypes)
+	return WorkerStats{
+		Domain:         aw.domain,
+		TaskList:       aw.executionParameters.TaskList,
+		Identity:       aw.identity,
+		ActivityTypes:  activityTypes,
+		ActivityWorker: aw.worker.stats(),
+	}
+}
+
+// stats returns a snapshot of the state of the worker.
+func (bw *baseWorker) stats() *TaskWorkerStats {
+	stats := &TaskWorkerStats{
+		Started:            bw.isStarted(),
+		MaxConcurrentTasks: bw.options.maxConcurrentTask,
+		TaskRateLimit:      float64(bw.taskLimiter.Limit()),
+		TaskRateLimitBurst: bw.taskLimiter.Burst(),
+	}
+	if stats.Started && !bw.isShutdown() {
+		stats.PollerCount = bw.options.pollerCount
+		if bw.autoScaler != nil {
+			stats.PollerCount = bw.autoScaler.getPollerCount()
+		}
+	}
+
+	bw.statsLock.Lock()
+	defer bw.statsLock.Unlock()
+	for task, startTime := range bw.runningTasks {
+		if isEmptyTask(task) {
+			// the placeholder of an empty poll, which is not a task being processed
+			continue
+		}
+		stats.RunningTasks = append(stats.RunningTasks, getRunningTaskStats(task, startTime))
+	}
+	sort.Slice(stats.RunningTasks, func(i, j int) bool {
+		return stats.RunningTasks[i].StartTime.Before(stats.RunningTasks[j].StartTime)
+	})
+	if bw.lastPollError != nil {
+		stats.LastPollError = bw.lastPollError.Error()
+		stats.LastPollErrorTime = bw.lastPollErrorTime
+	}
+	return stats
+}
+
+func isEmptyTask(task interface{}) bool {
+	switch t := task.(type) {
+	case *workflowTask:
+		return t.task == nil
+	case *activityTask:
+		return t.task == nil
+	}
+	return false
+}
+
+func getRunningTaskStats(task interface{}, startTime time.Time) RunningTaskStats {
+	stats := RunningTaskStats{StartTime: startTime}
+	switch t := task.(type) {
+	case *workflowTask:
+		stats.WorkflowExecution = getRunningTaskExecution(t.task.WorkflowExecution)
+		if t.task.WorkflowType != nil {
+			stats.WorkflowType = t.task.WorkflowType.GetName()
+		}
+	case *activityTask:
+		stats.WorkflowExecution = getRunningTaskExecution(t.task.WorkflowExecution)
+		if t.task.ActivityType != nil {
+			stats.ActivityType = t.task.ActivityType.GetName()
+		}
+		stats.ActivityID = t.task.GetActivityId()
+	}
+	return stats
+}
+
+func getRunningTaskExecution(execution *s.WorkflowExecution) WorkflowExecution {
+	if execution == nil {
+		return WorkflowExecution{}
+	}
+	return WorkflowExecution{ID: execution.GetWorkflowId(), RunID: execution.GetRunId()}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+)
+
+func TestWorkerStats(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	worker := NewWorker(service, "testDomain", "testTaskList", WorkerOptions{
+		Identity:                           "testIdentity",
+		MaxConcurrentActivityExecutionSize: 10,
+		MaxActivityExecutionPerSecond:      5,
+	})
+	worker.(WorkerRegistry).RegisterWorkflowWithOptions(testReplayWorkflow, RegisterWorkflowOptions{Name: "testStatsWorkflow"})
+	worker.(WorkerRegistry).RegisterActivityWithOptions(testActivity, RegisterActivityOptions{Name: "testStatsActivity"})
+
+	stats := worker.(WorkerStatsProvider).Stats()
+	assert.Equal(t, "testDomain", stats.Domain)
+	assert.Equal(t, "testTaskList", stats.TaskList)
+	assert.Equal(t, "testIdentity", stats.Identity)
+	assert.Contains(t, stats.WorkflowTypes, "testStatsWorkflow")
+	assert.Contains(t, stats.ActivityTypes, "testStatsActivity")
+	require.NotNil(t, stats.DecisionWorker)
+	assert.False(t, stats.DecisionWorker.Started)
+	assert.Equal(t, 0, stats.DecisionWorker.PollerCount)
+	require.NotNil(t, stats.ActivityWorker)
+	assert.Equal(t, 10, stats.ActivityWorker.MaxConcurrentTasks)
+	assert.Equal(t, 5.0, stats.ActivityWorker.TaskRateLimit)
+
+	worker = NewWorker(service, "testDomain", "testTaskList", WorkerOptions{DisableActivityWorker: true})
+	assert.Nil(t, worker.(WorkerStatsProvider).Stats().ActivityWorker)
+}
+
+func TestBaseWorkerStats(t *testing.T) {
+	bw := newBaseWorker(baseWorkerOptions{pollerCount: 2, maxConcurrentTask: 3, maxTaskPerSecond: 100}, getLogger(), nil)
+	startTime := time.Now()
+	bw.runningTasks[&activityTask{task: &s.PollForActivityTaskResponse{
+		WorkflowExecution: &s.WorkflowExecution{WorkflowId: common.StringPtr("wid"), RunId: common.StringPtr("rid")},
+		ActivityId:        common.StringPtr("0"),
+		ActivityType:      &s.ActivityType{Name: common.StringPtr("testActivity")},
+	}}] = startTime
+	bw.runningTasks[&workflowTask{task: &s.PollForDecisionTaskResponse{
+		WorkflowType: &s.WorkflowType{Name: common.StringPtr("testWorkflow")},
+	}}] = startTime.Add(-time.Second)
+	bw.runningTasks[&workflowTask{}] = startTime
+	bw.runningTasks[&activityTask{}] = startTime
+	bw.lastPollError = errors.New("poll failed")
+
+	stats := bw.stats()
+	assert.Equal(t, 3, stats.MaxConcurrentTasks)
+	assert.Equal(t, 100.0, stats.TaskRateLimit)
+	assert.Equal(t, "poll failed", stats.LastPollError)
+	require.Equal(t, 2, len(stats.RunningTasks))
+	assert.Equal(t, "testWorkflow", stats.RunningTasks[0].WorkflowType)
+	assert.Equal(t, RunningTaskStats{
+		WorkflowExecution: WorkflowExecution{ID: "wid", RunID: "rid"},
+		ActivityType:      "testActivity",
+		ActivityID:        "0",
+		StartTime:         startTime,
+	}, stats.RunningTasks[1])
+}
+
+type testStackTraceEventHandler struct {
+	workflowExecutionEventHandler
+	stackTrace string
+}
+
+func (h *testStackTraceEventHandler) StackTrace() string {
+	return h.stackTrace
+}
+
+func (h *testStackTraceEventHandler) Close() {}
+
+func TestWorkerStatsHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	worker := NewWorker(service, "testDomain", "testTaskList", WorkerOptions{StickyCache: StickyCacheOptions{Size: 10}})
+	stickyCache := worker.(*aggregatedWorker).workflowWorker.(*workflowWorker).executionParameters.StickyCache
+	defer stickyCache.close()
+
+	execution := WorkflowExecution{ID: "testWorkflowID", RunID: "testRunID"}
+	_, err := stickyCache.putWorkflowContext(execution.RunID, &workflowExecutionContext{
+		workflowInfo: &WorkflowInfo{WorkflowExecution: execution, WorkflowType: WorkflowType{Name: "testWorkflow"}},
+		wth:          &workflowTaskHandlerImpl{metricsScope: tally.NoopScope},
+		eventHandler: &testStackTraceEventHandler{stackTrace: "coroutine root [blocked on selector-1.Select]:"},
+	})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	NewWorkerStatsHandler(worker).ServeHTTP(recorder, httptest.NewRequest("GET", "/?stack_trace=true", nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var response workerStatsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "testTaskList", response.TaskList)
+	assert.NotNil(t, response.DecisionWorker)
+	assert.Equal(t, 1, response.StickyCacheSize)
+	assert.Equal(t, []cachedWorkflowStackTrace{{
+		WorkflowExecution: execution,
+		WorkflowType:      "testWorkflow",
+		StackTrace:        "coroutine root [blocked on selector-1.Select]:",
+	}}, response.StackTraces)
+
+	recorder = httptest.NewRecorder()
+	NewWorkerStatsHandler(worker).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	response = workerStatsResponse{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Empty(t, response.StackTraces)
+}
+
+type testNoStatsWorker struct {
+	Worker
+}
+
+func TestWorkerStatsHandlerWithoutStats(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewWorkerStatsHandler(testNoStatsWorker{}).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotImplemented, recorder.Code)
+}
@@ -293,6 +293,41 @@ func TestWorkerStartFailsWithInvalidDomain(t *testing.T) {
 	}
 }
 
+func TestWorkerRegistry(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	helloFn := func(msg string) (string, error) { return "hello_" + msg, nil }
+	byeFn := func(msg string) (string, error) { return "bye_" + msg, nil }
+	helloWorker := createWorker(t, service)
+	helloWorker.(WorkerRegistry).RegisterActivityWithOptions(helloFn, RegisterActivityOptions{Name: "registryActivity"})
+	byeWorker := createWorker(t, service)
+	byeWorker.(WorkerRegistry).RegisterActivityWithOptions(byeFn, RegisterActivityOptions{Name: "registryActivity"})
+
+	// Each worker resolves the activity type to its own implementation.
+	a, ok := helloWorker.(*aggregatedWorker).hostEnv.getActivityFn("registryActivity")
+	assert.True(t, ok)
+	assert.Equal(t, getFunctionName(helloFn), getFunctionName(a))
+	a, ok = byeWorker.(*aggregatedWorker).hostEnv.getActivityFn("registryActivity")
+	assert.True(t, ok)
+	assert.Equal(t, getFunctionName(byeFn), getFunctionName(a))
+	_, ok = getHostEnvironment().getActivityFn("registryActivity")
+	assert.False(t, ok)
+
+	// The globally registered functions are still hosted, and can be overridden by the worker.
+	_, ok = helloWorker.(*aggregatedWorker).hostEnv.getActivityFn("testActivity")
+	assert.True(t, ok)
+	assert.NotPanics(t, func() {
+		helloWorker.(WorkerRegistry).RegisterActivityWithOptions(helloFn, RegisterActivityOptions{Name: "testActivity"})
+	})
+	assert.Panics(t, func() {
+		helloWorker.(WorkerRegistry).RegisterActivityWithOptions(byeFn, RegisterActivityOptions{Name: "registryActivity"})
+	})
+	alias, ok := helloWorker.(*aggregatedWorker).hostEnv.getActivityAlias(getFunctionName(helloFn))
+	assert.True(t, ok)
+	assert.Equal(t, "testActivity", alias)
+}
+
 func createWorker(t *testing.T, service *workflowservicetest.MockClient) Worker {
 	domain := "testDomain"
 	domainStatus := s.DomainStatusRegistered
@@ -329,6 +364,40 @@ func createWorker(t *testing.T, service *workflowservicetest.MockClient) Worker
 	return worker
 }
 
+func TestWorkerOptionsConcurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	worker := NewWorker(service, "testDomain", "testTaskList", WorkerOptions{
+		MaxConcurrentActivityExecutionSize:     10,
+		MaxActivityExecutionPerSecond:          5,
+		MaxConcurrentActivityTaskPollers:       3,
+		MaxConcurrentDecisionTaskExecutionSize: 4,
+		MaxDecisionTaskExecutionPerSecond:      2,
+		MaxConcurrentDecisionTaskPollers:       1,
+	})
+	aw := worker.(*aggregatedWorker)
+	activityOptions := aw.activityWorker.(*activityWorker).worker.options
+	assert.Equal(t, 3, activityOptions.pollerCount)
+	assert.Equal(t, 10, activityOptions.maxConcurrentTask)
+	assert.Equal(t, 5.0, activityOptions.maxTaskPerSecond)
+	decisionOptions := aw.workflowWorker.(*workflowWorker).worker.options
+	assert.Equal(t, 1, decisionOptions.pollerCount)
+	assert.Equal(t, 4, decisionOptions.maxConcurrentTask)
+	assert.Equal(t, 2.0, decisionOptions.maxTaskPerSecond)
+
+	// The zero values use the defaults.
+	aw = NewWorker(service, "testDomain", "testTaskList", WorkerOptions{}).(*aggregatedWorker)
+	decisionOptions = aw.workflowWorker.(*workflowWorker).worker.options
+	assert.Equal(t, defaultConcurrentPollRoutineSize, decisionOptions.pollerCount)
+	assert.Equal(t, defaultMaxConcurrentWorkflowExecutionSize, decisionOptions.maxConcurrentTask)
+	assert.Equal(t, float64(defaultMaxWorkflowExecutionRate), decisionOptions.maxTaskPerSecond)
+
+	assert.Panics(t, func() {
+		NewWorker(service, "testDomain", "testTaskList", WorkerOptions{MaxConcurrentDecisionTaskPollers: -1})
+	})
+}
+
 func TestCompleteActivity(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockService := workflowservicetest.NewMockClient(mockCtrl)
@@ -21,13 +21,18 @@
 package cadence
 
 import (
+	"context"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
 	m "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/yarpc"
 	"go.uber.org/zap"
 )
 
@@ -121,3 +126,212 @@ func (s *WorkersTestSuite) TestPollForDecisionTask_InternalServiceError() {
 	workflowWorker.Start()
 	workflowWorker.Stop()
 }
+
+type workerStopActivity struct {
+	started   chan struct{}
+	cancelled chan error    // receives the error of the context of the activity once it is cancelled
+	wrapUp    time.Duration // how long the activity keeps running once its context is cancelled
+}
+
+func newWorkerStopActivity(wrapUp time.Duration) *workerStopActivity {
+	return &workerStopActivity{started: make(chan struct{}), cancelled: make(chan error, 1), wrapUp: wrapUp}
+}
+
+func (a *workerStopActivity) Execute(ctx context.Context, input []byte) ([]byte, error) {
+	close(a.started)
+	<-ctx.Done()
+	a.cancelled <- ctx.Err()
+	time.Sleep(a.wrapUp)
+	return []byte("stopped"), nil
+}
+
+func (a *workerStopActivity) ActivityType() ActivityType {
+	return ActivityType{Name: "workerStopActivity"}
+}
+
+func (a *workerStopActivity) GetFunction() interface{} {
+	return a.Execute
+}
+
+// startWorkerStopActivity starts an activity worker running the activity, which is reported through respond.
+func (s *WorkersTestSuite) startWorkerStopActivity(a *workerStopActivity, stopTimeout time.Duration,
+	respond func(service *workflowservicetest.MockClient)) Worker {
+	logger, _ := zap.NewDevelopment()
+	mockCtrl := gomock.NewController(s.T())
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	now := time.Now()
+	activityTask := &m.PollForActivityTaskResponse{
+		TaskToken:                     []byte("token"),
+		WorkflowExecution:             &m.WorkflowExecution{WorkflowId: common.StringPtr("wID"), RunId: common.StringPtr("rID")},
+		ActivityType:                  &m.ActivityType{Name: common.StringPtr("workerStopActivity")},
+		ActivityId:                    common.StringPtr("aID"),
+		ScheduledTimestamp:            common.Int64Ptr(now.UnixNano()),
+		ScheduleToCloseTimeoutSeconds: common.Int32Ptr(10),
+		StartedTimestamp:              common.Int64Ptr(now.UnixNano()),
+		StartToCloseTimeoutSeconds:    common.Int32Ptr(10),
+	}
+	service.EXPECT().DescribeDomain(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	service.EXPECT().PollForActivityTask(gomock.Any(), gomock.Any()).Return(activityTask, nil).Times(1)
+	service.EXPECT().PollForActivityTask(gomock.Any(), gomock.Any()).Return(&m.PollForActivityTaskResponse{}, nil).AnyTimes()
+	respond(service)
+
+	executionParameters := workerExecutionParameters{
+		TaskList:                        "testTaskList",
+		ConcurrentPollRoutineSize:       1,
+		ConcurrentActivityExecutionSize: 1,
+		MaxActivityExecutionPerSecond:   1000,
+		WorkerStopTimeout:               stopTimeout,
+		Logger:                          logger,
+	}
+	hostEnv := getHostEnvironment()
+	hostEnv.addActivity(a.ActivityType().Name, a)
+	activityWorker := newActivityWorker(service, "testDomain", executionParameters, nil, hostEnv)
+	s.NoError(activityWorker.Start())
+
+	select {
+	case <-a.started:
+	case <-time.After(time.Second):
+		s.FailNow("activity didn't start")
+	}
+	return activityWorker
+}
+
+func (s *WorkersTestSuite) TestActivityWorkerStopDrainsRunningActivities() {
+	respondC := make(chan *m.RespondActivityTaskCompletedRequest, 1)
+	a := newWorkerStopActivity(100 * time.Millisecond)
+	activityWorker := s.startWorkerStopActivity(a, time.Second, func(service *workflowservicetest.MockClient) {
+		service.EXPECT().RespondActivityTaskCompleted(gomock.Any(), gomock.Any()).Return(nil).Do(
+			func(ctx context.Context, request *m.RespondActivityTaskCompletedRequest, opts ...yarpc.CallOption) {
+				respondC <- request
+			}).Times(1)
+	})
+	stopTime := time.Now()
+	activityWorker.Stop()
+	s.True(time.Now().Sub(stopTime) < time.Second, "Stop waited for the whole WorkerStopTimeout")
+
+	// The activity is cancelled as soon as the worker stops, and what it returns is reported before Stop returns.
+	s.Equal(ErrWorkerStopping, <-a.cancelled)
+	select {
+	case request := <-respondC:
+		s.Equal([]byte("token"), request.TaskToken)
+		s.Equal([]byte("stopped"), request.Result)
+	default:
+		s.Fail("activity result wasn't reported")
+	}
+}
+
+func (s *WorkersTestSuite) TestActivityWorkerThrottledActivityTypes() {
+	logger, _ := zap.NewDevelopment()
+	mockCtrl := gomock.NewController(s.T())
+	service := workflowservicetest.NewMockClient(mockCtrl)
+
+	// The second throttledActivity waits for the first one, which waits for otherActivity.
+	otherDone := make(chan struct{})
+	registry := newRegistry()
+	s.NoError(registry.RegisterActivityWithOptions(func(ctx context.Context) error {
+		select {
+		case <-otherDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, RegisterActivityOptions{Name: "throttledActivity", MaxConcurrentExecutions: 1}))
+	s.NoError(registry.RegisterActivityWithOptions(func(ctx context.Context) error {
+		close(otherDone)
+		return nil
+	}, RegisterActivityOptions{Name: "otherActivity"}))
+
+	now := time.Now()
+	newActivityTask := func(token, activityType string) *m.PollForActivityTaskResponse {
+		return &m.PollForActivityTaskResponse{
+			TaskToken:                     []byte(token),
+			WorkflowExecution:             &m.WorkflowExecution{WorkflowId: common.StringPtr("wID"), RunId: common.StringPtr("rID")},
+			ActivityType:                  &m.ActivityType{Name: common.StringPtr(activityType)},
+			ActivityId:                    common.StringPtr(token),
+			ScheduledTimestamp:            common.Int64Ptr(now.UnixNano()),
+			ScheduleToCloseTimeoutSeconds: common.Int32Ptr(10),
+			StartedTimestamp:              common.Int64Ptr(now.UnixNano()),
+			StartToCloseTimeoutSeconds:    common.Int32Ptr(10),
+		}
+	}
+	service.EXPECT().DescribeDomain(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	for _, task := range []*m.PollForActivityTaskResponse{
+		newActivityTask("throttled1", "throttledActivity"),
+		newActivityTask("throttled2", "throttledActivity"),
+		newActivityTask("other", "otherActivity"),
+	} {
+		service.EXPECT().PollForActivityTask(gomock.Any(), gomock.Any()).Return(task, nil).Times(1)
+	}
+	service.EXPECT().PollForActivityTask(gomock.Any(), gomock.Any()).Return(&m.PollForActivityTaskResponse{}, nil).AnyTimes()
+	respondC := make(chan string, 3)
+	service.EXPECT().RespondActivityTaskCompleted(gomock.Any(), gomock.Any()).Return(nil).Do(
+		func(ctx context.Context, request *m.RespondActivityTaskCompletedRequest, opts ...yarpc.CallOption) {
+			respondC <- string(request.TaskToken)
+		}).Times(3)
+
+	// The worker has a slot for the first throttledActivity and one for otherActivity, as the second
+	// throttledActivity gives its slot back while it waits.
+	executionParameters := workerExecutionParameters{
+		TaskList:                        "testTaskList",
+		ConcurrentPollRoutineSize:       1,
+		ConcurrentActivityExecutionSize: 2,
+		MaxActivityExecutionPerSecond:   1000,
+		Logger:                          logger,
+	}
+	activityWorker := newActivityWorker(service, "testDomain", executionParameters, nil, registry)
+	s.NoError(activityWorker.Start())
+	defer activityWorker.Stop()
+
+	var completed []string
+	for len(completed) < 3 {
+		select {
+		case token := <-respondC:
+			completed = append(completed, token)
+		case <-time.After(5 * time.Second):
+			s.FailNow("activities didn't complete", "completed: %v", completed)
+		}
+	}
+	// otherActivity and the first throttledActivity complete in any order, the second throttledActivity runs last.
+	sort.Strings(completed[:2])
+	s.Equal([]string{"other", "throttled1", "throttled2"}, completed)
+}
+
+func (s *WorkersTestSuite) TestActivityWorkerStopTimeout() {
+	// The activity doesn't wrap up within the WorkerStopTimeout, so Stop returns before it is reported.
+	respondC := make(chan *m.RespondActivityTaskCompletedRequest, 1)
+	a := newWorkerStopActivity(500 * time.Millisecond)
+	activityWorker := s.startWorkerStopActivity(a, 100*time.Millisecond, func(service *workflowservicetest.MockClient) {
+		service.EXPECT().RespondActivityTaskCompleted(gomock.Any(), gomock.Any()).Return(nil).Do(
+			func(ctx context.Context, request *m.RespondActivityTaskCompletedRequest, opts ...yarpc.CallOption) {
+				respondC <- request
+			}).Times(1)
+	})
+	stopTime := time.Now()
+	activityWorker.Stop()
+	s.True(time.Now().Sub(stopTime) >= 100*time.Millisecond, "Stop returned before the WorkerStopTimeout")
+
+	s.Equal(ErrWorkerStopping, <-a.cancelled)
+	select {
+	case <-respondC:
+		s.Fail("activity was reported before Stop returned")
+	default:
+	}
+	// The activity is still reported once it returns.
+	select {
+	case request := <-respondC:
+		s.Equal([]byte("stopped"), request.Result)
+	case <-time.After(time.Second):
+		s.Fail("activity result wasn't reported")
+	}
+}
+
+func (s *WorkersTestSuite) TestActivityWorkerStopReportsActivityError() {
+	// An activity failing because of the worker stop is reported like any other failure.
+	request := convertActivityResultToRespondRequest("identity", []byte("token"), nil, ErrWorkerStopping)
+	failed, ok := request.(*m.RespondActivityTaskFailedRequest)
+	s.True(ok)
+	reason, details := getErrorDetails(ErrWorkerStopping)
+	s.Equal(reason, failed.GetReason())
+	s.Equal(details, failed.Details)
+}
@@ -140,6 +140,8 @@ type (
 		keptBlocked  bool             // true indicates that coroutine didn't make any progress since the last yield unblocking
 		closed       bool             // indicates that owning coroutine has finished execution
 		panicError   *PanicError      // non nil if coroutine had unhandled panic
+		goroutineID  string           // id of the goroutine that runs the coroutine, used to dump its stack trace
+		deadlocked   bool             // true indicates that coroutine didn't yield within the deadlock detection timeout
 	}
 
 	dispatcherImpl struct {
@@ -150,6 +152,9 @@ type (
 		executing        bool       // currently running ExecuteUntilAllBlocked. Used to avoid recursive calls to it.
 		mutex            sync.Mutex // used to synchronize executing
 		closed           bool
+		// time a coroutine may run without yielding before it is considered deadlocked, zero disables the detection
+		deadlockDetectionTimeout time.Duration
+		deadlockError            *PanicError // non nil if a coroutine is deadlocked
 	}
 
 	workflowOptions struct {
@@ -161,6 +166,7 @@ type (
 		domain                              *string
 		workflowID                          string
 		childPolicy                         ChildWorkflowPolicy
+		header                              *shared.Header
 		waitForCancellation                 bool
 		signalChannels                      map[string]Channel
 		queryHandlers                       map[string]func([]byte) ([]byte, error)
@@ -198,6 +204,13 @@ type (
 		fn        interface{}
 		queryType string
 	}
+
+	// workflowEnvironmentInterceptor is the last link of the workflow interceptor chain, it implements the workflow
+	// APIs on top of the workflowEnvironment.
+	workflowEnvironmentInterceptor struct {
+		env      workflowEnvironment
+		workflow workflow
+	}
 )
 
 const (
@@ -205,12 +218,18 @@ const (
 	workflowResultContextKey      = "workflowResult"
 	coroutinesContextKey          = "coroutines"
 	workflowEnvOptionsContextKey  = "wfEnvOptions"
+	workflowInterceptorContextKey = "workflowInterceptor"
+	// incomingHeaderContextKey holds the header received with the workflow start or with the handled signal.
+	incomingHeaderContextKey = "incomingHeader"
+	// outgoingHeaderContextKey holds the header sent with the activity or the child workflow started with the context.
+	outgoingHeaderContextKey = "outgoingHeader"
 )
 
 // Assert that structs do indeed implement the interfaces
 var _ Channel = (*channelImpl)(nil)
 var _ Selector = (*selectorImpl)(nil)
 var _ dispatcher = (*dispatcherImpl)(nil)
+var _ WorkflowInterceptor = (*workflowEnvironmentInterceptor)(nil)
 
 var stackBuf [100000]byte
 
@@ -231,6 +250,37 @@ func getWorkflowEnvironment(ctx Context) workflowEnvironment {
 	return wc.(workflowEnvironment)
 }
 
+func getWorkflowInterceptor(ctx Context) WorkflowInterceptor {
+	i := ctx.Value(workflowInterceptorContextKey)
+	if i == nil {
+		return &workflowEnvironmentInterceptor{env: getWorkflowEnvironment(ctx)}
+	}
+	return i.(WorkflowInterceptor)
+}
+
+// getIncomingHeader returns the header received with the workflow start or with the signal handled with the context.
+func getIncomingHeader(ctx Context) *shared.Header {
+	header, _ := ctx.Value(incomingHeaderContextKey).(*shared.Header)
+	return header
+}
+
+// getOutgoingHeader returns the header to send with the activity or the child workflow started with the context.
+func getOutgoingHeader(ctx Context) *shared.Header {
+	header, _ := ctx.Value(outgoingHeaderContextKey).(*shared.Header)
+	return header
+}
+
+// newWorkflowInterceptorChain creates the interceptors in the order of the factories, so that the first factory
+// creates the outermost interceptor.
+func newWorkflowInterceptorChain(env workflowEnvironment, workflow workflow) WorkflowInterceptor {
+	var interceptor WorkflowInterceptor = &workflowEnvironmentInterceptor{env: env, workflow: workflow}
+	factories := env.GetWorkflowInterceptors()
+	for i := len(factories) - 1; i >= 0; i-- {
+		interceptor = factories[i].NewInterceptor(env.WorkflowInfo(), interceptor)
+	}
+	return interceptor
+}
+
 func (f *futureImpl) Get(ctx Context, value interface{}) error {
 	more := f.channel.Receive(ctx, nil)
 	if more {
@@ -332,10 +382,11 @@ func (f childWorkflowFutureImpl) GetChildWorkflowExecution() Future {
 	return f.executionFuture
 }
 
-func (d *syncWorkflowDefinition) Execute(env workflowEnvironment, input []byte) {
+func (d *syncWorkflowDefinition) Execute(env workflowEnvironment, header *shared.Header, input []byte) {
 	d.rootCtx = WithValue(background, workflowEnvironmentContextKey, env)
 	var resultPtr *workflowResult
 	d.rootCtx = WithValue(d.rootCtx, workflowResultContextKey, &resultPtr)
+	d.rootCtx = WithValue(d.rootCtx, workflowInterceptorContextKey, newWorkflowInterceptorChain(env, d.workflow))
 
 	// Set default values for the workflow execution.
 	wInfo := env.WorkflowInfo()
@@ -347,7 +398,7 @@ func (d *syncWorkflowDefinition) Execute(env workflowEnvironment, input []byte)
 	activityOptions := getActivityOptions(d.rootCtx)
 	activityOptions.OriginalTaskListName = wInfo.TaskListName
 
-	d.dispatcher = newDispatcher(d.rootCtx, func(ctx Context) {
+	d.dispatcher = newDispatcherWithDeadlockDetection(d.rootCtx, env.GetDeadlockDetectionTimeout(), func(ctx Context) {
 		d.rootCtx, d.cancel = WithCancel(ctx)
 		r := &workflowResult{}
 
@@ -357,7 +408,9 @@ func (d *syncWorkflowDefinition) Execute(env workflowEnvironment, input []byte)
 		state := getState(d.rootCtx)
 		state.yield("yield before executing to setup state")
 
-		r.workflowResult, r.error = d.workflow.Execute(d.rootCtx, input)
+		workflowCtx := WithValue(d.rootCtx, incomingHeaderContextKey, header)
+		r.workflowResult, r.error = getWorkflowInterceptor(workflowCtx).ExecuteWorkflow(workflowCtx,
+			wInfo.WorkflowType.Name, input)
 		rpp := getWorkflowResultPointerPointer(ctx)
 		*rpp = r
 	})
@@ -368,27 +421,14 @@ func (d *syncWorkflowDefinition) Execute(env workflowEnvironment, input []byte)
 		d.cancel()
 	})
 
-	getWorkflowEnvironment(d.rootCtx).RegisterSignalHandler(func(name string, result []byte) {
-		eo := getWorkflowEnvOptions(d.rootCtx)
-		// We don't want this code to be blocked ever, using sendAsync().
-		ch := eo.getSignalChannel(d.rootCtx, name).(*channelImpl)
-		ok := ch.SendAsync(result)
-		if !ok {
-			panic(fmt.Sprintf("Exceeded channel buffer size for signal: %v", name))
-		}
+	getWorkflowEnvironment(d.rootCtx).RegisterSignalHandler(func(name string, result []byte, header *shared.Header) {
+		ctx := WithValue(d.rootCtx, incomingHeaderContextKey, header)
+		getWorkflowInterceptor(ctx).HandleSignal(ctx, name, EncodedValue(result))
 	})
 
 	getWorkflowEnvironment(d.rootCtx).RegisterQueryHandler(func(queryType string, queryArgs []byte) ([]byte, error) {
-		eo := getWorkflowEnvOptions(d.rootCtx)
-		handler, ok := eo.queryHandlers[queryType]
-		if !ok {
-			keys := []string{QueryTypeStackTrace}
-			for k := range eo.queryHandlers {
-				keys = append(keys, k)
-			}
-			return nil, fmt.Errorf("unkonwn queryType %v. KnownQueryTypes=%v", queryType, keys)
-		}
-		return handler(queryArgs)
+		result, err := getWorkflowInterceptor(d.rootCtx).HandleQuery(d.rootCtx, queryType, EncodedValues(queryArgs))
+		return []byte(result), err
 	})
 
 	// There is a inter dependency, before we call Execute() we can have a cancel request since
@@ -401,6 +441,33 @@ func (d *syncWorkflowDefinition) Execute(env workflowEnvironment, input []byte)
 	executeDispatcher(d.rootCtx, d.dispatcher)
 }
 
+func (wc *workflowEnvironmentInterceptor) ExecuteWorkflow(ctx Context, workflowType string, input []byte) ([]byte, error) {
+	return wc.workflow.Execute(ctx, input)
+}
+
+func (wc *workflowEnvironmentInterceptor) HandleSignal(ctx Context, signalName string, input EncodedValue) {
+	eo := getWorkflowEnvOptions(ctx)
+	// We don't want this code to be blocked ever, using sendAsync().
+	ch := eo.getSignalChannel(ctx, signalName).(*channelImpl)
+	ok := ch.SendAsync(input)
+	if !ok {
+		panic(fmt.Sprintf("Exceeded channel buffer size for signal: %v", signalName))
+	}
+}
+
+func (wc *workflowEnvironmentInterceptor) HandleQuery(ctx Context, queryType string, args EncodedValues) (EncodedValue, error) {
+	eo := getWorkflowEnvOptions(ctx)
+	handler, ok := eo.queryHandlers[queryType]
+	if !ok {
+		keys := []string{QueryTypeStackTrace}
+		for k := range eo.queryHandlers {
+			keys = append(keys, k)
+		}
+		return nil, fmt.Errorf("unkonwn queryType %v. KnownQueryTypes=%v", queryType, keys)
+	}
+	return handler([]byte(args))
+}
+
 func (d *syncWorkflowDefinition) OnDecisionTaskStarted() {
 	executeDispatcher(d.rootCtx, d.dispatcher)
 }
@@ -419,7 +486,14 @@ func (d *syncWorkflowDefinition) Close() {
 // Context passed to the root function is child of the passed rootCtx.
 // This way rootCtx can be used to pass values to the coroutine code.
 func newDispatcher(rootCtx Context, root func(ctx Context)) dispatcher {
-	result := &dispatcherImpl{}
+	return newDispatcherWithDeadlockDetection(rootCtx, 0, root)
+}
+
+// newDispatcherWithDeadlockDetection creates a new Dispatcher instance that fails ExecuteUntilAllBlocked if a
+// coroutine doesn't yield within the deadlockDetectionTimeout. Zero timeout disables the detection.
+func newDispatcherWithDeadlockDetection(rootCtx Context, deadlockDetectionTimeout time.Duration,
+	root func(ctx Context)) dispatcher {
+	result := &dispatcherImpl{deadlockDetectionTimeout: deadlockDetectionTimeout}
 	result.newCoroutine(rootCtx, root)
 	return result
 }
@@ -648,17 +722,63 @@ func getStackTraceRaw(top string, omitTop, omitBottom int) string {
 	return strings.Join(lines, "\n")
 }
 
+// getGoroutineID returns the id of the calling goroutine as it appears in the stack traces.
+func getGoroutineID() string {
+	var buf [64]byte
+	// the first line of the stack trace is "goroutine <id> [running]:"
+	fields := strings.Fields(string(buf[:runtime.Stack(buf[:], false)]))
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// getGoroutineStackTrace returns the stack trace of the goroutine with the given id. Unlike getStackTrace it doesn't
+// need to be called from that goroutine, which makes it usable for the coroutines that don't yield.
+func getGoroutineStackTrace(top, goroutineID string) string {
+	buf := make([]byte, len(stackBuf))
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	prefix := fmt.Sprintf("goroutine %s [", goroutineID)
+	for _, trace := range strings.Split(string(buf), "\n\n") {
+		if strings.HasPrefix(trace, prefix) {
+			lines := strings.Split(strings.TrimRightFunc(trace, unicode.IsSpace), "\n")
+			return strings.Join(append([]string{top}, lines[1:]...), "\n")
+		}
+	}
+	return top
+}
+
 // unblocked is called by coroutine to indicate that since the last time yield was unblocked channel or select
 // where unblocked versus calling yield again after checking their condition
 func (s *coroutineState) unblocked() {
 	s.keptBlocked = false
 }
 
-func (s *coroutineState) call() {
+// call unblocks the coroutine and waits until it yields. It returns false if the coroutine didn't yield within the
+// timeout, zero timeout waits forever.
+func (s *coroutineState) call(timeout time.Duration) bool {
 	s.unblock <- func(status string, stackDepth int) bool {
 		return false // unblock
 	}
-	<-s.aboutToBlock
+	if timeout <= 0 {
+		<-s.aboutToBlock
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.aboutToBlock:
+		return true
+	case <-timer.C:
+		return false
+	}
 }
 
 func (s *coroutineState) close() {
@@ -667,18 +787,33 @@ func (s *coroutineState) close() {
 }
 
 func (s *coroutineState) exit() {
-	if !s.closed {
-		s.unblock <- func(status string, stackDepth int) bool {
-			runtime.Goexit()
-			return true
-		}
+	if s.closed {
+		return
 	}
+	exitFunc := func(status string, stackDepth int) bool {
+		runtime.Goexit()
+		return true
+	}
+	if s.deadlocked {
+		// Deadlocked coroutine is not waiting for unblock, so exit it whenever it yields.
+		go func() {
+			<-s.aboutToBlock
+			if !s.closed {
+				s.unblock <- exitFunc
+			}
+		}()
+		return
+	}
+	s.unblock <- exitFunc
 }
 
 func (s *coroutineState) stackTrace() string {
 	if s.closed {
 		return ""
 	}
+	if s.deadlocked {
+		return getGoroutineStackTrace(fmt.Sprintf("coroutine %s [deadlocked]:", s.name), s.goroutineID)
+	}
 	stackCh := make(chan string, 1)
 	s.unblock <- func(status string, stackDepth int) bool {
 		stackCh <- getStackTrace(s.name, status, stackDepth+2)
@@ -736,6 +871,9 @@ func (d *dispatcherImpl) newNamedCoroutine(ctx Context, name string, f func(ctx
 				crt.panicError = newPanicError(r, st)
 			}
 		}()
+		if d.deadlockDetectionTimeout > 0 {
+			crt.goroutineID = getGoroutineID()
+		}
 		crt.initialYield(1, "")
 		f(spawned)
 	}(state)
@@ -764,6 +902,10 @@ func (d *dispatcherImpl) ExecuteUntilAllBlocked() (err *PanicError) {
 	d.executing = true
 	d.mutex.Unlock()
 	defer func() { d.executing = false }()
+	if d.deadlockError != nil {
+		// Deadlocked coroutine can't be called again.
+		return d.deadlockError
+	}
 	allBlocked := false
 	// Keep executing until at least one goroutine made some progress
 	for !allBlocked {
@@ -775,7 +917,11 @@ func (d *dispatcherImpl) ExecuteUntilAllBlocked() (err *PanicError) {
 			if !c.closed {
 				// TODO: Support handling of panic in a coroutine by dispatcher.
 				// TODO: Dump all outstanding coroutines if one of them panics
-				c.call()
+				if !c.call(d.deadlockDetectionTimeout) {
+					c.deadlocked = true
+					d.deadlockError = d.newDeadlockError(c)
+					return d.deadlockError
+				}
 			}
 			// c.call() can close the context so check again
 			if c.closed {
@@ -801,6 +947,14 @@ func (d *dispatcherImpl) ExecuteUntilAllBlocked() (err *PanicError) {
 	return nil
 }
 
+// newDeadlockError creates the error that reports the deadlocked coroutine with the stack traces of all coroutines.
+// Coroutines other than the deadlocked one are blocked on yield, so their stack traces are taken the usual way.
+func (d *dispatcherImpl) newDeadlockError(deadlocked *coroutineState) *PanicError {
+	msg := fmt.Sprintf("Potential deadlock detected: workflow coroutine %q didn't yield for over %v",
+		deadlocked.name, d.deadlockDetectionTimeout)
+	return newPanicError(msg, d.StackTrace())
+}
+
 func (d *dispatcherImpl) IsDone() bool {
 	return len(d.coroutines) == 0
 }
@@ -942,32 +1096,41 @@ func newWorkflowDefinition(workflow workflow) workflowDefinition {
 }
 
 func getValidatedWorkerFunction(workflowFunc interface{}, args []interface{}) (*WorkflowType, []byte, error) {
-	fnName := ""
+	fnName, err := getValidatedWorkflowType(getHostEnvironment(), workflowFunc, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input, err := getHostEnvironment().encodeArgs(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &WorkflowType{Name: fnName}, input, nil
+}
+
+// getValidatedWorkflowType validates the arguments against the workflow function and returns the workflow type name,
+// resolving the name the function is registered under in the registry.
+func getValidatedWorkflowType(registry *hostEnvImpl, workflowFunc interface{}, args []interface{}) (string, error) {
 	fType := reflect.TypeOf(workflowFunc)
 	switch fType.Kind() {
 	case reflect.String:
-		fnName = reflect.ValueOf(workflowFunc).String()
+		return reflect.ValueOf(workflowFunc).String(), nil
 
 	case reflect.Func:
 		if err := validateFunctionArgs(workflowFunc, args, true); err != nil {
-			return nil, nil, err
+			return "", err
 		}
-		fnName = getFunctionName(workflowFunc)
-		if alias, ok := getHostEnvironment().getWorkflowAlias(fnName); ok {
+		fnName := getFunctionName(workflowFunc)
+		if alias, ok := registry.getWorkflowAlias(fnName); ok {
 			fnName = alias
 		}
+		return fnName, nil
 
 	default:
-		return nil, nil, fmt.Errorf(
+		return "", fmt.Errorf(
 			"Invalid type 'workflowFunc' parameter provided, it can be either worker function or name of the worker type: %v",
 			workflowFunc)
 	}
-
-	input, err := getHostEnvironment().encodeArgs(args)
-	if err != nil {
-		return nil, nil, err
-	}
-	return &WorkflowType{Name: fnName}, input, nil
 }
 
 func getValidatedWorkflowOptions(ctx Context) (*workflowOptions, error) {
@@ -1078,6 +1241,24 @@ func (p ChildWorkflowPolicy) toThriftChildPolicyPtr() *shared.ChildPolicy {
 	return &childPolicy
 }
 
+// getRegisteredActivityFn returns the activity function registered under the name, which gives the result type to
+// decode, or the name when the activity is not registered.
+func getRegisteredActivityFn(registry *hostEnvImpl, activityType string) interface{} {
+	if fn, ok := registry.getActivityFn(activityType); ok {
+		return fn
+	}
+	return activityType
+}
+
+// getRegisteredWorkflowFn returns the workflow function registered under the name, which gives the result type to
+// decode, or the name when the workflow is not registered.
+func getRegisteredWorkflowFn(registry *hostEnvImpl, workflowType string) interface{} {
+	if fn, ok := registry.getWorkflowFn(workflowType); ok {
+		return fn
+	}
+	return workflowType
+}
+
 // newDecodeFuture creates a new future as well as associated Settable that is used to set its value.
 // fn - the decoded value needs to be validated against a function.
 func newDecodeFuture(ctx Context, fn interface{}) (Future, Settable) {
@@ -23,6 +23,7 @@ package cadence
 import (
 	"context"
 	"errors"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pborman/uuid"
 	"github.com/uber-go/tally"
 
@@ -49,6 +50,7 @@ type (
 		domain            string
 		metricsScope      tally.Scope
 		identity          string
+		tracer            opentracing.Tracer
 	}
 
 	// domainClient is the client for managing domains.
@@ -99,6 +101,16 @@ func (wc *workflowClient) StartWorkflow(
 		return nil, err
 	}
 
+	var header *s.Header
+	if wc.tracer != nil {
+		var span opentracing.Span
+		span, ctx = startClientSpan(ctx, wc.tracer, "StartWorkflow:"+workflowType.Name,
+			opentracing.Tag{Key: tagWorkflowType, Value: workflowType.Name},
+			opentracing.Tag{Key: tagWorkflowID, Value: workflowID})
+		defer func() { finishSpan(span, err) }()
+		header = injectSpanContext(wc.tracer, span.Context())
+	}
+
 	startRequest := &s.StartWorkflowExecutionRequest{
 		Domain:       common.StringPtr(wc.domain),
 		RequestId:    common.StringPtr(uuid.New()),
@@ -108,7 +120,8 @@ func (wc *workflowClient) StartWorkflow(
 		Input:        input,
 		ExecutionStartToCloseTimeoutSeconds: common.Int32Ptr(executionTimeout),
 		TaskStartToCloseTimeoutSeconds:      common.Int32Ptr(decisionTaskTimeout),
-		Identity:                            common.StringPtr(wc.identity)}
+		Identity:                            common.StringPtr(wc.identity),
+		Header:                              header}
 
 	var response *s.StartWorkflowExecutionResponse
 
@@ -138,15 +151,24 @@ func (wc *workflowClient) StartWorkflow(
 }
 
 // SignalWorkflow signals a workflow in execution.
-func (wc *workflowClient) SignalWorkflow(ctx context.Context, workflowID string, runID string, signalName string, arg interface{}) error {
+func (wc *workflowClient) SignalWorkflow(ctx context.Context, workflowID string, runID string, signalName string, arg interface{}) (err error) {
 	var input []byte
 	if arg != nil {
-		var err error
 		if input, err = getHostEnvironment().encodeArg(arg); err != nil {
 			return err
 		}
 	}
 
+	var header *s.Header
+	if wc.tracer != nil {
+		var span opentracing.Span
+		span, ctx = startClientSpan(ctx, wc.tracer, "SignalWorkflow:"+signalName,
+			opentracing.Tag{Key: tagSignalName, Value: signalName},
+			opentracing.Tag{Key: tagWorkflowID, Value: workflowID})
+		defer func() { finishSpan(span, err) }()
+		header = injectSpanContext(wc.tracer, span.Context())
+	}
+
 	request := &s.SignalWorkflowExecutionRequest{
 		Domain: common.StringPtr(wc.domain),
 		WorkflowExecution: &s.WorkflowExecution{
@@ -156,6 +178,7 @@ func (wc *workflowClient) SignalWorkflow(ctx context.Context, workflowID string,
 		SignalName: common.StringPtr(signalName),
 		Input:      input,
 		Identity:   common.StringPtr(wc.identity),
+		Header:     header,
 	}
 
 	return backoff.Retry(ctx,
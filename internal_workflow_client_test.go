@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/cadence/.gen/go/cadence/workflowservicetest"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/yarpc"
+)
+
+func TestStartWorkflowTracing(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	tracer := mocktracer.New()
+	client := NewClient(service, "testDomain", &ClientOptions{Tracer: tracer})
+
+	var requestSpan opentracing.Span
+	var request *s.StartWorkflowExecutionRequest
+	service.EXPECT().StartWorkflowExecution(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.StartWorkflowExecutionResponse{RunId: common.StringPtr("runID")}, nil).
+		Do(func(ctx context.Context, r *s.StartWorkflowExecutionRequest, opts ...yarpc.CallOption) {
+			requestSpan = opentracing.SpanFromContext(ctx)
+			request = r
+		})
+
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	options := StartWorkflowOptions{
+		ID:                           "testID",
+		TaskList:                     "testTaskList",
+		ExecutionStartToCloseTimeout: time.Minute,
+	}
+	_, err := client.StartWorkflow(ctx, options, "testWorkflowType", "input")
+	require.NoError(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "StartWorkflow:testWorkflowType", span.OperationName)
+	require.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, span.ParentID)
+	require.Equal(t, "testWorkflowType", span.Tag(tagWorkflowType))
+	require.Equal(t, "testID", span.Tag(tagWorkflowID))
+	require.Nil(t, span.Tag("error"))
+	// the span is propagated with the request and in the header of the started workflow rather than with the input.
+	require.Equal(t, span, requestSpan)
+	sent := extractSpanContext(tracer, request.Header)
+	require.NotNil(t, sent)
+	require.Equal(t, span.SpanContext.SpanID, sent.(mocktracer.MockSpanContext).SpanID)
+	input, err := getHostEnvironment().encodeArgs([]interface{}{"input"})
+	require.NoError(t, err)
+	require.Equal(t, input, request.Input)
+}
+
+func TestSignalWorkflowTracing(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	service := workflowservicetest.NewMockClient(mockCtrl)
+	tracer := mocktracer.New()
+	client := NewClient(service, "testDomain", &ClientOptions{Tracer: tracer})
+
+	var requestSpan opentracing.Span
+	var request *s.SignalWorkflowExecutionRequest
+	service.EXPECT().SignalWorkflowExecution(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&s.EntityNotExistsError{Message: "workflow not found"}).
+		Do(func(ctx context.Context, r *s.SignalWorkflowExecutionRequest, opts ...yarpc.CallOption) {
+			requestSpan = opentracing.SpanFromContext(ctx)
+			request = r
+		})
+
+	err := client.SignalWorkflow(context.Background(), "testID", "testRunID", "testSignal", "input")
+	require.Error(t, err)
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "SignalWorkflow:testSignal", span.OperationName)
+	require.Zero(t, span.ParentID)
+	require.Equal(t, "testSignal", span.Tag(tagSignalName))
+	require.Equal(t, "testID", span.Tag(tagWorkflowID))
+	require.Equal(t, true, span.Tag("error"))
+	require.Equal(t, span, requestSpan)
+	sent := extractSpanContext(tracer, request.Header)
+	require.NotNil(t, sent)
+	require.Equal(t, span.SpanContext.SpanID, sent.(mocktracer.MockSpanContext).SpanID)
+	input, err := getHostEnvironment().encodeArg("input")
+	require.NoError(t, err)
+	require.Equal(t, input, request.Input)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+// All code in this file is private to the package.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/uber-go/tally"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/zap"
+)
+
+const (
+	replayDomain     = "ReplayDomain"
+	replayTaskToken  = "ReplayTaskToken"
+	replayWorkflowID = "ReplayWorkflowID"
+	replayIdentity   = "replayer"
+)
+
+// replayOptions are the options of the worker the workflow code is replayed with.
+type replayOptions struct {
+	// time the workflow code may run without yielding before the replay fails, zero disables the detection
+	deadlockDetectionTimeout time.Duration
+	workflowInterceptors     []WorkflowInterceptorFactory
+}
+
+// replayWorkflowHistory replays the history with the workflows of the registry, the same way a worker processes a
+// decision task with the full history of the execution. The decisions of the completed decision tasks are matched with
+// the history events, and so are the ones of the final decision task when the history is closed.
+func replayWorkflowHistory(
+	logger *zap.Logger,
+	hostEnv *hostEnvImpl,
+	execution *s.WorkflowExecution,
+	history *s.History,
+	options replayOptions,
+) error {
+	if history == nil || len(history.Events) == 0 {
+		return errors.New("nil or empty history")
+	}
+	events := history.Events
+	attributes := events[0].WorkflowExecutionStartedEventAttributes
+	if events[0].GetEventType() != s.EventTypeWorkflowExecutionStarted || attributes == nil {
+		return errors.New("first history event is not WorkflowExecutionStarted")
+	}
+	if attributes.WorkflowType == nil {
+		return errors.New("nil WorkflowType in WorkflowExecutionStarted event")
+	}
+	if attributes.TaskList == nil {
+		return errors.New("nil TaskList in WorkflowExecutionStarted event")
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	replayExecution := &s.WorkflowExecution{
+		WorkflowId: common.StringPtr(replayWorkflowID),
+		RunId:      common.StringPtr(uuid.New()),
+	}
+	if execution != nil && execution.WorkflowId != nil {
+		replayExecution.WorkflowId = execution.WorkflowId
+	}
+	if execution != nil && execution.RunId != nil {
+		replayExecution.RunId = execution.RunId
+	}
+
+	// Like a worker processing a decision task, the decisions of the decision tasks completed before the last one are
+	// replayed and matched with the history. When the history is closed, the last decision task is the one that closed
+	// the workflow, its decisions are made as new ones and matched with the events following it.
+	startedEventIDs := completedDecisionStartedEventIDs(events)
+	isClosed := isWorkflowCloseEvent(events[len(events)-1])
+	var previousStartedEventID, lastStartedEventID int64
+	if len(startedEventIDs) > 0 {
+		lastStartedEventID = startedEventIDs[len(startedEventIDs)-1]
+		previousStartedEventID = lastStartedEventID
+		if isClosed {
+			previousStartedEventID = 0
+			if len(startedEventIDs) > 1 {
+				previousStartedEventID = startedEventIDs[len(startedEventIDs)-2]
+			}
+		}
+	}
+	task := &s.PollForDecisionTaskResponse{
+		TaskToken:              []byte(replayTaskToken),
+		WorkflowExecution:      replayExecution,
+		WorkflowType:           attributes.WorkflowType,
+		History:                history,
+		PreviousStartedEventId: common.Int64Ptr(previousStartedEventID),
+		StartedEventId:         common.Int64Ptr(events[len(events)-1].GetEventId()),
+	}
+
+	// The replay has its own cache, so the process one is left alone. Closing it destroys the state of the replayed
+	// workflow.
+	stickyCache := newStickyWorkflowCache(StickyCacheOptions{Size: 1})
+	defer stickyCache.close()
+	params := workerExecutionParameters{
+		TaskList:     attributes.TaskList.GetName(),
+		Identity:     replayIdentity,
+		Logger:       logger,
+		MetricsScope: tally.NoopScope,
+		StickyCache:  stickyCache,
+
+		DeadlockDetectionTimeout: options.deadlockDetectionTimeout,
+		WorkflowInterceptors:     options.workflowInterceptors,
+	}
+	taskHandler := newWorkflowTaskHandler(replayDomain, params, nil, hostEnv)
+	result, stackTrace, err := taskHandler.ProcessWorkflowTask(task, nil, true)
+	if err != nil {
+		return err
+	}
+
+	if !isClosed {
+		return nil
+	}
+	response, ok := result.(*s.RespondDecisionTaskCompletedRequest)
+	if !ok {
+		return errors.New("unexpected result of replayed decision task")
+	}
+	var finalEvents []*s.HistoryEvent
+	for _, event := range events {
+		if event.GetEventId() > lastStartedEventID && isDecisionEvent(event.GetEventType()) {
+			finalEvents = append(finalEvents, event)
+		}
+	}
+	if ndErr := matchReplayWithHistory(response.Decisions, finalEvents); ndErr != nil {
+		ndErr.stackTrace = stackTrace
+		return ndErr
+	}
+	return nil
+}
+
+// completedDecisionStartedEventIDs returns the IDs of the DecisionTaskStarted events of the completed decision tasks of
+// the history, in order.
+func completedDecisionStartedEventIDs(events []*s.HistoryEvent) []int64 {
+	var startedEventIDs []int64
+	for _, event := range events {
+		if event.GetEventType() == s.EventTypeDecisionTaskCompleted && event.DecisionTaskCompletedEventAttributes != nil {
+			startedEventIDs = append(startedEventIDs, event.DecisionTaskCompletedEventAttributes.GetStartedEventId())
+		}
+	}
+	return startedEventIDs
+}
+
+func isWorkflowCloseEvent(event *s.HistoryEvent) bool {
+	switch event.GetEventType() {
+	case s.EventTypeWorkflowExecutionCompleted,
+		s.EventTypeWorkflowExecutionFailed,
+		s.EventTypeWorkflowExecutionCanceled,
+		s.EventTypeWorkflowExecutionContinuedAsNew:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/common"
+	"go.uber.org/zap"
+)
+
+const replayTestTaskList = "replayTestTaskList"
+
+func replayTestWorkflow(ctx Context) error {
+	ctx = WithActivityOptions(ctx, ActivityOptions{
+		ScheduleToStartTimeout: time.Minute,
+		StartToCloseTimeout:    time.Minute,
+	})
+	return ExecuteActivity(ctx, "replayTestActivity").Get(ctx, nil)
+}
+
+func newReplayTestWorkflowReplayer() *WorkflowReplayer {
+	replayer := NewWorkflowReplayer()
+	replayer.RegisterWorkflowWithOptions(replayTestWorkflow, RegisterWorkflowOptions{Name: "ReplayTestWorkflow"})
+	return replayer
+}
+
+// createReplayTestHistory returns the history of replayTestWorkflow, that ran the given activity and then completed.
+func createReplayTestHistory(activityType string) *s.History {
+	taskList := &s.TaskList{Name: common.StringPtr(replayTestTaskList)}
+	return &s.History{Events: []*s.HistoryEvent{
+		createTestEventWorkflowExecutionStarted(1, &s.WorkflowExecutionStartedEventAttributes{
+			WorkflowType: &s.WorkflowType{Name: common.StringPtr("ReplayTestWorkflow")},
+			TaskList:     taskList,
+		}),
+		createTestEventDecisionTaskScheduled(2, &s.DecisionTaskScheduledEventAttributes{TaskList: taskList}),
+		createTestEventDecisionTaskStarted(3),
+		createTestEventDecisionTaskCompleted(4, &s.DecisionTaskCompletedEventAttributes{
+			ScheduledEventId: common.Int64Ptr(2),
+			StartedEventId:   common.Int64Ptr(3),
+		}),
+		createTestEventActivityTaskScheduled(5, &s.ActivityTaskScheduledEventAttributes{
+			ActivityId:   common.StringPtr("0"),
+			ActivityType: &s.ActivityType{Name: common.StringPtr(activityType)},
+			TaskList:     taskList,
+		}),
+		createTestEventActivityTaskStarted(6, &s.ActivityTaskStartedEventAttributes{ScheduledEventId: common.Int64Ptr(5)}),
+		createTestEventActivityTaskCompleted(7, &s.ActivityTaskCompletedEventAttributes{
+			ScheduledEventId: common.Int64Ptr(5),
+			StartedEventId:   common.Int64Ptr(6),
+		}),
+		createTestEventDecisionTaskScheduled(8, &s.DecisionTaskScheduledEventAttributes{TaskList: taskList}),
+		createTestEventDecisionTaskStarted(9),
+		createTestEventDecisionTaskCompleted(10, &s.DecisionTaskCompletedEventAttributes{
+			ScheduledEventId: common.Int64Ptr(8),
+			StartedEventId:   common.Int64Ptr(9),
+		}),
+		{
+			EventId:   common.Int64Ptr(11),
+			EventType: common.EventTypePtr(s.EventTypeWorkflowExecutionCompleted),
+			WorkflowExecutionCompletedEventAttributes: &s.WorkflowExecutionCompletedEventAttributes{
+				DecisionTaskCompletedEventId: common.Int64Ptr(10),
+			},
+		},
+	}}
+}
+
+func TestWorkflowReplayer(t *testing.T) {
+	replayer := newReplayTestWorkflowReplayer()
+	history := createReplayTestHistory("replayTestActivity")
+	require.NoError(t, replayer.ReplayWorkflowHistory(zap.NewNop(), history))
+
+	// the history of a running workflow, the decision task in progress is not matched
+	history.Events = history.Events[:9]
+	require.NoError(t, replayer.ReplayWorkflowHistory(nil, history))
+}
+
+func TestWorkflowReplayer_NonDeterministic(t *testing.T) {
+	replayer := newReplayTestWorkflowReplayer()
+	err := replayer.ReplayWorkflowHistory(zap.NewNop(), createReplayTestHistory("otherActivity"))
+	require.Error(t, err)
+	ndErr, ok := err.(*NonDeterministicError)
+	require.True(t, ok, err.Error())
+	require.Contains(t, ndErr.Error(), "otherActivity")
+
+	// the workflow completes while the history says it failed
+	history := createReplayTestHistory("replayTestActivity")
+	history.Events[10] = &s.HistoryEvent{
+		EventId:   common.Int64Ptr(11),
+		EventType: common.EventTypePtr(s.EventTypeWorkflowExecutionFailed),
+		WorkflowExecutionFailedEventAttributes: &s.WorkflowExecutionFailedEventAttributes{
+			Reason:                       common.StringPtr("failed"),
+			DecisionTaskCompletedEventId: common.Int64Ptr(10),
+		},
+	}
+	err = replayer.ReplayWorkflowHistory(zap.NewNop(), history)
+	require.Error(t, err)
+	_, ok = err.(*NonDeterministicError)
+	require.True(t, ok, err.Error())
+	require.Contains(t, err.Error(), "CompleteWorkflowExecution")
+}
+
+func TestWorkflowReplayer_InvalidHistory(t *testing.T) {
+	replayer := newReplayTestWorkflowReplayer()
+	require.Error(t, replayer.ReplayWorkflowHistory(nil, nil))
+	require.Error(t, replayer.ReplayWorkflowHistory(nil, &s.History{}))
+
+	history := createReplayTestHistory("replayTestActivity")
+	history.Events = history.Events[1:]
+	require.Error(t, replayer.ReplayWorkflowHistory(nil, history))
+}
+
+func TestWorkflowReplayer_JSONFile(t *testing.T) {
+	content, err := json.Marshal(createReplayTestHistory("replayTestActivity"))
+	require.NoError(t, err)
+	file, err := ioutil.TempFile("", "history")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	replayer := newReplayTestWorkflowReplayer()
+	require.NoError(t, replayer.ReplayWorkflowHistoryFromJSONFile(zap.NewNop(), file.Name()))
+	require.Error(t, replayer.ReplayWorkflowHistoryFromJSONFile(zap.NewNop(), file.Name()+".missing"))
+}
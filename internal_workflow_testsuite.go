@@ -108,6 +108,7 @@ type (
 		mock          *mock.Mock
 		service       workflowserviceclient.Interface
 		workerOptions WorkerOptions
+		registry      *hostEnvImpl
 		logger        *zap.Logger
 		metricsScope  tally.Scope
 		mockClock     *clock.Mock
@@ -147,7 +148,7 @@ type (
 		changeVersions map[string]Version
 
 		workflowCancelHandler func()
-		signalHandler         func(name string, input []byte)
+		signalHandler         func(name string, input []byte, header *shared.Header)
 		queryHandler          func(string, []byte) ([]byte, error)
 
 		isTestCompleted bool
@@ -162,6 +163,7 @@ func newTestWorkflowEnvironmentImpl(s *WorkflowTestSuite) *testWorkflowEnvironme
 		testWorkflowEnvironmentShared: &testWorkflowEnvironmentShared{
 			testSuite:                  s,
 			taskListSpecificActivities: make(map[string]*taskListSpecificActivity),
+			registry:                   newRegistry(),
 
 			logger:          s.logger,
 			metricsScope:    s.scope,
@@ -279,6 +281,21 @@ func (env *testWorkflowEnvironmentImpl) setWorkerOptions(options WorkerOptions)
 	if options.MetricsScope != nil {
 		env.workerOptions.MetricsScope = options.MetricsScope
 	}
+	if len(options.WorkflowInterceptors) > 0 {
+		env.workerOptions.WorkflowInterceptors = options.WorkflowInterceptors
+	}
+	if len(options.ActivityInterceptors) > 0 {
+		env.workerOptions.ActivityInterceptors = options.ActivityInterceptors
+	}
+	if options.Tracer != nil {
+		env.workerOptions.Tracer = options.Tracer
+	}
+	if options.DeadlockDetectionTimeout != 0 {
+		env.workerOptions.DeadlockDetectionTimeout = options.DeadlockDetectionTimeout
+	}
+	if options.DisableDeadlockDetection {
+		env.workerOptions.DisableDeadlockDetection = true
+	}
 }
 
 func (env *testWorkflowEnvironmentImpl) setActivityTaskList(tasklist string, activityFns ...interface{}) {
@@ -301,7 +318,7 @@ func (env *testWorkflowEnvironmentImpl) executeWorkflow(workflowFn interface{},
 		workflowType = workflowFn.(string)
 	case reflect.Func:
 		workflowType = getFunctionName(workflowFn)
-		if alias, ok := getHostEnvironment().getWorkflowAlias(workflowType); ok {
+		if alias, ok := env.registry.getWorkflowAlias(workflowType); ok {
 			workflowType = alias
 		}
 	default:
@@ -312,10 +329,11 @@ func (env *testWorkflowEnvironmentImpl) executeWorkflow(workflowFn interface{},
 	if err != nil {
 		panic(err)
 	}
-	env.executeWorkflowInternal(workflowType, input)
+	env.executeWorkflowInternal(workflowType, nil, input)
 }
 
-func (env *testWorkflowEnvironmentImpl) executeWorkflowInternal(workflowType string, input []byte) {
+func (env *testWorkflowEnvironmentImpl) executeWorkflowInternal(workflowType string, header *shared.Header,
+	input []byte) {
 	env.workflowInfo.WorkflowType.Name = workflowType
 	workflowDefinition, err := env.getWorkflowDefinition(env.workflowInfo.WorkflowType)
 	if err != nil {
@@ -326,16 +344,15 @@ func (env *testWorkflowEnvironmentImpl) executeWorkflowInternal(workflowType str
 	// In case of child workflow, this executeWorkflowInternal() is run in separate goroutinue, so use postCallback
 	// to make sure workflowDef.Execute() is run in main loop.
 	env.postCallback(func() {
-		env.workflowDef.Execute(env, input)
+		env.workflowDef.Execute(env, header, input)
 	}, false)
 	env.startMainLoop()
 }
 
 func (env *testWorkflowEnvironmentImpl) getWorkflowDefinition(wt WorkflowType) (workflowDefinition, error) {
-	hostEnv := getHostEnvironment()
-	wf, ok := hostEnv.getWorkflowFn(wt.Name)
+	wf, ok := env.registry.getWorkflowFn(wt.Name)
 	if !ok {
-		supported := strings.Join(hostEnv.getRegisteredWorkflowTypes(), ", ")
+		supported := strings.Join(env.registry.getRegisteredWorkflowTypes(), ", ")
 		return nil, fmt.Errorf("Unable to find workflow type: %v. Supported types: [%v]", wt.Name, supported)
 	}
 	wd := &workflowExecutorWrapper{
@@ -349,7 +366,7 @@ func (env *testWorkflowEnvironmentImpl) executeActivity(
 	activityFn interface{},
 	args ...interface{},
 ) (EncodedValue, error) {
-	fnName := getFunctionName(activityFn)
+	fnName := getActivityFunctionName(env.registry, activityFn)
 
 	input, err := getHostEnvironment().encodeArgs(args)
 	if err != nil {
@@ -638,6 +655,23 @@ func (env *testWorkflowEnvironmentImpl) GetMetricsScope() tally.Scope {
 	return env.workerOptions.MetricsScope
 }
 
+func (env *testWorkflowEnvironmentImpl) IsReplaying() bool {
+	// this test environment never replays history
+	return false
+}
+
+func (env *testWorkflowEnvironmentImpl) GetWorkflowInterceptors() []WorkflowInterceptorFactory {
+	return getWorkflowInterceptorFactories(env.workerOptions)
+}
+
+func (env *testWorkflowEnvironmentImpl) GetDeadlockDetectionTimeout() time.Duration {
+	return getDeadlockDetectionTimeout(fillWorkerOptionsDefaults(env.workerOptions))
+}
+
+func (env *testWorkflowEnvironmentImpl) GetRegistry() *hostEnvImpl {
+	return env.registry
+}
+
 func (env *testWorkflowEnvironmentImpl) ExecuteActivity(parameters executeActivityParameters, callback resultHandler) *activityInfo {
 	var activityID string
 	if parameters.ActivityID == nil || *parameters.ActivityID == "" {
@@ -646,6 +680,17 @@ func (env *testWorkflowEnvironmentImpl) ExecuteActivity(parameters executeActivi
 		activityID = *parameters.ActivityID
 	}
 	activityInfo := &activityInfo{activityID: activityID}
+	if parameters.ActivityType.Name == sessionCreationActivityName {
+		// There is no session worker in the test environment, the session is created on this host right away and
+		// runs until the workflow completes it.
+		env.activities[activityID] = &testActivityHandle{callback: callback, activityType: parameters.ActivityType.Name}
+		taskList := strings.TrimSuffix(parameters.TaskListName, sessionCreationTaskListSuffix)
+		env.signalWorkflow(activityID, sessionCreationResponse{
+			TaskList: getSessionResourceTaskList(taskList),
+			HostName: getHostName(),
+		})
+		return activityInfo
+	}
 	task := newTestActivityTask(
 		defaultTestWorkflowID,
 		defaultTestRunID,
@@ -910,15 +955,16 @@ func (m *mockWrapper) executeMock(ctx interface{}, input []byte, mockRet mock.Ar
 func (env *testWorkflowEnvironmentImpl) newTestActivityTaskHandler(taskList string) ActivityTaskHandler {
 	wOptions := fillWorkerOptionsDefaults(env.workerOptions)
 	params := workerExecutionParameters{
-		TaskList:     taskList,
-		Identity:     wOptions.Identity,
-		MetricsScope: wOptions.MetricsScope,
-		Logger:       wOptions.Logger,
-		UserContext:  wOptions.BackgroundActivityContext,
+		TaskList:             taskList,
+		Identity:             wOptions.Identity,
+		MetricsScope:         wOptions.MetricsScope,
+		Logger:               wOptions.Logger,
+		UserContext:          wOptions.BackgroundActivityContext,
+		ActivityInterceptors: getActivityInterceptorFactories(wOptions),
 	}
 	ensureRequiredParams(&params)
 
-	if len(getHostEnvironment().getRegisteredActivities()) == 0 {
+	if len(env.registry.getRegisteredActivities()) == 0 {
 		panic(fmt.Sprintf("no activity is registered for tasklist '%v'", taskList))
 	}
 
@@ -932,7 +978,7 @@ func (env *testWorkflowEnvironmentImpl) newTestActivityTaskHandler(taskList stri
 			}
 		}
 
-		activity, ok := getHostEnvironment().getActivity(name)
+		activity, ok := env.registry.getActivity(name)
 		if !ok {
 			return nil
 		}
@@ -940,7 +986,7 @@ func (env *testWorkflowEnvironmentImpl) newTestActivityTaskHandler(taskList stri
 		return &activityExecutorWrapper{activityExecutor: ae, env: env}
 	}
 
-	taskHandler := newActivityTaskHandlerWithCustomProvider(env.service, params, getHostEnvironment(), getActivity)
+	taskHandler := newActivityTaskHandlerWithCustomProvider(env.service, env.workflowInfo.Domain, params, env.registry, getActivity)
 	return taskHandler
 }
 
@@ -958,6 +1004,8 @@ func newTestActivityTask(workflowID, runID, activityID string, params executeAct
 		ScheduleToCloseTimeoutSeconds: common.Int32Ptr(params.ScheduleToCloseTimeoutSeconds),
 		StartedTimestamp:              common.Int64Ptr(time.Now().UnixNano()),
 		StartToCloseTimeoutSeconds:    common.Int32Ptr(params.StartToCloseTimeoutSeconds),
+		HeartbeatTimeoutSeconds:       common.Int32Ptr(params.HeartbeatTimeoutSeconds),
+		Header:                        params.Header,
 	}
 	return task
 }
@@ -1005,7 +1053,8 @@ func (env *testWorkflowEnvironmentImpl) RegisterCancelHandler(handler func()) {
 	env.workflowCancelHandler = handler
 }
 
-func (env *testWorkflowEnvironmentImpl) RegisterSignalHandler(handler func(name string, input []byte)) {
+func (env *testWorkflowEnvironmentImpl) RegisterSignalHandler(
+	handler func(name string, input []byte, header *shared.Header)) {
 	env.signalHandler = handler
 }
 
@@ -1042,7 +1091,7 @@ func (env *testWorkflowEnvironmentImpl) ExecuteChildWorkflow(options workflowOpt
 	env.runningCount.Inc()
 
 	// run child workflow in separate goroutinue
-	go childEnv.executeWorkflowInternal(options.workflowType.Name, options.input)
+	go childEnv.executeWorkflowInternal(options.workflowType.Name, options.header, options.input)
 
 	return nil
 }
@@ -1076,6 +1125,7 @@ func (env *testWorkflowEnvironmentImpl) getActivityInfo(activityID, activityType
 		ActivityType:      ActivityType{Name: activityType},
 		TaskToken:         []byte(activityID),
 		WorkflowExecution: env.workflowInfo.WorkflowExecution,
+		WorkflowDomain:    env.workflowInfo.Domain,
 	}
 }
 
@@ -1094,7 +1144,7 @@ func (env *testWorkflowEnvironmentImpl) signalWorkflow(name string, input interf
 		panic(err)
 	}
 	env.postCallback(func() {
-		env.signalHandler(name, data)
+		env.signalHandler(name, data, nil)
 	}, true)
 }
 
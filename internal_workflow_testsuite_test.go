@@ -24,10 +24,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/cadence/.gen/go/shared"
@@ -242,6 +244,262 @@ func (s *WorkflowTestSuiteUnitTest) Test_WorkflowMixedClock() {
 	s.Equal("expected", result)
 }
 
+func (s *WorkflowTestSuiteUnitTest) Test_ResettableTimer() {
+	workflowFn := func(ctx Context) (time.Duration, error) {
+		startTime := Now(ctx)
+		timer := NewResettableTimer(ctx, 10*time.Minute)
+		signalCh := GetSignalChannel(ctx, "reset")
+		for !timer.IsReady() {
+			NewSelector(ctx).AddReceive(signalCh, func(c Channel, more bool) {
+				var d time.Duration
+				c.Receive(ctx, &d)
+				timer.Reset(d)
+			}).AddFuture(timer, func(f Future) {
+				// inactivity timeout
+			}).Select(ctx)
+		}
+		if err := timer.Get(ctx, nil); err != nil {
+			return 0, err
+		}
+		return Now(ctx).Sub(startTime), nil
+	}
+
+	RegisterWorkflow(workflowFn)
+	env := s.NewTestWorkflowEnvironment()
+	var scheduledTimers []time.Duration
+	var cancelledTimers int
+	env.SetOnTimerScheduledListener(func(timerID string, duration time.Duration) {
+		scheduledTimers = append(scheduledTimers, duration)
+	})
+	env.SetOnTimerCancelledListener(func(timerID string) {
+		cancelledTimers++
+	})
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("reset", 10*time.Minute) // extends deadline to 15m
+	}, 5*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("reset", 10*time.Minute) // extends deadline to 22m
+	}, 12*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("reset", time.Minute) // shortens deadline to 17m
+	}, 16*time.Minute)
+	env.ExecuteWorkflow(workflowFn)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var elapsed time.Duration
+	env.GetWorkflowResult(&elapsed)
+	s.Equal(17*time.Minute, elapsed)
+	// extending the deadline re-arms the pending timer when it fires, only shortening it cancels the pending timer.
+	s.Equal([]time.Duration{10 * time.Minute, 5 * time.Minute, 7 * time.Minute, time.Minute}, scheduledTimers)
+	s.Equal(1, cancelledTimers)
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_ResettableTimerStop() {
+	workflowFn := func(ctx Context) error {
+		timer := NewResettableTimer(ctx, time.Hour)
+		NewTimer(ctx, time.Minute).Get(ctx, nil)
+		timer.Stop()
+		timer.Reset(time.Minute) // no-op after stop
+		return timer.Get(ctx, nil)
+	}
+
+	RegisterWorkflow(workflowFn)
+	env := s.NewTestWorkflowEnvironment()
+	env.ExecuteWorkflow(workflowFn)
+
+	s.True(env.IsWorkflowCompleted())
+	_, ok := env.GetWorkflowError().(*CanceledError)
+	s.True(ok)
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_Ticker() {
+	workflowFn := func(ctx Context) ([]time.Duration, error) {
+		startTime := Now(ctx)
+		ticker := NewTicker(ctx, 10*time.Minute)
+		var ticks []time.Duration
+		var tickTime time.Time
+		for ticker.Chan().Receive(ctx, &tickTime) {
+			ticks = append(ticks, tickTime.Sub(startTime))
+			if len(ticks) == 3 {
+				ticker.Stop()
+			}
+		}
+		return ticks, nil
+	}
+
+	RegisterWorkflow(workflowFn)
+	env := s.NewTestWorkflowEnvironment()
+	// start the workflow at a time that is not a multiple of the interval.
+	env.impl.mockClock.Add(7*time.Minute + 13*time.Second)
+	env.ExecuteWorkflow(workflowFn)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var ticks []time.Duration
+	env.GetWorkflowResult(&ticks)
+	s.Equal([]time.Duration{10 * time.Minute, 20 * time.Minute, 30 * time.Minute}, ticks)
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_TickerWithStart() {
+	workflowFn := func(ctx Context, tickerStart time.Time) ([]time.Duration, error) {
+		startTime := Now(ctx)
+		ticker := NewTickerWithStart(ctx, tickerStart, 10*time.Minute)
+		var ticks []time.Duration
+		var tickTime time.Time
+		for ticker.Chan().Receive(ctx, &tickTime) {
+			ticks = append(ticks, tickTime.Sub(startTime))
+			if len(ticks) == 2 {
+				ticker.Stop()
+			}
+		}
+		return ticks, nil
+	}
+
+	RegisterWorkflow(workflowFn)
+	// the workflow resumes 3 minutes into the interval of a ticker started by a previous run.
+	env := s.NewTestWorkflowEnvironment()
+	env.ExecuteWorkflow(workflowFn, env.impl.mockClock.Now().Add(-23*time.Minute))
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var ticks []time.Duration
+	env.GetWorkflowResult(&ticks)
+	s.Equal([]time.Duration{7 * time.Minute, 17 * time.Minute}, ticks)
+
+	// a start time in the future only sets the phase of the ticks.
+	env = s.NewTestWorkflowEnvironment()
+	env.ExecuteWorkflow(workflowFn, env.impl.mockClock.Now().Add(25*time.Minute))
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.GetWorkflowResult(&ticks)
+	s.Equal([]time.Duration{5 * time.Minute, 15 * time.Minute}, ticks)
+}
+
+type testWorkflowInterceptorFactory struct {
+	calls             []string
+	forbiddenActivity string
+}
+
+type testWorkflowInterceptor struct {
+	WorkflowInterceptorBase
+	factory *testWorkflowInterceptorFactory
+}
+
+func (f *testWorkflowInterceptorFactory) NewInterceptor(info *WorkflowInfo, next WorkflowInterceptor) WorkflowInterceptor {
+	return &testWorkflowInterceptor{WorkflowInterceptorBase: WorkflowInterceptorBase{Next: next}, factory: f}
+}
+
+func (i *testWorkflowInterceptor) ExecuteWorkflow(ctx Context, workflowType string, input []byte) ([]byte, error) {
+	i.factory.calls = append(i.factory.calls, "ExecuteWorkflow")
+	return i.Next.ExecuteWorkflow(ctx, workflowType, input)
+}
+
+func (i *testWorkflowInterceptor) ExecuteActivity(ctx Context, activityType string, args ...interface{}) Future {
+	i.factory.calls = append(i.factory.calls, "ExecuteActivity:"+activityType)
+	if activityType == i.factory.forbiddenActivity {
+		future, settable := NewFuture(ctx)
+		settable.SetError(NewCustomError("forbidden-activity"))
+		return future
+	}
+	return i.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+func (i *testWorkflowInterceptor) NewTimer(ctx Context, d time.Duration) Future {
+	i.factory.calls = append(i.factory.calls, "NewTimer")
+	return i.Next.NewTimer(ctx, d)
+}
+
+func (i *testWorkflowInterceptor) HandleSignal(ctx Context, signalName string, input EncodedValue) {
+	i.factory.calls = append(i.factory.calls, "HandleSignal:"+signalName)
+	i.Next.HandleSignal(ctx, signalName, input)
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_WorkflowInterceptor() {
+	workflowFn := func(ctx Context) (string, error) {
+		ctx = WithActivityOptions(ctx, s.activityOptions)
+		var result string
+		if err := ExecuteActivity(ctx, testActivityHello, "interceptor").Get(ctx, &result); err != nil {
+			return "", err
+		}
+		if err := NewTimer(ctx, time.Minute).Get(ctx, nil); err != nil {
+			return "", err
+		}
+		GetSignalChannel(ctx, "signal").Receive(ctx, nil)
+		err := ExecuteActivity(ctx, testActivityHeartbeat, "forbidden", time.Second).Get(ctx, nil)
+		if customErr, ok := err.(*CustomError); !ok || customErr.Reason() != "forbidden-activity" {
+			return "", errors.New("expected forbidden activity to be blocked")
+		}
+		return result, nil
+	}
+
+	RegisterWorkflow(workflowFn)
+	factory := &testWorkflowInterceptorFactory{forbiddenActivity: getFunctionName(testActivityHeartbeat)}
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(WorkerOptions{WorkflowInterceptors: []WorkflowInterceptorFactory{factory}})
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("signal", "signal-input")
+	}, 2*time.Minute)
+	env.ExecuteWorkflow(workflowFn)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var result string
+	env.GetWorkflowResult(&result)
+	s.Equal("hello_interceptor", result)
+	s.Equal([]string{
+		"ExecuteWorkflow",
+		"ExecuteActivity:testActivityHello",
+		"NewTimer",
+		"HandleSignal:signal",
+		"ExecuteActivity:" + getFunctionName(testActivityHeartbeat),
+	}, factory.calls)
+}
+
+func testWorkerOnlyActivity(ctx context.Context) (string, error) {
+	return "activity", nil
+}
+
+func testWorkerOnlyChildWorkflow(ctx Context) (string, error) {
+	return "child", nil
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_WorkflowInterceptorResultDecoding() {
+	workflowFn := func(ctx Context) ([]bool, error) {
+		ctx = WithActivityOptions(ctx, s.activityOptions)
+		ctx = WithChildWorkflowOptions(ctx, ChildWorkflowOptions{ExecutionStartToCloseTimeout: time.Minute})
+		var result string
+		activityFuture := ExecuteActivity(ctx, testWorkerOnlyActivity)
+		if err := activityFuture.Get(ctx, &result); err != nil {
+			return nil, err
+		}
+		childFuture := ExecuteChildWorkflow(ctx, testWorkerOnlyChildWorkflow)
+		if err := childFuture.Get(ctx, &result); err != nil {
+			return nil, err
+		}
+		// the results are decoded with the functions registered with the environment only.
+		_, activityByName := activityFuture.(*decodeFutureImpl).fn.(string)
+		_, childByName := childFuture.(childWorkflowFutureImpl).fn.(string)
+		return []bool{activityByName, childByName}, nil
+	}
+
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(workflowFn)
+	env.RegisterWorkflow(testWorkerOnlyChildWorkflow)
+	env.RegisterActivity(testWorkerOnlyActivity)
+	env.SetWorkerOptions(WorkerOptions{
+		WorkflowInterceptors: []WorkflowInterceptorFactory{&testWorkflowInterceptorFactory{}},
+	})
+	env.ExecuteWorkflow(workflowFn)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var byName []bool
+	env.GetWorkflowResult(&byName)
+	s.Equal([]bool{false, false}, byName)
+}
+
 func (s *WorkflowTestSuiteUnitTest) Test_WorkflowActivityCancellation() {
 	workflowFn := func(ctx Context) error {
 		ctx = WithActivityOptions(ctx, s.activityOptions)
@@ -313,6 +571,180 @@ func (s *WorkflowTestSuiteUnitTest) Test_ActivityWithUserContext() {
 	s.Equal(testValue, value)
 }
 
+type testActivityInterceptorFactory struct {
+	name  string
+	calls *[]string
+}
+
+type testActivityInterceptor struct {
+	ActivityInterceptorBase
+	factory *testActivityInterceptorFactory
+	info    *ActivityInfo
+}
+
+func (f *testActivityInterceptorFactory) NewInterceptor(info *ActivityInfo, next ActivityInterceptor) ActivityInterceptor {
+	return &testActivityInterceptor{ActivityInterceptorBase: ActivityInterceptorBase{Next: next}, factory: f, info: info}
+}
+
+func (i *testActivityInterceptor) ExecuteActivity(ctx context.Context, activityType string, args ...interface{}) (result interface{}, err error) {
+	*i.factory.calls = append(*i.factory.calls, fmt.Sprintf("%v:ExecuteActivity:%v:%v", i.factory.name, i.info.ActivityType.Name, args))
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, NewCustomError("activity-panic", fmt.Sprintf("%v", p))
+		}
+	}()
+	result, err = i.Next.ExecuteActivity(ctx, activityType, args...)
+	*i.factory.calls = append(*i.factory.calls, fmt.Sprintf("%v:Result:%v", i.factory.name, result))
+	return result, err
+}
+
+func (i *testActivityInterceptor) RecordHeartbeat(ctx context.Context, details ...interface{}) {
+	*i.factory.calls = append(*i.factory.calls, fmt.Sprintf("%v:RecordHeartbeat:%v", i.factory.name, details))
+	i.Next.RecordHeartbeat(ctx, details...)
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_ActivityInterceptor() {
+	activityFn := func(ctx context.Context, msg string) (string, error) {
+		RecordActivityHeartbeat(ctx, "progress")
+		if msg == "panic" {
+			panic("activity panic")
+		}
+		return "hello_" + msg, nil
+	}
+	RegisterActivity(activityFn)
+	activityType := getFunctionName(activityFn)
+
+	var calls []string
+	env := s.NewTestActivityEnvironment()
+	env.SetWorkerOptions(WorkerOptions{ActivityInterceptors: []ActivityInterceptorFactory{
+		&testActivityInterceptorFactory{name: "outer", calls: &calls},
+		&testActivityInterceptorFactory{name: "inner", calls: &calls},
+	}})
+	blob, err := env.ExecuteActivity(activityFn, "interceptor")
+	s.NoError(err)
+	var result string
+	blob.Get(&result)
+	s.Equal("hello_interceptor", result)
+	s.Equal([]string{
+		"outer:ExecuteActivity:" + activityType + ":[interceptor]",
+		"inner:ExecuteActivity:" + activityType + ":[interceptor]",
+		"outer:RecordHeartbeat:[progress]",
+		"inner:RecordHeartbeat:[progress]",
+		"inner:Result:hello_interceptor",
+		"outer:Result:hello_interceptor",
+	}, calls)
+
+	_, err = env.ExecuteActivity(activityFn, "panic")
+	customErr, ok := err.(*CustomError)
+	s.True(ok)
+	s.Equal("activity-panic", customErr.Reason())
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_Tracing() {
+	workflowFn := func(ctx Context) (string, error) {
+		ctx = WithActivityOptions(ctx, s.activityOptions)
+		var activityResult string
+		err := ExecuteActivity(ctx, testActivityHello, "activity").Get(ctx, &activityResult)
+		if err != nil {
+			return "", err
+		}
+
+		cwo := ChildWorkflowOptions{ExecutionStartToCloseTimeout: time.Minute}
+		ctx = WithChildWorkflowOptions(ctx, cwo)
+		var childResult string
+		err = ExecuteChildWorkflow(ctx, testWorkflowHello).Get(ctx, &childResult)
+		return activityResult + " " + childResult, err
+	}
+	RegisterWorkflow(workflowFn)
+	workflowType := getFunctionName(workflowFn)
+
+	tracer := mocktracer.New()
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(WorkerOptions{Tracer: tracer})
+	env.ExecuteWorkflow(workflowFn)
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var result string
+	env.GetWorkflowResult(&result)
+	s.Equal("hello_activity hello_world", result)
+
+	// every span is reported as "operation<-parent operation", the parents are found by the span ID the mocktracer
+	// recorded as the parent ID of the span.
+	spans := tracer.FinishedSpans()
+	spansByID := map[int]*mocktracer.MockSpan{}
+	for _, span := range spans {
+		spansByID[span.SpanContext.SpanID] = span
+	}
+	var root *mocktracer.MockSpan
+	var relations []string
+	for _, span := range spans {
+		if span.ParentID == 0 {
+			relations = append(relations, span.OperationName+"<-")
+			root = span
+			continue
+		}
+		parent, ok := spansByID[span.ParentID]
+		s.True(ok, "parent of %v not found", span.OperationName)
+		relations = append(relations, span.OperationName+"<-"+parent.OperationName)
+
+		// a span is the child of a span of its own workflow execution, except the child workflow execution span
+		// which is the child of the StartChildWorkflow span of the parent workflow.
+		if span.OperationName == "RunWorkflow:testWorkflowHello" {
+			s.Equal(env.impl.workflowInfo.WorkflowExecution.ID, parent.Tag(tagWorkflowID))
+			s.NotEqual(span.Tag(tagWorkflowID), parent.Tag(tagWorkflowID))
+		} else {
+			s.Equal(span.Tag(tagWorkflowID), parent.Tag(tagWorkflowID))
+		}
+	}
+	expected := []string{
+		"RunWorkflow:" + workflowType + "<-",
+		"StartActivity:testActivityHello<-RunWorkflow:" + workflowType,
+		"RunActivity:testActivityHello<-StartActivity:testActivityHello",
+		"StartChildWorkflow:testWorkflowHello<-RunWorkflow:" + workflowType,
+		"RunWorkflow:testWorkflowHello<-StartChildWorkflow:testWorkflowHello",
+		"StartActivity:testActivityHello<-RunWorkflow:testWorkflowHello",
+		"RunActivity:testActivityHello<-StartActivity:testActivityHello",
+	}
+	sort.Strings(expected)
+	sort.Strings(relations)
+	s.Equal(expected, relations)
+
+	// all the spans belong to the trace of the workflow execution.
+	s.NotNil(root)
+	for _, span := range spans {
+		s.Equal(root.SpanContext.TraceID, span.SpanContext.TraceID)
+	}
+
+	// the spans of each workflow execution and of its activities are correlated by the workflow ID tag.
+	spanCounts := map[string]int{}
+	for _, span := range spans {
+		workflowID, _ := span.Tag(tagWorkflowID).(string)
+		s.NotEmpty(workflowID)
+		spanCounts[workflowID]++
+	}
+	s.Len(spanCounts, 2)
+	s.Equal(4, spanCounts[env.impl.workflowInfo.WorkflowExecution.ID])
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_DeadlockDetection() {
+	blocked := make(chan struct{})
+	defer close(blocked)
+	workflowFn := func(ctx Context) error {
+		<-blocked // native channel doesn't yield
+		return nil
+	}
+	RegisterWorkflow(workflowFn)
+
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(WorkerOptions{DeadlockDetectionTimeout: 100 * time.Millisecond})
+	env.ExecuteWorkflow(workflowFn)
+	s.True(env.IsWorkflowCompleted())
+	panicErr, ok := env.GetWorkflowError().(*PanicError)
+	s.True(ok)
+	s.Contains(panicErr.Error(), "Potential deadlock detected")
+	s.Contains(panicErr.StackTrace(), "[deadlocked]")
+}
+
 func (s *WorkflowTestSuiteUnitTest) Test_CompleteActivity() {
 	env := s.NewTestWorkflowEnvironment()
 	var activityInfo ActivityInfo
@@ -338,6 +770,29 @@ func (s *WorkflowTestSuiteUnitTest) Test_CompleteActivity() {
 	s.Equal("async_complete", result)
 }
 
+func (s *WorkflowTestSuiteUnitTest) Test_ActivityInfo() {
+	env := s.NewTestWorkflowEnvironment()
+	var activityInfo ActivityInfo
+	var ctxDeadline time.Time
+	mockActivity := func(ctx context.Context, msg string) (string, error) {
+		activityInfo = GetActivityInfo(ctx)
+		ctxDeadline, _ = ctx.Deadline()
+		return "hello_" + msg, nil
+	}
+	env.OnActivity(testActivityHello, mock.Anything, mock.Anything).Return(mockActivity).Once()
+
+	env.ExecuteWorkflow(testWorkflowHello)
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	s.Equal(20*time.Second, activityInfo.HeartbeatTimeout)
+	s.False(activityInfo.ScheduledTimestamp.IsZero())
+	s.False(activityInfo.StartedTimestamp.Before(activityInfo.ScheduledTimestamp))
+	// The start to close timeout is shorter than the default schedule to close timeout.
+	s.Equal(activityInfo.StartedTimestamp.Add(time.Minute), activityInfo.Deadline)
+	s.Equal(activityInfo.Deadline, ctxDeadline)
+}
+
 func (s *WorkflowTestSuiteUnitTest) Test_WorkflowCancellation() {
 	workflowFn := func(ctx Context) error {
 		ctx = WithActivityOptions(ctx, s.activityOptions)
@@ -983,6 +1438,133 @@ func (s *WorkflowTestSuiteUnitTest) Test_ActivityFriendlyName() {
 	s.Equal("foo", called[1])
 }
 
+func (s *WorkflowTestSuiteUnitTest) Test_EnvironmentRegistry() {
+	workflowFn := func(ctx Context) (string, error) {
+		ctx = WithActivityOptions(ctx, s.activityOptions)
+		var result string
+		err := ExecuteActivity(ctx, "registryActivity", "registry").Get(ctx, &result)
+		return result, err
+	}
+	helloFn := func(msg string) (string, error) {
+		return "hello_" + msg, nil
+	}
+	byeFn := func(msg string) (string, error) {
+		return "bye_" + msg, nil
+	}
+
+	// Both environments host their own implementation of the same activity type.
+	helloEnv := s.NewTestWorkflowEnvironment()
+	helloEnv.RegisterWorkflowWithOptions(workflowFn, RegisterWorkflowOptions{Name: "registryWorkflow"})
+	helloEnv.RegisterActivityWithOptions(helloFn, RegisterActivityOptions{Name: "registryActivity"})
+	byeEnv := s.NewTestWorkflowEnvironment()
+	byeEnv.RegisterWorkflowWithOptions(workflowFn, RegisterWorkflowOptions{Name: "registryWorkflow"})
+	byeEnv.RegisterActivityWithOptions(byeFn, RegisterActivityOptions{Name: "registryActivity"})
+
+	var result string
+	helloEnv.ExecuteWorkflow("registryWorkflow")
+	s.True(helloEnv.IsWorkflowCompleted())
+	s.NoError(helloEnv.GetWorkflowError())
+	s.NoError(helloEnv.GetWorkflowResult(&result))
+	s.Equal("hello_registry", result)
+
+	byeEnv.ExecuteWorkflow(workflowFn)
+	s.True(byeEnv.IsWorkflowCompleted())
+	s.NoError(byeEnv.GetWorkflowError())
+	s.NoError(byeEnv.GetWorkflowResult(&result))
+	s.Equal("bye_registry", result)
+
+	// The registrations don't leak into the other environments or the global registry.
+	s.Panics(func() { s.NewTestWorkflowEnvironment().ExecuteWorkflow("registryWorkflow") })
+	_, ok := getHostEnvironment().getActivity("registryActivity")
+	s.False(ok)
+
+	activityEnv := s.NewTestActivityEnvironment()
+	activityEnv.RegisterActivity(helloFn)
+	value, err := activityEnv.ExecuteActivity(helloFn, "activity")
+	s.NoError(err)
+	s.NoError(value.Get(&result))
+	s.Equal("hello_activity", result)
+}
+
+type testActivityStruct struct {
+	greeting string
+}
+
+func (a *testActivityStruct) Greet(ctx context.Context, name string) (string, error) {
+	return a.greeting + "_" + name, nil
+}
+
+func (a *testActivityStruct) Farewell(name string) (string, error) {
+	return "bye_" + name, nil
+}
+
+func (a *testActivityStruct) Greeting() string {
+	return a.greeting
+}
+
+func (s *WorkflowTestSuiteUnitTest) Test_ActivityStruct() {
+	workflowFn := func(ctx Context, activities []interface{}) ([]string, error) {
+		ctx = WithActivityOptions(ctx, s.activityOptions)
+		var results []string
+		for _, activity := range activities {
+			var result string
+			if err := ExecuteActivity(ctx, activity, "world").Get(ctx, &result); err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+	executeWorkflow := func(env *TestWorkflowEnvironment, activities ...interface{}) ([]string, []string) {
+		var called []string
+		env.SetOnActivityStartedListener(func(activityInfo *ActivityInfo, ctx context.Context, args EncodedValues) {
+			called = append(called, activityInfo.ActivityType.Name)
+		})
+		env.ExecuteWorkflow(workflowFn, activities)
+		s.True(env.IsWorkflowCompleted())
+		s.NoError(env.GetWorkflowError())
+		var results []string
+		s.NoError(env.GetWorkflowResult(&results))
+		return results, called
+	}
+
+	// Without a prefix, the activities are named after the method expressions.
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(workflowFn)
+	env.RegisterActivity(&testActivityStruct{greeting: "hi"})
+	_, ok := env.impl.registry.getActivity(getFunctionName((*testActivityStruct).Greeting))
+	s.False(ok)
+	results, called := executeWorkflow(env,
+		(*testActivityStruct).Greet, (&testActivityStruct{}).Greet, (*testActivityStruct).Farewell)
+	s.Equal([]string{"hi_world", "hi_world", "bye_world"}, results)
+	greetName := getFunctionName((*testActivityStruct).Greet)
+	farewellName := getFunctionName((*testActivityStruct).Farewell)
+	s.Equal([]string{greetName, greetName, farewellName}, called)
+	s.Panics(func() { env.RegisterActivity(&struct{}{}) })
+
+	// With a prefix, the activities are executed by name.
+	env = s.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(workflowFn)
+	env.RegisterActivityWithOptions(&testActivityStruct{greeting: "hello"}, RegisterActivityOptions{Name: "greeter."})
+	results, called = executeWorkflow(env, "greeter.Greet", "greeter.Farewell")
+	s.Equal([]string{"hello_world", "bye_world"}, results)
+	s.Equal([]string{"greeter.Greet", "greeter.Farewell"}, called)
+
+	// The calling side derives the name from the method itself, so it doesn't need the struct registered.
+	caller := newHostEnvironment()
+	name, err := getValidatedActivityType(caller, (*testActivityStruct).Greet, []interface{}{"world"})
+	s.NoError(err)
+	s.Equal(greetName, name)
+	name, err = getValidatedActivityType(caller, (&testActivityStruct{}).Farewell, []interface{}{"world"})
+	s.NoError(err)
+	s.Equal(farewellName, name)
+	// The receiver of a method expression isn't one of the activity arguments.
+	_, err = getValidatedActivityType(caller, (*testActivityStruct).Greet, []interface{}{1})
+	s.Error(err)
+	_, err = getValidatedActivityType(caller, (*testActivityStruct).Farewell, []interface{}{"world", "again"})
+	s.Error(err)
+}
+
 func (s *WorkflowTestSuiteUnitTest) Test_WorkflowFriendlyName() {
 
 	workflowFn := func(ctx Context) error {
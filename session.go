@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"time"
+)
+
+type (
+	// SessionOptions specifies how a session is created.
+	SessionOptions struct {
+		// ExecutionTimeout - The maximum time the session can run. The session fails once it expires.
+		// Mandatory: No default.
+		ExecutionTimeout time.Duration
+
+		// CreationTimeout - How long CreateSession waits for a worker to accept the session.
+		// Mandatory: No default.
+		CreationTimeout time.Duration
+
+		// HeartbeatTimeout - How long it takes to detect that the worker host running the session is gone.
+		// Optional: default 20s.
+		HeartbeatTimeout time.Duration
+	}
+
+	// SessionInfo contains information about a session.
+	SessionInfo struct {
+		SessionID    string
+		HostName     string // Host name of the worker running the activities of the session.
+		SessionState SessionState
+	}
+
+	// SessionState is the state of a session.
+	SessionState int
+)
+
+const (
+	// SessionStateOpen means that the activities executed in the session run on the worker host of the session.
+	SessionStateOpen SessionState = iota
+	// SessionStateFailed means that the session has failed, the activities executed in the session fail with
+	// ErrSessionFailed.
+	SessionStateFailed
+	// SessionStateClosed means that the session was completed by CompleteSession, the activities executed in the
+	// session fail with ErrSessionClosed.
+	SessionStateClosed
+)
+
+// CreateSession creates a session and returns a context for it. The activities executed with the session context all
+// run on the same worker host, which must be started with WorkerOptions.EnableSessionWorker. For example:
+//  sessionCtx, err := CreateSession(ctx, SessionOptions{ExecutionTimeout: time.Hour, CreationTimeout: time.Minute})
+//  if err != nil {
+//      return err
+//  }
+//  defer CompleteSession(sessionCtx)
+//  var fileName string
+//  err = ExecuteActivity(sessionCtx, downloadFile, url).Get(sessionCtx, &fileName)
+//  ...
+//  err = ExecuteActivity(sessionCtx, processFile, fileName).Get(sessionCtx, nil)
+// The session is accepted by a worker polling the task list of the activities of ctx, and counts towards the
+// WorkerOptions.MaxConcurrentSessionExecutionSize of that worker until it is completed. CreateSession returns an error
+// when no worker accepts the session within the CreationTimeout, or when ctx already has an open session.
+// The session fails when its worker host stops heartbeating for it, or when it runs longer than its ExecutionTimeout.
+// GetSessionInfo then reports SessionStateFailed, and the activities executed in the session fail with
+// ErrSessionFailed.
+func CreateSession(ctx Context, options SessionOptions) (Context, error) {
+	return createSession(ctx, options)
+}
+
+// CompleteSession completes the session of ctx, which releases it on its worker host. The activities of the session
+// that are still running are not cancelled, and the ones executed with ctx afterwards fail with ErrSessionClosed. It is
+// a no-op when ctx has no open session.
+func CompleteSession(ctx Context) {
+	completeSession(ctx)
+}
+
+// GetSessionInfo returns information about the session of ctx, nil if ctx has no session.
+func GetSessionInfo(ctx Context) *SessionInfo {
+	s := getSession(ctx)
+	if s == nil {
+		return nil
+	}
+	info := s.info
+	return &info
+}
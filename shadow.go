@@ -0,0 +1,100 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"time"
+)
+
+type (
+	// ShadowOptions configure the workflow executions replayed by a worker in shadow mode, see
+	// WorkerOptions.EnableShadowWorker.
+	ShadowOptions struct {
+		// Optional: Sets the workflow types of the replayed executions.
+		// default: the workflow types registered with the worker, including the ones registered globally.
+		WorkflowTypes []string
+
+		// Optional: Sets whether the open executions, the closed ones or both are replayed.
+		// default: ShadowExecutionStatusAll
+		ExecutionStatus ShadowExecutionStatus
+
+		// Optional: Only the executions started within this window before a scan are replayed by the scan.
+		// default: 24 hours
+		WorkflowStartTimeWindow time.Duration
+
+		// Optional: Sets the fraction of the listed executions that are replayed, between 0 and 1.
+		// The zero value of this uses the default value.
+		// default: 1, every listed execution is replayed
+		SamplingRate float64
+
+		// Optional: Sets the max number of executions replayed by a scan. A scan stops listing the executions once
+		// it has replayed that many, the workflow types being scanned in order.
+		// default: 1000
+		MaxExecutionsPerScan int
+
+		// Optional: Sets the time between the end of a scan and the start of the next one.
+		// default: 5 minutes
+		ScanInterval time.Duration
+
+		// Optional: Called with the result of each scan once it is completed. It is not called for the scan
+		// interrupted by Worker.Stop.
+		// default: no callback
+		OnScanCompleted func(result ShadowScanResult)
+	}
+
+	// ShadowExecutionStatus selects the workflow executions replayed by a worker in shadow mode by their status.
+	ShadowExecutionStatus int
+
+	// ShadowScanResult is the result of a scan of a worker in shadow mode.
+	ShadowScanResult struct {
+		StartTime time.Time
+		EndTime   time.Time
+		// The number of executions listed, and the number of sampled ones that were replayed.
+		Listed   int
+		Replayed int
+		// The replayed executions whose history matched the decisions of the workflow code.
+		Succeeded int
+		// The replayed executions whose history didn't match the decisions of the workflow code. Their Error is a
+		// *NonDeterministicError.
+		NonDeterministic []ShadowReplayFailure
+		// The executions whose history couldn't be fetched, or whose replay failed for another reason, like a panic
+		// of the workflow code.
+		Failed []ShadowReplayFailure
+		// The error that ended the listing of the executions early, nil when all the executions were listed.
+		ListError error
+	}
+
+	// ShadowReplayFailure describes a workflow execution whose replay by a worker in shadow mode failed.
+	ShadowReplayFailure struct {
+		WorkflowType      string
+		WorkflowExecution WorkflowExecution
+		Error             error
+	}
+)
+
+const (
+	// ShadowExecutionStatusAll replays both the open and the closed executions.
+	ShadowExecutionStatusAll ShadowExecutionStatus = iota
+	// ShadowExecutionStatusOpen replays the open executions only.
+	ShadowExecutionStatusOpen
+	// ShadowExecutionStatusClosed replays the closed executions only.
+	ShadowExecutionStatusClosed
+)
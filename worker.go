@@ -22,8 +22,10 @@ package cadence
 
 import (
 	"context"
+	"net/http"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/uber-go/tally"
 
 	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
@@ -42,6 +44,32 @@ type (
 		Stop()
 	}
 
+	// WorkerStatsProvider provides the stats of a worker for debugging, see also NewWorkerStatsHandler. The workers
+	// returned by NewWorker implement it.
+	WorkerStatsProvider interface {
+		// Stats returns a snapshot of the state of the worker.
+		Stats() WorkerStats
+	}
+
+	// WorkerRegistry registers workflows and activities with a single worker. The workers returned by NewWorker
+	// implement it:
+	//  worker := cadence.NewWorker(service, domain, taskList, options)
+	//  worker.(cadence.WorkerRegistry).RegisterWorkflow(MyWorkflow)
+	WorkerRegistry interface {
+		// RegisterWorkflow registers the workflow function with this worker only. It works as the global
+		// RegisterWorkflow, and the workflows registered with the worker take precedence over the ones registered
+		// globally, which the worker still hosts. Register the workflows before the worker is started.
+		RegisterWorkflow(workflowFunc interface{})
+		// RegisterWorkflowWithOptions registers the workflow function with options with this worker only.
+		RegisterWorkflowWithOptions(workflowFunc interface{}, options RegisterWorkflowOptions)
+		// RegisterActivity registers the activity function with this worker only. It works as the global
+		// RegisterActivity, and the activities registered with the worker take precedence over the ones registered
+		// globally, which the worker still hosts. Register the activities before the worker is started.
+		RegisterActivity(activityFunc interface{})
+		// RegisterActivityWithOptions registers the activity function with options with this worker only.
+		RegisterActivityWithOptions(activityFunc interface{}, options RegisterActivityOptions)
+	}
+
 	// WorkerOptions is used to configure a worker instance.
 	WorkerOptions struct {
 		// Optional: To set the maximum concurrent activity executions this host can have.
@@ -58,8 +86,48 @@ type (
 		// Warning: activity's StartToCloseTimeout starts ticking even if a task is blocked due to rate limiting.
 		MaxActivityExecutionPerSecond float64
 
+		// Optional: Sets the number of goroutines polling the task list for activity tasks.
+		// The zero value of this uses the default value.
+		// default: defaultConcurrentPollRoutineSize(2)
+		MaxConcurrentActivityTaskPollers int
+
+		// Optional: To set the maximum concurrent decision task executions this host can have.
+		// The zero value of this uses the default value.
+		// default: defaultMaxConcurrentWorkflowExecutionSize(50)
+		MaxConcurrentDecisionTaskExecutionSize int
+
+		// Optional: Sets the rate limiting on number of decision tasks that can be executed per second. Like
+		// MaxActivityExecutionPerSecond, it can be set to less than 1.
+		// The zero value of this uses the default value.
+		// default: defaultMaxWorkflowExecutionRate(100k)
+		MaxDecisionTaskExecutionPerSecond float64
+
+		// Optional: Sets the number of goroutines polling the task list for decision tasks.
+		// The zero value of this uses the default value.
+		// default: defaultConcurrentPollRoutineSize(2)
+		MaxConcurrentDecisionTaskPollers int
+
+		// Optional: Scales the number of pollers of the worker to the load of its task list. A poller is added when
+		// the task list is backlogged and the worker has free task slots, and a poller is removed after consecutive
+		// empty polls. The number of pollers stays between the Min and Max poller options, and is reported with the
+		// poller-count gauge.
+		// default: false, the worker runs the max number of pollers.
+		EnablePollerAutoScaler bool
+
+		// Optional: Sets the min number of goroutines polling for activity tasks when EnablePollerAutoScaler is set.
+		// The zero value of this uses the default value.
+		// default: defaultMinConcurrentPollRoutineSize(1)
+		MinConcurrentActivityTaskPollers int
+
+		// Optional: Sets the min number of goroutines polling for decision tasks when EnablePollerAutoScaler is set.
+		// The zero value of this uses the default value.
+		// default: defaultMinConcurrentPollRoutineSize(1)
+		MinConcurrentDecisionTaskPollers int
+
 		// Optional: if the activities need auto heart beating for those activities
-		// by the framework
+		// by the framework. While an activity with a heartbeat timeout runs, the framework heartbeats
+		// with the last details recorded by the activity and cancels the activity context when the
+		// activity is cancelled.
 		// default: false not to heartbeat.
 		AutoHeartBeat bool
 
@@ -105,10 +173,164 @@ type (
 		// The resolution is seconds. See details about StickyExecution on the comments for DisableStickyExecution.
 		StickyScheduleToStartTimeout time.Duration
 
+		// Optional: Gives the worker its own cache of sticky workflow executions, which is purged when the worker is
+		// stopped. The workers without their own cache share the cache of the process, see SetStickyCacheOptions.
+		// default: the zero value shares the cache of the process.
+		StickyCache StickyCacheOptions
+
 		// Optional: sets context for activity. The context can be used to pass any configuration to activity
 		// like common logger for all activities.
 		BackgroundActivityContext context.Context
+
+		// Optional: Sets the interceptors applied to every workflow execution. The first factory creates the outermost
+		// interceptor, so it sees the calls first, both the ones into the workflow and the ones made by the workflow
+		// code, and their results last.
+		// default: no interceptors
+		WorkflowInterceptors []WorkflowInterceptorFactory
+
+		// Optional: Sets the interceptors applied to every activity execution. The first factory creates the outermost
+		// interceptor.
+		// default: no interceptors
+		ActivityInterceptors []ActivityInterceptorFactory
+
+		// Optional: Sets the tracer used to create the spans of workflow and activity executions. The span contexts
+		// are carried in the headers of the workflows, activities and signals, so a workflow span is a child of the
+		// Client.StartWorkflow or StartChildWorkflow span and an activity span is a child of the StartActivity span.
+		// The spans are also tagged with the ID and the run ID of their workflow execution. The inputs of workflows
+		// and activities are left unchanged. Activities can get their span through opentracing.SpanFromContext.
+		// default: no tracing
+		Tracer opentracing.Tracer
+
+		// Optional: Sets the time a workflow coroutine may run without yielding before it is considered deadlocked.
+		// Workflow code yields whenever it blocks on a cadence API, so this only happens when it blocks on a native
+		// mutex, channel or I/O call, or runs a long computation. The decision task of a deadlocked workflow fails with
+		// a PanicError that contains the stack traces of all the workflow coroutines.
+		// default: 1s
+		DeadlockDetectionTimeout time.Duration
+
+		// Optional: Disables the deadlock detection. Set it when stepping through workflow code in a debugger, as a
+		// coroutine stopped at a breakpoint doesn't yield.
+		// default: false
+		DisableDeadlockDetection bool
+
+		// Optional: Sets what the worker does when the replay of a workflow doesn't match its history. The mismatch is
+		// reported as a NonDeterministicError and counted by the cadence-non-deterministic-error metric.
+		// default: NonDeterministicWorkflowPolicyBlockWorkflow
+		NonDeterministicWorkflowPolicy NonDeterministicWorkflowPolicy
+
+		// Optional: Sets how long Worker.Stop waits for the running activity and decision tasks to complete and report
+		// their results. The pollers stop as soon as the worker is stopped, and the context of the running activities
+		// is cancelled with ErrWorkerStopping so that they can wrap up. What the activities return is reported like
+		// any other result, as long as they return within the timeout. Decision tasks in progress are allowed to
+		// finish.
+		// default: 0, Stop doesn't wait for the running tasks, which keep running
+		WorkerStopTimeout time.Duration
+
+		// Optional: Enables the session worker, which lets workflows run a sequence of activities on this worker host
+		// through CreateSession. It has no effect when the activity worker is disabled.
+		// default: false
+		EnableSessionWorker bool
+
+		// Optional: Sets the maximum number of sessions this worker runs concurrently. Once the limit is reached, the
+		// worker stops accepting new sessions until one of its sessions is completed.
+		// The zero value of this uses the default value.
+		// default: defaultMaxConcurrentSessionExecutionSize(1k)
+		MaxConcurrentSessionExecutionSize int
+
+		// Optional: Sets the rules injecting faults into the operations of the worker, see FaultInjectionRule. Use
+		// them to test the resilience of workflows, never in production.
+		// default: no fault injected
+		FaultInjectionRules []FaultInjectionRule
+
+		// Optional: Runs the worker in shadow mode. Instead of polling the task list, the worker periodically lists
+		// the recent workflow executions of the domain, fetches their history and replays it with the registered
+		// workflows, to detect the code changes that break the determinism of running or recorded workflows before
+		// they are deployed. No decision is sent and no activity runs. The replays are counted by the
+		// cadence-shadow-replay-succeed, cadence-shadow-replay-non-deterministic and cadence-shadow-replay-failed
+		// metrics, and the mismatches are logged, see also ShadowOptions.OnScanCompleted.
+		// default: false
+		EnableShadowWorker bool
+
+		// Optional: Sets the workflow executions replayed by the worker in shadow mode, see ShadowOptions.
+		ShadowOptions ShadowOptions
+	}
+
+	// StickyCacheOptions configures a cache of the workflow executions that the workers keep in memory for sticky
+	// execution. An evicted execution replays its full history on its next decision task. The cache reports the
+	// cadence-sticky-cache-size and cadence-sticky-cache-hit-ratio gauges and the cadence-sticky-cache-eviction counter.
+	StickyCacheOptions struct {
+		// Optional: Sets the max number of cached workflow executions.
+		// The zero value of this uses the default value.
+		// default: defaultStickyCacheSize(10k)
+		Size int
+
+		// Optional: Evicts the least recently used executions when the estimated memory of the cached executions
+		// exceeds it. The memory of an execution is estimated from the number and the payloads of its history events.
+		// default: 0, no limit
+		MaxMemoryBytes int64
+
+		// Optional: Evicts the executions that didn't process a decision task for longer than it. Idle executions are
+		// evicted when the cache is used by a decision task.
+		// default: 0, no idle eviction
+		IdleTimeout time.Duration
+	}
+
+	// WorkerStats is a snapshot of the state of a worker, returned by WorkerStatsProvider.Stats.
+	WorkerStats struct {
+		Domain   string
+		TaskList string
+		Identity string
+		// The workflow and activity types hosted by the worker, including the ones registered globally.
+		WorkflowTypes []string
+		ActivityTypes []string
+		// The states of the decision and activity task workers, nil when they are disabled.
+		DecisionWorker *TaskWorkerStats
+		ActivityWorker *TaskWorkerStats
+		// The number of workflow executions in the sticky cache used by the worker.
+		StickyCacheSize int
 	}
+
+	// TaskWorkerStats is a snapshot of the state of the decision or the activity task worker of a Worker.
+	TaskWorkerStats struct {
+		Started bool
+		// The number of goroutines polling the task list.
+		PollerCount        int
+		MaxConcurrentTasks int
+		// The tasks being processed, oldest first.
+		RunningTasks []RunningTaskStats
+		// The limit on the number of tasks processed per second, and the burst of the limiter.
+		TaskRateLimit      float64
+		TaskRateLimitBurst int
+		// The last error returned by a poll and when it happened, empty if no poll failed.
+		LastPollError     string
+		LastPollErrorTime time.Time
+	}
+
+	// RunningTaskStats describes a task being processed by a worker.
+	RunningTaskStats struct {
+		WorkflowExecution WorkflowExecution
+		// The workflow type of a decision task.
+		WorkflowType string
+		// The activity type and ID of an activity task.
+		ActivityType string
+		ActivityID   string
+		StartTime    time.Time
+	}
+)
+
+// NonDeterministicWorkflowPolicy defines how the workflow worker handles a workflow whose replay doesn't match its
+// history.
+type NonDeterministicWorkflowPolicy int
+
+const (
+	// NonDeterministicWorkflowPolicyBlockWorkflow fails the decision task, so it is retried until the workflow code is
+	// fixed and deployed. The workflow makes no progress in the meantime.
+	NonDeterministicWorkflowPolicyBlockWorkflow NonDeterministicWorkflowPolicy = iota
+	// NonDeterministicWorkflowPolicyFailWorkflow fails the workflow execution with the NonDeterministicError.
+	NonDeterministicWorkflowPolicyFailWorkflow
+	// NonDeterministicWorkflowPolicyLogAndContinue logs the NonDeterministicError and completes the decision task with
+	// the decisions made by the current workflow code.
+	NonDeterministicWorkflowPolicyLogAndContinue
 )
 
 // NewWorker creates an instance of worker for managing workflow and activity executions.
@@ -117,6 +339,7 @@ type (
 // taskList 	- is the task list name you use to identify your client worker, also
 // 		  identifies group of workflow and activity implementations that are hosted by a single worker process.
 // options 	-  configure any worker specific options like logger, metrics, identity.
+// This method calls panic if the options are invalid, for example if a size or a rate is negative.
 func NewWorker(
 	service workflowserviceclient.Interface,
 	domain string,
@@ -125,3 +348,24 @@ func NewWorker(
 ) Worker {
 	return newAggregatedWorker(service, domain, taskList, options)
 }
+
+// SetStickyCacheOptions configures the cache of sticky workflow executions shared by the workers of the process that
+// don't have their own cache. Call it before creating any worker, as the workers get the cache when they are created.
+// It panics once the cache is in use or if the options are invalid.
+func SetStickyCacheOptions(options StickyCacheOptions) {
+	setStickyCacheOptions(options)
+}
+
+// PurgeStickyCache evicts the workflow executions cached by all the workers of the process. Their next decision tasks
+// replay their full history. Use it to reset the workers between tests, or to release memory before a rolling deploy.
+func PurgeStickyCache() {
+	purgeStickyCaches()
+}
+
+// NewWorkerStatsHandler returns an http.Handler that serves the Stats of the worker as JSON. When the request has the
+// stack_trace=true query parameter, the response also has the __stack_trace of every workflow execution in the sticky
+// cache used by the worker. Getting a stack trace waits for the decision task in progress of the workflow execution.
+// The handler fails with http.StatusNotImplemented if the worker doesn't implement WorkerStatsProvider.
+func NewWorkerStatsHandler(worker Worker) http.Handler {
+	return &workerStatsHandler{worker: worker}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"time"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/cadence/.gen/go/cadence/workflowserviceclient"
+	"go.uber.org/zap"
+)
+
+type (
+	// WorkerHost runs the workers of a process that polls several domains and task lists. The workers share the
+	// connection to the cadence server and a sticky cache, and are started and stopped together.
+	WorkerHost interface {
+		// Start starts all the workers in a non-blocking fashion. When a worker fails to start, the workers already
+		// started are stopped and the error is returned.
+		Start() error
+		// Run starts all the workers, and stops them when the process receives SIGINT or SIGTERM, like Worker.Run.
+		// It returns an error only if it fails to start the workers.
+		Run() error
+		// Stop stops all the workers at once, waiting for their running tasks as set by WorkerOptions.WorkerStopTimeout.
+		Stop()
+		// Workers returns the workers of the host, in the order of their WorkerHostEntry.
+		Workers() []Worker
+		// Health returns the health of the host and of each of its workers.
+		Health() WorkerHostHealth
+	}
+
+	// WorkerHostEntry describes a worker of a WorkerHost.
+	WorkerHostEntry struct {
+		Domain   string
+		TaskList string
+
+		// Optional: Registers the workflows and activities with the worker. The functions registered globally are
+		// hosted by every worker.
+		// default: the worker hosts the functions registered globally only.
+		Register func(registry WorkerRegistry)
+
+		// Optional: Configures the worker. When the Logger or the MetricsScope is not set, the worker uses the one of
+		// the host, with the task list as a tag. When the StickyCache is not set, the worker uses the cache of the host.
+		Options WorkerOptions
+	}
+
+	// WorkerHostOptions configures a WorkerHost.
+	WorkerHostOptions struct {
+		// Optional: Sets the logger of the host and of the workers without their own logger.
+		// default: the default logger of the workers.
+		Logger *zap.Logger
+
+		// Optional: Sets the metrics scope of the workers without their own scope.
+		// default: no metrics.
+		MetricsScope tally.Scope
+
+		// Optional: Gives the workers of the host their own sticky cache, which is purged when the host is stopped.
+		// default: the zero value shares the cache of the process, see SetStickyCacheOptions.
+		StickyCache StickyCacheOptions
+
+		// Optional: Sets for how long a failed poll of a worker makes it unhealthy.
+		// default: 1 minute
+		HealthPollErrorWindow time.Duration
+	}
+
+	// WorkerHostHealth is the health of a WorkerHost, returned by WorkerHost.Health.
+	WorkerHostHealth struct {
+		// The host is healthy when it is started and all its workers are healthy.
+		Healthy bool
+		Workers []WorkerHealth
+	}
+
+	// WorkerHealth is the health of a worker of a WorkerHost. A worker is healthy when its decision and activity task
+	// workers are started and none of their polls failed within the WorkerHostOptions.HealthPollErrorWindow.
+	WorkerHealth struct {
+		Domain   string
+		TaskList string
+		Healthy  bool
+		// Why the worker is unhealthy, empty when it is healthy.
+		Reason string
+	}
+)
+
+// NewWorkerHost creates the workers of the entries, all connected to the cadence server through the service. It
+// returns an error if there is no entry, if two entries have the same domain and task list, or if the options of the
+// host or of an entry are invalid.
+func NewWorkerHost(
+	service workflowserviceclient.Interface,
+	entries []WorkerHostEntry,
+	options WorkerHostOptions,
+) (WorkerHost, error) {
+	return newWorkerHost(service, entries, options)
+}
@@ -114,6 +114,28 @@ type (
 		GetChildWorkflowExecution() Future
 	}
 
+	// ResettableTimer is a timer whose Future becomes ready only when no Reset happened within the requested
+	// duration. It is useful to implement inactivity timeouts without canceling and recreating timers.
+	// Use cadence.NewResettableTimer(ctx, d) to create a ResettableTimer instance.
+	ResettableTimer interface {
+		Future
+		// Reset restarts the timer so that it fires after d from the current workflow time. Reset on a timer that
+		// has already fired or was stopped is a no-op. A non positive d causes the timer to fire immediately.
+		Reset(d time.Duration)
+		// Stop stops the timer. The Future becomes ready with *CanceledError if it was not ready yet.
+		Stop()
+	}
+
+	// Ticker delivers the workflow time on its Channel at regular intervals.
+	// Use cadence.NewTicker(ctx, interval) to create a Ticker instance.
+	Ticker interface {
+		// Chan returns the Channel on which the ticks are delivered. The Channel holds at most one pending tick,
+		// ticks are dropped if the workflow doesn't keep up. The Channel is closed once the ticker is stopped.
+		Chan() Channel
+		// Stop turns off the ticker. No more ticks will be sent after Stop.
+		Stop()
+	}
+
 	// WorkflowType identifies a workflow type.
 	WorkflowType struct {
 		Name string
@@ -299,8 +321,18 @@ func NewFuture(ctx Context) (Future, Settable) {
 // ExecuteActivity returns Future with activity result or failure.
 func ExecuteActivity(ctx Context, activity interface{}, args ...interface{}) Future {
 	// Validate type and its arguments.
-	future, settable := newDecodeFuture(ctx, activity)
-	activityType, input, err := getValidatedActivityFunction(activity, args)
+	activityType, err := getValidatedActivityType(getWorkflowEnvironment(ctx).GetRegistry(), activity, args)
+	if err != nil {
+		future, settable := newDecodeFuture(ctx, activity)
+		settable.Set(nil, err)
+		return future
+	}
+	return getWorkflowInterceptor(ctx).ExecuteActivity(ctx, activityType, args...)
+}
+
+func (wc *workflowEnvironmentInterceptor) ExecuteActivity(ctx Context, activityType string, args ...interface{}) Future {
+	future, settable := newDecodeFuture(ctx, getRegisteredActivityFn(wc.env.GetRegistry(), activityType))
+	activityTypePtr, input, err := getValidatedActivityFunction(activityType, args)
 	if err != nil {
 		settable.Set(nil, err)
 		return future
@@ -312,17 +344,33 @@ func ExecuteActivity(ctx Context, activity interface{}, args ...interface{}) Fut
 		settable.Set(nil, err)
 		return future
 	}
-	parameters.ActivityType = *activityType
+	if sess := getSession(ctx); sess != nil {
+		switch sess.info.SessionState {
+		case SessionStateOpen:
+		case SessionStateFailed:
+			settable.Set(nil, ErrSessionFailed)
+			return future
+		default:
+			settable.Set(nil, ErrSessionClosed)
+			return future
+		}
+		// Activities of the session run on the worker host of the session.
+		sessionParameters := *parameters
+		sessionParameters.TaskListName = sess.taskList
+		parameters = &sessionParameters
+	}
+	parameters.ActivityType = *activityTypePtr
 	parameters.Input = input
+	parameters.Header = getOutgoingHeader(ctx)
 
-	a := getWorkflowEnvironment(ctx).ExecuteActivity(*parameters, func(r []byte, e error) {
+	a := wc.env.ExecuteActivity(*parameters, func(r []byte, e error) {
 		settable.Set(r, e)
 	})
 	Go(ctx, func(ctx Context) {
 		if ctxDone := ctx.Done(); ctxDone != nil {
 			NewSelector(ctx).AddReceive(ctxDone, func(c Channel, more bool) {
 				if ctx.Err() == ErrCanceled {
-					getWorkflowEnvironment(ctx).RequestCancelActivity(a.activityID)
+					wc.env.RequestCancelActivity(a.activityID)
 				}
 			}).AddFuture(future, func(f Future) {
 				// activity is done, no-op
@@ -349,12 +397,25 @@ func ExecuteActivity(ctx Context, activity interface{}, args ...interface{}) Fut
 // error CanceledError.
 // ExecuteChildWorkflow returns ChildWorkflowFuture.
 func ExecuteChildWorkflow(ctx Context, childWorkflow interface{}, args ...interface{}) ChildWorkflowFuture {
-	mainFuture, mainSettable := newDecodeFuture(ctx, childWorkflow)
+	childWorkflowType, err := getValidatedWorkflowType(getWorkflowEnvironment(ctx).GetRegistry(), childWorkflow, args)
+	if err != nil {
+		mainFuture, mainSettable := newDecodeFuture(ctx, childWorkflow)
+		executionFuture, _ := NewFuture(ctx)
+		mainSettable.Set(nil, err)
+		return childWorkflowFutureImpl{
+			decodeFutureImpl: mainFuture.(*decodeFutureImpl),
+			executionFuture:  executionFuture.(*futureImpl)}
+	}
+	return getWorkflowInterceptor(ctx).ExecuteChildWorkflow(ctx, childWorkflowType, args...)
+}
+
+func (wc *workflowEnvironmentInterceptor) ExecuteChildWorkflow(ctx Context, childWorkflowType string, args ...interface{}) ChildWorkflowFuture {
+	mainFuture, mainSettable := newDecodeFuture(ctx, getRegisteredWorkflowFn(wc.env.GetRegistry(), childWorkflowType))
 	executionFuture, executionSettable := NewFuture(ctx)
 	result := childWorkflowFutureImpl{
 		decodeFutureImpl: mainFuture.(*decodeFutureImpl),
 		executionFuture:  executionFuture.(*futureImpl)}
-	wfType, input, err := getValidatedWorkerFunction(childWorkflow, args)
+	wfType, input, err := getValidatedWorkerFunction(childWorkflowType, args)
 	if err != nil {
 		mainSettable.Set(nil, err)
 		return result
@@ -367,8 +428,9 @@ func ExecuteChildWorkflow(ctx Context, childWorkflow interface{}, args ...interf
 
 	options.input = input
 	options.workflowType = wfType
+	options.header = getOutgoingHeader(ctx)
 	var childWorkflowExecution *WorkflowExecution
-	getWorkflowEnvironment(ctx).ExecuteChildWorkflow(*options, func(r []byte, e error) {
+	wc.env.ExecuteChildWorkflow(*options, func(r []byte, e error) {
 		mainSettable.Set(r, e)
 	}, func(r WorkflowExecution, e error) {
 		if e == nil {
@@ -381,7 +443,7 @@ func ExecuteChildWorkflow(ctx Context, childWorkflow interface{}, args ...interf
 			NewSelector(ctx).AddReceive(ctxDone, func(c Channel, more bool) {
 				if ctx.Err() == ErrCanceled && childWorkflowExecution != nil {
 					// child workflow started, and ctx cancelled
-					getWorkflowEnvironment(ctx).RequestCancelWorkflow(
+					wc.env.RequestCancelWorkflow(
 						*options.domain, childWorkflowExecution.ID, childWorkflowExecution.RunID)
 				}
 			}).AddFuture(mainFuture, func(f Future) {
@@ -418,6 +480,13 @@ func GetMetricsScope(ctx Context) tally.Scope {
 	return getWorkflowEnvironment(ctx).GetMetricsScope()
 }
 
+// IsReplaying returns true if the workflow code is being replayed from history. Use it to skip side effects like
+// logging or metrics that should only happen once. cadence.GetLogger(ctx) and cadence.GetMetricsScope(ctx) already
+// take care of this.
+func IsReplaying(ctx Context) bool {
+	return getWorkflowEnvironment(ctx).IsReplaying()
+}
+
 // Now returns the current time when the decision is started or replayed.
 // The workflow needs to use this Now() to get the wall clock time instead of the Go lang library one.
 func Now(ctx Context) time.Time {
@@ -430,13 +499,17 @@ func Now(ctx Context) time.Time {
 // is canceled, the returned Future become ready, and Future.Get() will return *CanceledError.
 // The current timer resolution implementation is in seconds but is subjected to change.
 func NewTimer(ctx Context, d time.Duration) Future {
+	return getWorkflowInterceptor(ctx).NewTimer(ctx, d)
+}
+
+func (wc *workflowEnvironmentInterceptor) NewTimer(ctx Context, d time.Duration) Future {
 	future, settable := NewFuture(ctx)
 	if d <= 0 {
 		settable.Set(true, nil)
 		return future
 	}
 
-	t := getWorkflowEnvironment(ctx).NewTimer(d, func(r []byte, e error) {
+	t := wc.env.NewTimer(d, func(r []byte, e error) {
 		settable.Set(nil, e)
 	})
 	if t != nil {
@@ -444,7 +517,7 @@ func NewTimer(ctx Context, d time.Duration) Future {
 			if ctxDone := ctx.Done(); ctxDone != nil {
 				NewSelector(ctx).AddReceive(ctxDone, func(c Channel, more bool) {
 					// We will cancel the timer either it is explicit cancellation (or) we are closed.
-					getWorkflowEnvironment(ctx).RequestCancelTimer(t.timerID)
+					wc.env.RequestCancelTimer(t.timerID)
 				}).AddFuture(future, func(f Future) {
 					// timer is done, no-op
 				}).Select(ctx)
@@ -454,6 +527,77 @@ func NewTimer(ctx Context, d time.Duration) Future {
 	return future
 }
 
+// NewResettableTimer returns a timer that becomes ready after the duration d unless it is reset. Each call to
+// Reset(d) pushes the fire time to d from the current workflow time. Resets that extend the deadline don't create any
+// new timer decisions, the pending timer is simply re-armed for the remaining time when it fires. The timer is stopped
+// and its Future is ready with *CanceledError when the ctx is canceled.
+// The current timer resolution implementation is in seconds but is subjected to change.
+//  timer := cadence.NewResettableTimer(ctx, 10*time.Minute)
+//  for {
+//      s := cadence.NewSelector(ctx)
+//      s.AddReceive(signalCh, func(c Channel, more bool) { c.Receive(ctx, nil); timer.Reset(10*time.Minute) })
+//      s.AddFuture(timer, func(f Future) { /* inactivity timeout */ })
+//      s.Select(ctx)
+//  }
+func NewResettableTimer(ctx Context, d time.Duration) ResettableTimer {
+	future, _ := NewFuture(ctx)
+	t := &resettableTimerImpl{
+		futureImpl: future.(*futureImpl),
+		env:        getWorkflowEnvironment(ctx),
+	}
+	t.Reset(d)
+	if !t.IsReady() {
+		Go(ctx, func(ctx Context) {
+			if ctxDone := ctx.Done(); ctxDone != nil {
+				NewSelector(ctx).AddReceive(ctxDone, func(c Channel, more bool) {
+					t.Stop()
+				}).AddFuture(t, func(f Future) {
+					// timer is done, no-op
+				}).Select(ctx)
+			}
+		})
+	}
+	return t
+}
+
+// NewTicker returns a Ticker that sends the workflow time on its Channel every interval. Ticks are driven by durable
+// timers and are scheduled at multiples of the interval from the creation of the ticker, so they don't drift when a
+// tick is received late. The ticker stops when Stop is called or the ctx is canceled. The interval must be at least
+// one second as the current timer resolution implementation is in seconds.
+//  ticker := cadence.NewTicker(ctx, 5*time.Minute)
+//  defer ticker.Stop()
+//  var tickTime time.Time
+//  for ticker.Chan().Receive(ctx, &tickTime) {
+//      // poll external system
+//  }
+func NewTicker(ctx Context, interval time.Duration) Ticker {
+	return NewTickerWithStart(ctx, Now(ctx), interval)
+}
+
+// NewTickerWithStart returns a Ticker like NewTicker, with the ticks scheduled at multiples of the interval from
+// startTime rather than from the creation of the ticker. The first tick is the first of them after the current
+// workflow time. A workflow that continues as new keeps the phase of its ticks by passing the start time of the
+// original ticker to the new run:
+//  func PollWorkflow(ctx cadence.Context, tickerStart time.Time) error {
+//      if tickerStart.IsZero() {
+//          tickerStart = cadence.Now(ctx)
+//      }
+//      ticker := cadence.NewTickerWithStart(ctx, tickerStart, 5*time.Minute)
+//      ...
+//      return cadence.NewContinueAsNewError(ctx, PollWorkflow, tickerStart)
+//  }
+func NewTickerWithStart(ctx Context, startTime time.Time, interval time.Duration) Ticker {
+	if interval < time.Second {
+		panic(fmt.Sprintf("invalid ticker interval %v, must be at least one second", interval))
+	}
+	ctx, cancel := WithCancel(ctx)
+	t := &tickerImpl{channel: NewBufferedChannel(ctx, 1), cancel: cancel}
+	Go(ctx, func(ctx Context) {
+		t.run(ctx, startTime, interval)
+	})
+	return t
+}
+
 // Sleep pauses the current workflow for at least the duration d. A negative or zero duration causes Sleep to return
 // immediately. Workflow code needs to use this Sleep() to sleep instead of the Go lang library one(timer.Sleep()).
 // You can cancel the pending sleep by cancel the Context (using context from cadence.WithCancel(ctx)).
@@ -475,12 +619,16 @@ func Sleep(ctx Context, d time.Duration) (err error) {
 // of the target workflow using the context like:
 //	ctx := WithWorkflowDomain(ctx, "domain-name")
 func RequestCancelWorkflow(ctx Context, workflowID, runID string) error {
+	return getWorkflowInterceptor(ctx).RequestCancelWorkflow(ctx, workflowID, runID)
+}
+
+func (wc *workflowEnvironmentInterceptor) RequestCancelWorkflow(ctx Context, workflowID, runID string) error {
 	ctx1 := setWorkflowEnvOptionsIfNotExist(ctx)
 	options := getWorkflowEnvOptions(ctx1)
 	if options.domain == nil {
 		return errors.New("need a valid domain")
 	}
-	return getWorkflowEnvironment(ctx).RequestCancelWorkflow(*options.domain, workflowID, runID)
+	return wc.env.RequestCancelWorkflow(*options.domain, workflowID, runID)
 }
 
 // WithChildWorkflowOptions adds all workflow options to the context.
@@ -587,6 +735,10 @@ func (b EncodedValue) Get(valuePtr interface{}) error {
 //         ....
 //  }
 func SideEffect(ctx Context, f func(ctx Context) interface{}) EncodedValue {
+	return getWorkflowInterceptor(ctx).SideEffect(ctx, f)
+}
+
+func (wc *workflowEnvironmentInterceptor) SideEffect(ctx Context, f func(ctx Context) interface{}) EncodedValue {
 	future, settable := NewFuture(ctx)
 	wrapperFunc := func() ([]byte, error) {
 		r := f(ctx)
@@ -595,7 +747,7 @@ func SideEffect(ctx Context, f func(ctx Context) interface{}) EncodedValue {
 	resultCallback := func(result []byte, err error) {
 		settable.Set(EncodedValue(result), err)
 	}
-	getWorkflowEnvironment(ctx).SideEffect(wrapperFunc, resultCallback)
+	wc.env.SideEffect(wrapperFunc, resultCallback)
 	var encoded EncodedValue
 	if err := future.Get(ctx, &encoded); err != nil {
 		panic(err)
@@ -651,7 +803,11 @@ const DefaultVersion Version = -1
 // It is necessary as GetVersion performs validation of a version against a workflow history and fails decisions if
 // a workflow code is not compatible with it.
 func GetVersion(ctx Context, changeID string, minSupported, maxSupported Version) Version {
-	return getWorkflowEnvironment(ctx).GetVersion(changeID, minSupported, maxSupported)
+	return getWorkflowInterceptor(ctx).GetVersion(ctx, changeID, minSupported, maxSupported)
+}
+
+func (wc *workflowEnvironmentInterceptor) GetVersion(ctx Context, changeID string, minSupported, maxSupported Version) Version {
+	return wc.env.GetVersion(changeID, minSupported, maxSupported)
 }
 
 // SetQueryHandler sets the query handler to handle workflow query. The queryType specify which query type this handler
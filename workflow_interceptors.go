@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"time"
+)
+
+type (
+	// WorkflowInterceptorFactory is used to create a single link of the workflow interceptor chain.
+	// Set WorkerOptions.WorkflowInterceptors to add interceptor factories to a worker.
+	WorkflowInterceptorFactory interface {
+		// NewInterceptor creates an interceptor for a single workflow execution. The returned interceptor must
+		// delegate every call to next for the workflow to function correctly.
+		NewInterceptor(info *WorkflowInfo, next WorkflowInterceptor) WorkflowInterceptor
+	}
+
+	// WorkflowInterceptor intercepts the workflow entry point, the calls workflow code makes to the cadence APIs and
+	// the signals and queries delivered to the workflow. All methods are invoked from the workflow context, so the
+	// same determinism rules as for the workflow code apply to the interceptors. Interceptors are also invoked when
+	// the workflow is replayed. Use cadence.GetLogger(ctx) and cadence.GetMetricsScope(ctx) which are replay aware
+	// or check cadence.IsReplaying(ctx) before doing any other side effects.
+	// Embed WorkflowInterceptorBase to only override the methods of interest.
+	WorkflowInterceptor interface {
+		// ExecuteWorkflow intercepts the workflow entry point. The input is the encoded workflow arguments.
+		ExecuteWorkflow(ctx Context, workflowType string, input []byte) (result []byte, err error)
+		// ExecuteActivity intercepts cadence.ExecuteActivity. The activityType is the registered name of the activity.
+		ExecuteActivity(ctx Context, activityType string, args ...interface{}) Future
+		// ExecuteChildWorkflow intercepts cadence.ExecuteChildWorkflow. The childWorkflowType is the registered name
+		// of the child workflow.
+		ExecuteChildWorkflow(ctx Context, childWorkflowType string, args ...interface{}) ChildWorkflowFuture
+		// NewTimer intercepts cadence.NewTimer.
+		NewTimer(ctx Context, d time.Duration) Future
+		// SideEffect intercepts cadence.SideEffect.
+		SideEffect(ctx Context, f func(ctx Context) interface{}) EncodedValue
+		// GetVersion intercepts cadence.GetVersion.
+		GetVersion(ctx Context, changeID string, minSupported, maxSupported Version) Version
+		// RequestCancelWorkflow intercepts cadence.RequestCancelWorkflow.
+		RequestCancelWorkflow(ctx Context, workflowID, runID string) error
+		// HandleSignal intercepts the receipt of a signal before it is delivered to the signal channel.
+		HandleSignal(ctx Context, signalName string, input EncodedValue)
+		// HandleQuery intercepts a query before it is passed to the query handler set by cadence.SetQueryHandler.
+		HandleQuery(ctx Context, queryType string, args EncodedValues) (EncodedValue, error)
+	}
+
+	// WorkflowInterceptorBase is a WorkflowInterceptor that forwards all calls to the Next interceptor.
+	// It is meant to be embedded by interceptors that only need to override some of the methods.
+	WorkflowInterceptorBase struct {
+		Next WorkflowInterceptor
+	}
+)
+
+var _ WorkflowInterceptor = (*WorkflowInterceptorBase)(nil)
+
+// ExecuteWorkflow forwards to t.Next
+func (t *WorkflowInterceptorBase) ExecuteWorkflow(ctx Context, workflowType string, input []byte) ([]byte, error) {
+	return t.Next.ExecuteWorkflow(ctx, workflowType, input)
+}
+
+// ExecuteActivity forwards to t.Next
+func (t *WorkflowInterceptorBase) ExecuteActivity(ctx Context, activityType string, args ...interface{}) Future {
+	return t.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+// ExecuteChildWorkflow forwards to t.Next
+func (t *WorkflowInterceptorBase) ExecuteChildWorkflow(ctx Context, childWorkflowType string, args ...interface{}) ChildWorkflowFuture {
+	return t.Next.ExecuteChildWorkflow(ctx, childWorkflowType, args...)
+}
+
+// NewTimer forwards to t.Next
+func (t *WorkflowInterceptorBase) NewTimer(ctx Context, d time.Duration) Future {
+	return t.Next.NewTimer(ctx, d)
+}
+
+// SideEffect forwards to t.Next
+func (t *WorkflowInterceptorBase) SideEffect(ctx Context, f func(ctx Context) interface{}) EncodedValue {
+	return t.Next.SideEffect(ctx, f)
+}
+
+// GetVersion forwards to t.Next
+func (t *WorkflowInterceptorBase) GetVersion(ctx Context, changeID string, minSupported, maxSupported Version) Version {
+	return t.Next.GetVersion(ctx, changeID, minSupported, maxSupported)
+}
+
+// RequestCancelWorkflow forwards to t.Next
+func (t *WorkflowInterceptorBase) RequestCancelWorkflow(ctx Context, workflowID, runID string) error {
+	return t.Next.RequestCancelWorkflow(ctx, workflowID, runID)
+}
+
+// HandleSignal forwards to t.Next
+func (t *WorkflowInterceptorBase) HandleSignal(ctx Context, signalName string, input EncodedValue) {
+	t.Next.HandleSignal(ctx, signalName, input)
+}
+
+// HandleQuery forwards to t.Next
+func (t *WorkflowInterceptorBase) HandleQuery(ctx Context, queryType string, args EncodedValues) (EncodedValue, error) {
+	return t.Next.HandleQuery(ctx, queryType, args)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cadence
+
+import (
+	"context"
+
+	s "go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/zap"
+)
+
+// WorkflowReplayer replays recorded workflow histories with the workflow code registered with it, so code changes can
+// be checked for nondeterminism before they are deployed. The replay doesn't need a running cadence service, and no
+// decision is reported anywhere.
+//
+//	replayer := cadence.NewWorkflowReplayer()
+//	replayer.RegisterWorkflow(sampleWorkflow)
+//	err := replayer.ReplayWorkflowHistoryFromJSONFile(logger, "sample_workflow_history.json")
+type WorkflowReplayer struct {
+	workerRegistration
+}
+
+// NewWorkflowReplayer creates a WorkflowReplayer. The workflows registered with it take precedence over the ones
+// registered globally, which it can replay as well.
+func NewWorkflowReplayer() *WorkflowReplayer {
+	return &WorkflowReplayer{workerRegistration: workerRegistration{hostEnv: newRegistry()}}
+}
+
+// ReplayWorkflowHistory replays the history of a workflow execution. The history has to start with the
+// WorkflowExecutionStarted event. It returns a *NonDeterministicError that lists the replay decisions and the history
+// events side by side when the decisions made by the workflow code don't match the history, or the error of the replay,
+// like a *PanicError when the workflow code panics.
+// The logger is optional, the replay logs nothing without it.
+func (r *WorkflowReplayer) ReplayWorkflowHistory(logger *zap.Logger, history *s.History) error {
+	return replayWorkflowHistory(logger, r.hostEnv, nil, history, replayOptions{})
+}
+
+// ReplayWorkflowHistoryFromJSONFile replays the history of a workflow execution read from a JSON file written by
+// ExportHistoryJSON, or holding the JSON encoding of a shared.History. See ReplayWorkflowHistory for the errors it
+// returns.
+func (r *WorkflowReplayer) ReplayWorkflowHistoryFromJSONFile(logger *zap.Logger, jsonFileName string) error {
+	history, err := importHistoryJSONFile(jsonFileName)
+	if err != nil {
+		return err
+	}
+	return r.ReplayWorkflowHistory(logger, history)
+}
+
+// ReplayWorkflowExecution replays the history of a workflow execution fetched with the client.
+// - runID can be default(empty string). if empty string then it will pick the running execution of that workflow ID.
+// See ReplayWorkflowHistory for the errors it returns, besides the ones of Client.GetWorkflowHistory.
+func (r *WorkflowReplayer) ReplayWorkflowExecution(
+	ctx context.Context,
+	client Client,
+	logger *zap.Logger,
+	workflowID string,
+	runID string,
+) error {
+	history, err := client.GetWorkflowHistory(ctx, workflowID, runID)
+	if err != nil {
+		return err
+	}
+	execution := &s.WorkflowExecution{WorkflowId: &workflowID}
+	if runID != "" {
+		execution.RunId = &runID
+	}
+	return replayWorkflowHistory(logger, r.hostEnv, execution, history, replayOptions{})
+}
@@ -101,6 +101,19 @@ func (t *TestActivityEnvironment) ExecuteActivity(activityFn interface{}, args .
 	return t.impl.executeActivity(activityFn, args...)
 }
 
+// RegisterActivity registers the activity function with this TestActivityEnvironment only. The activities registered
+// with the environment take precedence over the ones registered globally with RegisterActivity.
+func (t *TestActivityEnvironment) RegisterActivity(activityFn interface{}) {
+	t.RegisterActivityWithOptions(activityFn, RegisterActivityOptions{})
+}
+
+// RegisterActivityWithOptions registers the activity function with options with this TestActivityEnvironment only.
+func (t *TestActivityEnvironment) RegisterActivityWithOptions(activityFn interface{}, options RegisterActivityOptions) {
+	if err := t.impl.registry.RegisterActivityWithOptions(activityFn, options); err != nil {
+		panic(err)
+	}
+}
+
 // SetWorkerOptions sets the WorkerOptions that will be use by TestActivityEnvironment. TestActivityEnvironment will
 // use options of Identity, MetricsScope and BackgroundActivityContext on the WorkerOptions. Other options are ignored.
 func (t *TestActivityEnvironment) SetWorkerOptions(options WorkerOptions) *TestActivityEnvironment {
@@ -108,6 +121,33 @@ func (t *TestActivityEnvironment) SetWorkerOptions(options WorkerOptions) *TestA
 	return t
 }
 
+// RegisterWorkflow registers the workflow function with this TestWorkflowEnvironment only. The workflows registered
+// with the environment, which its child workflows share, take precedence over the ones registered globally with
+// RegisterWorkflow.
+func (t *TestWorkflowEnvironment) RegisterWorkflow(workflowFn interface{}) {
+	t.RegisterWorkflowWithOptions(workflowFn, RegisterWorkflowOptions{})
+}
+
+// RegisterWorkflowWithOptions registers the workflow function with options with this TestWorkflowEnvironment only.
+func (t *TestWorkflowEnvironment) RegisterWorkflowWithOptions(workflowFn interface{}, options RegisterWorkflowOptions) {
+	if err := t.impl.registry.RegisterWorkflowWithOptions(workflowFn, options); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterActivity registers the activity function with this TestWorkflowEnvironment only. The activities registered
+// with the environment take precedence over the ones registered globally with RegisterActivity.
+func (t *TestWorkflowEnvironment) RegisterActivity(activityFn interface{}) {
+	t.RegisterActivityWithOptions(activityFn, RegisterActivityOptions{})
+}
+
+// RegisterActivityWithOptions registers the activity function with options with this TestWorkflowEnvironment only.
+func (t *TestWorkflowEnvironment) RegisterActivityWithOptions(activityFn interface{}, options RegisterActivityOptions) {
+	if err := t.impl.registry.RegisterActivityWithOptions(activityFn, options); err != nil {
+		panic(err)
+	}
+}
+
 // OnActivity setup a mock call for activity. Parameter activity must be activity function (func) or activity name (string).
 // You must call Return() with appropriate parameters on the returned *MockCallWrapper instance. The supplied parameters to
 // the Return() call should either be a function that has exact same signature as the mocked activity, or it should be
@@ -130,11 +170,7 @@ func (t *TestWorkflowEnvironment) OnActivity(activity interface{}, args ...inter
 		if err := validateFnFormat(fnType, false); err != nil {
 			panic(err)
 		}
-		fnName := getFunctionName(activity)
-		if alias, ok := getHostEnvironment().getActivityAlias(fnName); ok {
-			fnName = alias
-		}
-		call = t.Mock.On(fnName, args...)
+		call = t.Mock.On(getActivityFunctionName(t.impl.registry, activity), args...)
 
 	case reflect.String:
 		call = t.Mock.On(activity.(string), args...)
@@ -168,7 +204,7 @@ func (t *TestWorkflowEnvironment) OnWorkflow(workflow interface{}, args ...inter
 			panic(err)
 		}
 		fnName := getFunctionName(workflow)
-		if alias, ok := getHostEnvironment().getWorkflowAlias(fnName); ok {
+		if alias, ok := t.impl.registry.getWorkflowAlias(fnName); ok {
 			fnName = alias
 		}
 		call = t.Mock.On(fnName, args...)
@@ -229,13 +265,25 @@ func (t *TestWorkflowEnvironment) ExecuteWorkflow(workflowFn interface{}, args .
 	t.impl.executeWorkflow(workflowFn, args...)
 }
 
+// ReplayWorkflowHistoryFromJSONFile replays the history of a workflow execution read from a JSON file written by
+// ExportHistoryJSON with the workflows registered with this TestWorkflowEnvironment, to check that the workflow code
+// is still compatible with a recorded execution. See WorkflowReplayer.ReplayWorkflowHistory for the errors it returns.
+func (t *TestWorkflowEnvironment) ReplayWorkflowHistoryFromJSONFile(jsonFileName string) error {
+	history, err := importHistoryJSONFile(jsonFileName)
+	if err != nil {
+		return err
+	}
+	return replayWorkflowHistory(t.impl.logger, t.impl.registry, nil, history, replayOptions{})
+}
+
 // Now returns the current workflow time (a.k.a cadence.Now() time) of this TestWorkflowEnvironment.
 func (t *TestWorkflowEnvironment) Now() time.Time {
 	return t.impl.Now()
 }
 
 // SetWorkerOptions sets the WorkerOptions for TestWorkflowEnvironment. TestWorkflowEnvironment will use options set by
-// use options of Identity, MetricsScope and BackgroundActivityContext on the WorkerOptions. Other options are ignored.
+// use options of Identity, MetricsScope, BackgroundActivityContext, WorkflowInterceptors, ActivityInterceptors, Tracer,
+// DeadlockDetectionTimeout and DisableDeadlockDetection on the WorkerOptions. Other options are ignored.
 func (t *TestWorkflowEnvironment) SetWorkerOptions(options WorkerOptions) *TestWorkflowEnvironment {
 	t.impl.setWorkerOptions(options)
 	return t